@@ -3,7 +3,7 @@ package base
 import (
 	"encoding/json"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
 	"party-buddy/internal/schemas/api"
 )
@@ -24,7 +24,7 @@ type OurNotFoundHandler struct{}
 func (o OurNotFoundHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	msg := "page was not found"
 	WriteErrorResponse(w, http.StatusNotFound, api.ErrNotFound, msg)
-	log.Printf("request: %v %s -> err: %v", r.Method, r.URL, msg)
+	slog.InfoContext(r.Context(), "request failed", "err", msg)
 }
 
 type OurMethodNotAllowedHandler struct{}
@@ -32,7 +32,7 @@ type OurMethodNotAllowedHandler struct{}
 func (o OurMethodNotAllowedHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	msg := fmt.Sprintf("method not allowed for endpoint: %v", r.URL.Path)
 	WriteErrorResponse(w, http.StatusMethodNotAllowed, api.ErrMethodNotAllowed, msg)
-	log.Printf("request: %v %s -> err: %v", r.Method, r.URL, msg)
+	slog.InfoContext(r.Context(), "request failed", "err", msg)
 }
 
 // WriteErrorResponse writes error response to w with given status code.
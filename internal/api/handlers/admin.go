@@ -0,0 +1,227 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"io"
+	"log/slog"
+	"net/http"
+	"party-buddy/internal/api/base"
+	"party-buddy/internal/api/middleware"
+	"party-buddy/internal/db"
+	"party-buddy/internal/schemas"
+	"party-buddy/internal/schemas/api"
+	"party-buddy/internal/session"
+	"strconv"
+
+	"golang.org/x/exp/slices"
+)
+
+// sidFromRequest extracts the session id from the route.
+// On failure writes an error response and returns false.
+func sidFromRequest(w http.ResponseWriter, r *http.Request) (session.SessionID, bool) {
+	id, err := uuid.Parse(mux.Vars(r)["session-id"])
+	if err != nil {
+		msg := "invalid url"
+		base.WriteErrorResponse(w, http.StatusNotFound, api.ErrNotFound, msg)
+		slog.InfoContext(r.Context(), "request failed", "err", msg)
+		return session.SessionID{}, false
+	}
+	return session.SessionID(id), true
+}
+
+// auditAdminAction records the action performed by the authenticated administrator.
+// The record is a part of the request transaction: it's only stored if the transaction is committed.
+// On failure writes an error response and returns false.
+func auditAdminAction(
+	w http.ResponseWriter,
+	r *http.Request,
+	action db.AdminAction,
+	sid uuid.NullUUID,
+	details string,
+) bool {
+	authInfo := middleware.AuthInfoFromContext(r.Context())
+	tx := middleware.TxFromContext(r.Context())
+
+	slog.InfoContext(r.Context(), "admin action",
+		"admin_id", authInfo.ID, "action", action, "sid", sid.UUID, "details", details)
+	if err := db.CreateAdminAuditEntry(r.Context(), tx, authInfo.ID, action, sid, details); err != nil {
+		base.WriteErrorResponse(w, http.StatusInternalServerError, api.ErrInternal, "failed to write the audit log")
+		slog.ErrorContext(r.Context(), "request failed", "err", err)
+		return false
+	}
+	return true
+}
+
+// commitAdminAction commits the request transaction holding the audit record.
+// On failure writes an error response and returns false.
+func commitAdminAction(w http.ResponseWriter, r *http.Request) bool {
+	tx := middleware.TxFromContext(r.Context())
+	if err := tx.Commit(r.Context()); err != nil {
+		base.WriteErrorResponse(w, http.StatusInternalServerError, api.ErrInternal, "failed to write the audit log")
+		slog.ErrorContext(r.Context(), "request failed", "err", err)
+		return false
+	}
+	return true
+}
+
+func writeAdminSessionError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, session.ErrNoSession):
+		base.WriteErrorResponse(w, http.StatusNotFound, api.ErrNotFound, "session not found")
+	case errors.Is(err, session.ErrNoPlayer):
+		base.WriteErrorResponse(w, http.StatusNotFound, api.ErrNotFound, "player not found")
+	default:
+		base.WriteErrorResponse(w, http.StatusInternalServerError, api.ErrInternal, "internal server error")
+		slog.ErrorContext(r.Context(), "request failed", "err", err)
+		return
+	}
+	slog.InfoContext(r.Context(), "request failed", "err", err)
+}
+
+type AdminListSessionsHandler struct{}
+
+// AdminListSessionsHandler lists all live sessions, oldest first.
+func (h AdminListSessionsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !auditAdminAction(w, r, db.AdminListSessions, uuid.NullUUID{}, "") {
+		return
+	}
+
+	manager := middleware.ManagerFromContext(r.Context())
+	var summaries []session.SessionSummary
+	manager.Storage().Atomically(func(s *session.UnsafeStorage) {
+		summaries = s.SessionSummaries()
+	})
+
+	if !commitAdminAction(w, r) {
+		return
+	}
+
+	slices.SortFunc(summaries, func(a, b session.SessionSummary) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+	resp := make([]api.AdminSessionSummary, 0, len(summaries))
+	for _, summary := range summaries {
+		resp = append(resp, toAdminSessionSummary(summary))
+	}
+
+	slog.InfoContext(r.Context(), "request handled")
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+type AdminGetSessionHandler struct{}
+
+// AdminGetSessionHandler returns a snapshot of a live session's full state.
+func (h AdminGetSessionHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	sid, ok := sidFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	manager := middleware.ManagerFromContext(r.Context())
+	var details session.SessionDetails
+	var err error
+	manager.Storage().Atomically(func(s *session.UnsafeStorage) {
+		details, err = s.SessionDetails(sid)
+	})
+	if err != nil {
+		writeAdminSessionError(w, r, err)
+		return
+	}
+
+	if !auditAdminAction(w, r, db.AdminViewSession, sid.NullUUID(), "") || !commitAdminAction(w, r) {
+		return
+	}
+
+	slog.InfoContext(r.Context(), "request handled")
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(toAdminSessionDetails(details))
+}
+
+type AdminCloseSessionHandler struct{}
+
+// AdminCloseSessionHandler closes a live session.
+// The reason given in the request body is sent to the players.
+// The action is audit-logged before it's performed, so a failed attempt is recorded too.
+func (h AdminCloseSessionHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	sid, ok := sidFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	bytes, err := io.ReadAll(r.Body)
+	if err != nil {
+		base.WriteErrorResponse(w, http.StatusInternalServerError, api.ErrInternal, "failed to read request body")
+		slog.ErrorContext(r.Context(), "failed to read request body", "err", err)
+		return
+	}
+
+	var req schemas.AdminCloseSessionRequest
+	if err = api.Parse(r.Context(), &req, bytes, false); err != nil {
+		var dto api.Error
+		errors.As(err, &dto)
+		base.WriteErrorResponse(w, http.StatusBadRequest, dto.Kind, dto.Message)
+		slog.InfoContext(r.Context(), "request failed", "err", dto)
+		return
+	}
+
+	if !auditAdminAction(w, r, db.AdminCloseSession, sid.NullUUID(), fmt.Sprintf("reason: %q", *req.Reason)) ||
+		!commitAdminAction(w, r) {
+		return
+	}
+
+	manager := middleware.ManagerFromContext(r.Context())
+	if err = manager.CloseSession(r.Context(), sid, *req.Reason); err != nil {
+		writeAdminSessionError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	slog.InfoContext(r.Context(), "request handled")
+}
+
+type AdminKickPlayerHandler struct {
+	// Ban prevents the player's client from joining the session again.
+	Ban bool
+}
+
+// AdminKickPlayerHandler removes a player from a live session, optionally banning them.
+// The action is audit-logged before it's performed, so a failed attempt is recorded too.
+func (h AdminKickPlayerHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	sid, ok := sidFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	playerID, err := strconv.ParseUint(mux.Vars(r)["player-id"], 10, 32)
+	if err != nil {
+		msg := "invalid url"
+		base.WriteErrorResponse(w, http.StatusNotFound, api.ErrNotFound, msg)
+		slog.InfoContext(r.Context(), "request failed", "err", msg)
+		return
+	}
+
+	action := db.AdminKickPlayer
+	if h.Ban {
+		action = db.AdminBanPlayer
+	}
+	if !auditAdminAction(w, r, action, sid.NullUUID(), fmt.Sprintf("player: %d", playerID)) ||
+		!commitAdminAction(w, r) {
+		return
+	}
+
+	manager := middleware.ManagerFromContext(r.Context())
+	if err = manager.KickPlayer(r.Context(), sid, session.PlayerID(playerID), h.Ban); err != nil {
+		writeAdminSessionError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	slog.InfoContext(r.Context(), "request handled")
+}
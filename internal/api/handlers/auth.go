@@ -0,0 +1,193 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"io"
+	"log/slog"
+	"net/http"
+	"party-buddy/internal/api/base"
+	"party-buddy/internal/api/middleware"
+	"party-buddy/internal/auth"
+	"party-buddy/internal/db"
+	"party-buddy/internal/schemas"
+	"party-buddy/internal/schemas/api"
+	"time"
+)
+
+// issueTokens issues a new access token and refresh token pair for the user
+func issueTokens(ctx context.Context, tx pgx.Tx, issuer *auth.Issuer, userID uuid.UUID) (api.TokenResponse, error) {
+	accessToken, expiresAt, err := issuer.IssueAccessToken(userID)
+	if err != nil {
+		return api.TokenResponse{}, err
+	}
+
+	refreshToken, hash := auth.NewRefreshToken()
+	err = db.CreateRefreshToken(ctx, tx, hash, userID, time.Now().Add(issuer.RefreshTokenTTL()))
+	if err != nil {
+		return api.TokenResponse{}, err
+	}
+
+	return api.TokenResponse{
+		UserID:               userID,
+		AccessToken:          accessToken,
+		AccessTokenExpiresAt: expiresAt,
+		RefreshToken:         refreshToken,
+	}, nil
+}
+
+func writeTokenResponse(w http.ResponseWriter, resp api.TokenResponse) {
+	encoder := json.NewEncoder(w)
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	_ = encoder.Encode(resp)
+}
+
+type RegisterDeviceHandler struct {
+	Issuer *auth.Issuer
+}
+
+// RegisterDeviceHandler registers a new user for the device and issues them a pair of tokens.
+// The user id is generated by the server unless the client migrates its legacy id.
+// Only the legacy ids that own images or games may be kept.
+func (h RegisterDeviceHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	bytes, err := io.ReadAll(r.Body)
+	if err != nil {
+		base.WriteErrorResponse(w, http.StatusInternalServerError, api.ErrInternal, "failed to read request body")
+		slog.ErrorContext(r.Context(), "failed to read request body", "err", err)
+		return
+	}
+	if len(bytes) == 0 {
+		bytes = []byte("{}")
+	}
+
+	var req schemas.RegisterDeviceRequest
+	if err = api.Parse(r.Context(), &req, bytes, false); err != nil {
+		var dto api.Error
+		errors.As(err, &dto)
+		base.WriteErrorResponse(w, http.StatusBadRequest, dto.Kind, dto.Message)
+		slog.InfoContext(r.Context(), "request failed", "err", dto)
+		return
+	}
+
+	tx := middleware.TxFromContext(r.Context())
+	userID := uuid.New()
+
+	if req.LegacyID != nil {
+		if !h.Issuer.LegacyIDsAllowed() {
+			msg := "legacy ids are no longer accepted"
+			base.WriteErrorResponse(w, http.StatusBadRequest, api.ErrParamInvalid, msg)
+			slog.InfoContext(r.Context(), "request failed", "err", msg)
+			return
+		}
+
+		registered, err := db.UserExists(r.Context(), tx, *req.LegacyID)
+		if err != nil {
+			base.WriteErrorResponse(w, http.StatusInternalServerError, api.ErrInternal, "failed to register device")
+			slog.ErrorContext(r.Context(), "request failed", "err", err)
+			return
+		}
+		if registered {
+			msg := "the user id has already been registered"
+			base.WriteErrorResponse(w, http.StatusConflict, api.ErrUserIDTaken, msg)
+			slog.InfoContext(r.Context(), "request failed", "err", msg)
+			return
+		}
+
+		seen, err := db.LegacyIDSeen(r.Context(), tx, *req.LegacyID, h.Issuer.LegacyIDsUntil())
+		if err != nil {
+			base.WriteErrorResponse(w, http.StatusInternalServerError, api.ErrInternal, "failed to register device")
+			slog.ErrorContext(r.Context(), "request failed", "err", err)
+			return
+		}
+
+		// an id that owns nothing has nothing to keep, so it gets a fresh one
+		if seen {
+			userID = *req.LegacyID
+		} else {
+			slog.InfoContext(r.Context(), "legacy id not seen, issuing a new one", "legacy-id", *req.LegacyID)
+		}
+	}
+
+	if err = db.CreateUser(r.Context(), tx, userID, db.Base); err != nil {
+		base.WriteErrorResponse(w, http.StatusInternalServerError, api.ErrInternal, "failed to register device")
+		slog.ErrorContext(r.Context(), "request failed", "err", err)
+		return
+	}
+
+	resp, err := issueTokens(r.Context(), tx, h.Issuer, userID)
+	if err != nil {
+		base.WriteErrorResponse(w, http.StatusInternalServerError, api.ErrInternal, "failed to register device")
+		slog.ErrorContext(r.Context(), "request failed", "err", err)
+		return
+	}
+
+	if err = tx.Commit(r.Context()); err != nil {
+		base.WriteErrorResponse(w, http.StatusInternalServerError, api.ErrInternal, "failed to register device")
+		slog.ErrorContext(r.Context(), "request failed", "err", err)
+		return
+	}
+
+	writeTokenResponse(w, resp)
+	slog.InfoContext(r.Context(), "request handled")
+}
+
+type RefreshTokenHandler struct {
+	Issuer *auth.Issuer
+}
+
+// RefreshTokenHandler exchanges a refresh token for a new pair of tokens.
+// The presented refresh token is revoked.
+func (h RefreshTokenHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	bytes, err := io.ReadAll(r.Body)
+	if err != nil {
+		base.WriteErrorResponse(w, http.StatusInternalServerError, api.ErrInternal, "failed to read request body")
+		slog.ErrorContext(r.Context(), "failed to read request body", "err", err)
+		return
+	}
+
+	var req schemas.RefreshTokenRequest
+	if err = api.Parse(r.Context(), &req, bytes, false); err != nil {
+		var dto api.Error
+		errors.As(err, &dto)
+		base.WriteErrorResponse(w, http.StatusBadRequest, dto.Kind, dto.Message)
+		slog.InfoContext(r.Context(), "request failed", "err", dto)
+		return
+	}
+
+	tx := middleware.TxFromContext(r.Context())
+
+	userID, err := db.RevokeRefreshToken(r.Context(), tx, auth.HashRefreshToken(*req.RefreshToken))
+	if err != nil {
+		var notFound db.RecordNotFound
+		if errors.As(err, &notFound) {
+			msg := "the refresh token is invalid, expired or already used"
+			base.WriteErrorResponse(w, http.StatusUnauthorized, api.ErrTokenInvalid, msg)
+			slog.InfoContext(r.Context(), "request failed", "err", msg)
+			return
+		}
+		base.WriteErrorResponse(w, http.StatusInternalServerError, api.ErrInternal, "failed to refresh token")
+		slog.ErrorContext(r.Context(), "request failed", "err", err)
+		return
+	}
+
+	resp, err := issueTokens(r.Context(), tx, h.Issuer, userID)
+	if err != nil {
+		base.WriteErrorResponse(w, http.StatusInternalServerError, api.ErrInternal, "failed to refresh token")
+		slog.ErrorContext(r.Context(), "request failed", "err", err)
+		return
+	}
+
+	if err = tx.Commit(r.Context()); err != nil {
+		base.WriteErrorResponse(w, http.StatusInternalServerError, api.ErrInternal, "failed to refresh token")
+		slog.ErrorContext(r.Context(), "request failed", "err", err)
+		return
+	}
+
+	writeTokenResponse(w, resp)
+	slog.InfoContext(r.Context(), "request handled")
+}
@@ -5,20 +5,67 @@ import (
 	"net/http"
 	"party-buddy/internal/api/base"
 	"party-buddy/internal/api/middleware"
+	"party-buddy/internal/apispec"
+	"party-buddy/internal/auth"
 	"party-buddy/internal/db"
+	"party-buddy/internal/health"
+	"party-buddy/internal/imgstore"
+	"party-buddy/internal/metrics"
+	"party-buddy/internal/ratelimit"
 	"party-buddy/internal/session"
 	"party-buddy/internal/validate"
+	"party-buddy/internal/ws"
 )
 
-// ConfigureMux configures the handlers for HTTP routes and methods
-func ConfigureMux(pool *db.DBPool, manager *session.Manager) *mux.Router {
-	r := mux.NewRouter()
-	r.NotFoundHandler = base.OurNotFoundHandler{}
-	r.MethodNotAllowedHandler = base.OurMethodNotAllowedHandler{}
+// ConfigureMux configures the handlers for HTTP routes and methods.
+// The routes are described in apispec.OpenAPI
+func ConfigureMux(
+	pool *db.DBPool,
+	manager *session.Manager,
+	storage imgstore.Storage,
+	issuer *auth.Issuer,
+	checker *health.Checker,
+	limiter *ratelimit.Limiter,
+	sse *ws.SSERegistry,
+) http.Handler {
+	requestIDMid := middleware.RequestIDMiddleware{}
+
+	return requestIDMid.Middleware(newRouter(pool, manager, storage, issuer, checker, limiter, sse))
+}
+
+func newRouter(
+	pool *db.DBPool,
+	manager *session.Manager,
+	storage imgstore.Storage,
+	issuer *auth.Issuer,
+	checker *health.Checker,
+	limiter *ratelimit.Limiter,
+	sse *ws.SSERegistry,
+) *mux.Router {
+	root := mux.NewRouter()
+	root.NotFoundHandler = base.OurNotFoundHandler{}
+	root.MethodNotAllowedHandler = base.OurMethodNotAllowedHandler{}
 
 	dbm := middleware.DBUsingMiddleware{Pool: pool}
 	managerMid := middleware.ManagerUsingMiddleware{Manager: manager}
 	validateMid := middleware.ValidateMiddleware{Factory: validate.NewValidationFactory()}
+	storageMid := middleware.ImgStorageUsingMiddleware{Storage: storage}
+	authMid := middleware.AuthUsingMiddleware{Issuer: issuer}
+	metricsMid := middleware.MetricsMiddleware{}
+	rateLimitMid := middleware.RateLimitMiddleware{Limiter: limiter}
+
+	root.Use(metricsMid.Middleware)
+
+	// the routes registered on root are served without a db transaction
+	root.Handle("/metrics", metrics.Handler()).Methods(http.MethodGet)
+	root.Handle("/healthz", HealthzHandler{}).Methods(http.MethodGet)
+	root.Handle("/readyz", ReadyzHandler{Checker: checker}).Methods(http.MethodGet)
+	root.Handle("/api/openapi.yaml", SpecHandler{Spec: apispec.OpenAPI}).Methods(http.MethodGet)
+	root.Handle("/api/asyncapi.yaml", SpecHandler{Spec: apispec.AsyncAPI}).Methods(http.MethodGet)
+
+	r := root.PathPrefix("/").Subrouter()
+	r.NotFoundHandler = root.NotFoundHandler
+	r.MethodNotAllowedHandler = root.MethodNotAllowedHandler
 
 	r.Use(dbm.Middleware)
 	r.Use(validateMid.Middleware)
@@ -26,17 +73,65 @@ func ConfigureMux(pool *db.DBPool, manager *session.Manager) *mux.Router {
 	// TODO: delete before production
 	r.HandleFunc("/", base.IndexHandler).Methods(http.MethodGet)
 
-	r.Handle("/api/v1/images/{img-id}", middleware.AuthMiddleware(
-		GetImageHandler{})).Methods(http.MethodGet)
+	r.Handle("/api/v1/auth/device", rateLimitMid.Middleware(ratelimit.DeviceRegister,
+		RegisterDeviceHandler{Issuer: issuer})).Methods(http.MethodPost)
+
+	r.Handle("/api/v1/auth/refresh", RefreshTokenHandler{Issuer: issuer}).Methods(http.MethodPost)
+
+	r.Handle("/api/v1/images/{img-id}", authMid.SignedImgMiddleware(
+		managerMid.Middleware(storageMid.Middleware(GetImageHandler{})))).Methods(http.MethodGet)
+
+	r.Handle("/api/v1/images/{img-id}", authMid.Middleware(rateLimitMid.Middleware(ratelimit.ImgUpload,
+		storageMid.Middleware(UploadImageHandler{})))).Methods(http.MethodPut)
+
+	r.Handle("/api/v1/session", authMid.Middleware(rateLimitMid.Middleware(ratelimit.SessionJoin,
+		managerMid.Middleware(SessionConnectHandler{Limiter: limiter})))).Methods(http.MethodGet)
+
+	r.Handle("/api/v1/session", authMid.Middleware(rateLimitMid.Middleware(ratelimit.SessionCreate,
+		managerMid.Middleware(SessionCreateHandler{})))).Methods(http.MethodPost)
 
-	r.Handle("/api/v1/session", middleware.AuthMiddleware(
-		managerMid.Middleware(SessionConnectHandler{}))).Methods(http.MethodGet)
+	// the SSE fallback for the clients that cannot use WebSocket
+	r.Handle("/api/v1/session/events", authMid.Middleware(rateLimitMid.Middleware(ratelimit.SessionJoin,
+		managerMid.Middleware(SessionEventsHandler{Limiter: limiter, Registry: sse})))).Methods(http.MethodGet)
 
-	r.Handle("/api/v1/session", middleware.AuthMiddleware(
-		managerMid.Middleware(SessionCreateHandler{}))).Methods(http.MethodPost)
+	r.Handle("/api/v1/session/messages", authMid.Middleware(
+		SessionMessageHandler{Registry: sse})).Methods(http.MethodPost)
 
-	r.Handle("/api/v1/games/{game-id}", middleware.AuthMiddleware(
+	r.Handle("/api/v1/lobbies", authMid.Middleware(
+		managerMid.Middleware(LobbiesHandler{}))).Methods(http.MethodGet)
+
+	r.Handle("/api/v1/lobbies/quick-join", authMid.Middleware(rateLimitMid.Middleware(ratelimit.SessionCreate,
+		managerMid.Middleware(QuickJoinHandler{})))).Methods(http.MethodPost)
+
+	r.Handle("/api/v1/games/import", authMid.Middleware(rateLimitMid.Middleware(ratelimit.GameImport,
+		storageMid.Middleware(ImportGameHandler{})))).Methods(http.MethodPost)
+
+	r.Handle("/api/v1/games/import/csv", authMid.Middleware(rateLimitMid.Middleware(ratelimit.GameImport,
+		ImportCSVHandler{}))).Methods(http.MethodPost)
+
+	r.Handle("/api/v1/games/{game-id}", authMid.Middleware(
 		GetGameHandler{})).Methods(http.MethodGet)
 
-	return r
+	r.Handle("/api/v1/games/{game-id}/export", authMid.Middleware(
+		storageMid.Middleware(ExportGameHandler{}))).Methods(http.MethodGet)
+
+	r.Handle("/api/v1/games/{game-id}/clone", authMid.Middleware(rateLimitMid.Middleware(ratelimit.GameClone,
+		CloneGameHandler{}))).Methods(http.MethodPost)
+
+	r.Handle("/api/v1/admin/sessions", authMid.AdminMiddleware(
+		managerMid.Middleware(AdminListSessionsHandler{}))).Methods(http.MethodGet)
+
+	r.Handle("/api/v1/admin/sessions/{session-id}", authMid.AdminMiddleware(
+		managerMid.Middleware(AdminGetSessionHandler{}))).Methods(http.MethodGet)
+
+	r.Handle("/api/v1/admin/sessions/{session-id}/close", authMid.AdminMiddleware(
+		managerMid.Middleware(AdminCloseSessionHandler{}))).Methods(http.MethodPost)
+
+	r.Handle("/api/v1/admin/sessions/{session-id}/players/{player-id}/kick", authMid.AdminMiddleware(
+		managerMid.Middleware(AdminKickPlayerHandler{}))).Methods(http.MethodPost)
+
+	r.Handle("/api/v1/admin/sessions/{session-id}/players/{player-id}/ban", authMid.AdminMiddleware(
+		managerMid.Middleware(AdminKickPlayerHandler{Ban: true}))).Methods(http.MethodPost)
+
+	return root
 }
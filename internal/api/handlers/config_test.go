@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"net/http"
+	"party-buddy/internal/apispec"
+	"party-buddy/internal/ratelimit"
+	"party-buddy/internal/ws"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"gopkg.in/yaml.v3"
+)
+
+// undocumentedRoutes are not described in the OpenAPI document
+var undocumentedRoutes = map[string]bool{
+	"GET /": true,
+}
+
+func TestRoutesDocumented(t *testing.T) {
+	var doc struct {
+		Paths map[string]map[string]any `yaml:"paths"`
+	}
+	if err := yaml.Unmarshal(apispec.OpenAPI, &doc); err != nil {
+		t.Fatalf("could not parse the OpenAPI document: %v", err)
+	}
+
+	documented := make(map[string]bool)
+	for path, item := range doc.Paths {
+		for method := range item {
+			if method != "parameters" {
+				documented[strings.ToUpper(method)+" "+path] = true
+			}
+		}
+	}
+
+	limiter := ratelimit.New(nil, false, nil)
+	router := newRouter(nil, nil, nil, nil, nil, limiter, ws.NewSSERegistry())
+	routes := make(map[string]bool)
+	err := router.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		path, err := route.GetPathTemplate()
+		if err != nil {
+			// a subrouter prefix
+			return nil
+		}
+		methods, err := route.GetMethods()
+		if err != nil {
+			return nil
+		}
+		for _, method := range methods {
+			routes[method+" "+path] = true
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("could not walk the routes: %v", err)
+	}
+
+	for route := range routes {
+		if !documented[route] && !undocumentedRoutes[route] {
+			t.Errorf("the route %s is not documented", route)
+		}
+	}
+	for route := range documented {
+		if !routes[route] {
+			t.Errorf("the documented route %s does not exist", route)
+		}
+	}
+
+	if len(routes) == 0 || !routes[http.MethodGet+" /api/v1/session"] {
+		t.Error("the routes have not been found")
+	}
+}
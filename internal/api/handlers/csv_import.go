@@ -0,0 +1,264 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/cohesivestack/valgo"
+	"io"
+	"log/slog"
+	"net/http"
+	"party-buddy/internal/api/base"
+	"party-buddy/internal/api/middleware"
+	"party-buddy/internal/configuration"
+	"party-buddy/internal/schemas"
+	"party-buddy/internal/schemas/api"
+	"party-buddy/internal/validate"
+	"strconv"
+	"strings"
+
+	"golang.org/x/exp/slices"
+)
+
+// # CSV import
+//
+// Each row of a CSV file describes a task: a choice task if it has options, a checked-text task otherwise.
+// The first row names the columns (csvColumns, in any order; only the options may be left out).
+// The options are separated by csvOptionSeparator, and the answer of a choice task is the correct option.
+
+var csvColumns = []string{"name", "description", "duration", "answer", "options"}
+
+var csvOptionalColumns = []string{"options"}
+
+const csvOptionSeparator = "|"
+
+// csvRow is a row of the CSV file, keyed by the column names.
+type csvRow map[string]string
+
+type ImportCSVHandler struct{}
+
+// ImportCSVHandler validates the tasks of a CSV file and reports on each row.
+//
+// If the game-name or game-description query params are provided, the valid rows are assembled into a new game
+// owned by the client, unless it's a dry run. Both are then validated as those of a private game.
+func (ImportCSVHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	dryRun := false
+	if val := query.Get("dry-run"); val != "" {
+		var err error
+		if dryRun, err = strconv.ParseBool(val); err != nil {
+			msg := "dry-run must be a boolean"
+			base.WriteErrorResponse(w, http.StatusBadRequest, api.ErrParamInvalid, msg)
+			slog.InfoContext(r.Context(), "request failed", "err", msg)
+			return
+		}
+	}
+	assemble := query.Has("game-name") || query.Has("game-description")
+
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, configuration.MaxCSVImportSize))
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			msg := "the file is too large"
+			base.WriteErrorResponse(w, http.StatusRequestEntityTooLarge, api.ErrCSVTooLarge, msg)
+			slog.InfoContext(r.Context(), "request failed", "err", msg)
+			return
+		}
+		base.WriteErrorResponse(w, http.StatusInternalServerError, api.ErrInternal, "failed to read request body")
+		slog.ErrorContext(r.Context(), "failed to read request body", "err", err)
+		return
+	}
+
+	tasks, resp, err := readCSVTasks(r.Context(), data)
+	if err != nil {
+		var dto api.Error
+		errors.As(err, &dto)
+		base.WriteErrorResponse(w, http.StatusBadRequest, dto.Kind, dto.Message)
+		slog.InfoContext(r.Context(), "request failed", "err", dto)
+		return
+	}
+
+	if assemble {
+		name, description := query.Get("game-name"), query.Get("game-description")
+		imgRequest := api.ImgRequest(-1)
+		gameInfo := schemas.FullGameInfo{
+			Name:        &name,
+			Description: &description,
+			ImgRequest:  &imgRequest,
+			Tasks:       &tasks,
+		}
+		if val := gameInfo.Validate(r.Context()); !val.Valid() {
+			msg := "the valid rows don't make a valid game"
+			if fieldName, valMsg, ok := validate.ExtractValgoErrorFields(val.Error().(*valgo.Error)); ok {
+				msg = fmt.Sprintf("in field `%s`: %s", fieldName, valMsg)
+			}
+			base.WriteErrorResponse(w, http.StatusBadRequest, api.ErrGameInvalid, msg)
+			slog.InfoContext(r.Context(), "request failed", "err", msg)
+			return
+		}
+
+		if !dryRun {
+			tx := middleware.TxFromContext(r.Context())
+			authInfo := middleware.AuthInfoFromContext(r.Context())
+
+			gameID, err := createGame(r.Context(), tx, authInfo.ID, gameInfo, nil)
+			if err != nil {
+				var errConv api.ErrorFromConverters
+				errors.As(err, &errConv)
+				slog.ErrorContext(r.Context(), "request failed", "err", errConv)
+				base.WriteErrorResponse(w, errConv.StatusCode, errConv.ApiError.Kind, errConv.ApiError.Message)
+				return
+			}
+			if err = tx.Commit(r.Context()); err != nil {
+				base.WriteErrorResponse(w, http.StatusInternalServerError, api.ErrInternal, "failed to import game")
+				slog.ErrorContext(r.Context(), "request failed", "err", err)
+				return
+			}
+			resp.GameID = &gameID
+		}
+	}
+
+	encoder := json.NewEncoder(w)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = encoder.Encode(resp)
+	slog.InfoContext(r.Context(), "request handled")
+}
+
+// readCSVTasks parses the CSV file and validates its rows.
+// Returns the tasks of the valid rows and the report on every row.
+// Fails if the file itself is malformed.
+func readCSVTasks(ctx context.Context, data []byte) ([]schemas.BaseTaskWithImgRequest, api.CSVImportResponse, error) {
+	resp := api.CSVImportResponse{Rows: make([]api.CSVRowReport, 0)}
+
+	reader := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte("\ufeff"))))
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, resp, csvError(err)
+	}
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, name := range csvColumns {
+		if _, ok := columns[name]; !ok && !slices.Contains(csvOptionalColumns, name) {
+			return nil, resp, api.Errorf(api.ErrMalformedRequest, "the column `%s` is missing", name)
+		}
+	}
+
+	tasks := make([]schemas.BaseTaskWithImgRequest, 0)
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, resp, csvError(err)
+		}
+		line, _ := reader.FieldPos(0)
+
+		row := make(csvRow)
+		for _, name := range csvColumns {
+			if i, ok := columns[name]; ok && i < len(record) {
+				row[name] = strings.TrimSpace(record[i])
+			}
+		}
+
+		report := api.CSVRowReport{Line: line, Valid: true}
+		task, err := csvRowToTask(row)
+		if err == nil {
+			err = validateTask(ctx, &task)
+		}
+		if err != nil {
+			msg := err.Error()
+			report.Valid, report.Error = false, &msg
+		} else {
+			tasks = append(tasks, task)
+			resp.ValidCount++
+		}
+		resp.Rows = append(resp.Rows, report)
+	}
+
+	return tasks, resp, nil
+}
+
+func csvError(err error) error {
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		return api.Errorf(api.ErrMalformedRequest, "the file is not valid CSV: line %d: %s", parseErr.Line, parseErr.Err)
+	}
+	if errors.Is(err, io.EOF) {
+		return api.Errorf(api.ErrMalformedRequest, "the file is empty")
+	}
+	return api.Errorf(api.ErrMalformedRequest, "the file is not valid CSV: %s", err)
+}
+
+// csvRowToTask converts the row to a task without validating it.
+// The empty cells are treated as missing values.
+func csvRowToTask(row csvRow) (schemas.BaseTaskWithImgRequest, error) {
+	imgRequest := api.ImgRequest(-1)
+	task := schemas.BaseTaskWithImgRequest{ImgRequest: &imgRequest}
+
+	cell := func(name string) *string {
+		if val, ok := row[name]; ok && val != "" {
+			return &val
+		}
+		return nil
+	}
+	task.Name = cell("name")
+	task.Description = cell("description")
+
+	if val := cell("duration"); val != nil {
+		secs, err := strconv.ParseUint(*val, 10, 16)
+		if err != nil {
+			return task, fmt.Errorf("in field `duration`: must be a number of seconds")
+		}
+		task.Duration = &schemas.PollDuration{Kind: schemas.Fixed, Secs: uint16(secs)}
+	}
+
+	taskType := schemas.CheckedText
+	if val := cell("options"); val != nil {
+		taskType = schemas.Choice
+		options := strings.Split(*val, csvOptionSeparator)
+		for i := range options {
+			options[i] = strings.TrimSpace(options[i])
+		}
+		task.Options = &options
+
+		if answer := cell("answer"); answer != nil {
+			for i := range options {
+				if options[i] == *answer {
+					answerIdx := uint8(i)
+					task.AnswerIndex = &answerIdx
+					break
+				}
+			}
+			if task.AnswerIndex == nil {
+				return task, fmt.Errorf("in field `answer`: must be one of the options")
+			}
+		}
+	} else {
+		task.Answer = cell("answer")
+	}
+	task.Type = &taskType
+
+	return task, nil
+}
+
+// validateTask checks the task with the same rules as the tasks of the games sent along with the session requests.
+func validateTask(ctx context.Context, task *schemas.BaseTaskWithImgRequest) error {
+	val := task.Validate(ctx)
+	if val.Valid() {
+		return nil
+	}
+	if fieldName, msg, ok := validate.ExtractValgoErrorFields(val.Error().(*valgo.Error)); ok {
+		return fmt.Errorf("in field `%s`: %s", fieldName, msg)
+	}
+	return errors.New("the task is invalid")
+}
@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"context"
+	"party-buddy/internal/schemas"
+	"party-buddy/internal/schemas/api"
+	"party-buddy/internal/validate"
+	"strings"
+	"testing"
+)
+
+func TestReadCSVTasks(t *testing.T) {
+	ctx := validate.NewContext(context.Background(), validate.NewValidationFactory())
+	data := "\ufeffName,Description,Duration,Answer,Options\n" +
+		"Capital,The capital of France?,30,PARIS,\n" +
+		"Colour,The colour of the sky?,15,blue,red | blue | green | black\n" +
+		"Lowercase,Answers must be uppercase,30,paris,\n" +
+		"No answer,Not one of the options,15,white,red|blue|green|black\n" +
+		"Duration,Not a number,soon,PARIS,\n" +
+		",No name,30,PARIS,\n" +
+		"Few options,Only two,15,red,red|blue\n"
+
+	tasks, resp, err := readCSVTasks(ctx, []byte(data))
+	if err != nil {
+		t.Fatalf("readCSVTasks failed: %v", err)
+	}
+
+	if len(tasks) != 2 || resp.ValidCount != 2 {
+		t.Fatalf("got %d valid tasks (%d reported), want 2", len(tasks), resp.ValidCount)
+	}
+	if *tasks[0].Type != schemas.CheckedText || *tasks[0].Answer != "PARIS" {
+		t.Errorf("the first row is not the checked-text task: %+v", tasks[0])
+	}
+	if *tasks[1].Type != schemas.Choice || *tasks[1].AnswerIndex != 1 || (*tasks[1].Options)[3] != "black" {
+		t.Errorf("the second row is not the choice task: %+v", tasks[1])
+	}
+
+	wantErrors := map[int]string{
+		4: "answer",
+		5: "answer",
+		6: "duration",
+		7: "name",
+		8: "options",
+	}
+	if len(resp.Rows) != 7 {
+		t.Fatalf("got %d rows, want 7", len(resp.Rows))
+	}
+	for _, row := range resp.Rows {
+		field, invalid := wantErrors[row.Line]
+		if row.Valid == invalid {
+			t.Errorf("line %d: got valid = %v", row.Line, row.Valid)
+			continue
+		}
+		if invalid && (row.Error == nil || !strings.Contains(*row.Error, "`"+field+"`")) {
+			t.Errorf("line %d: got error %v, want one in the field %s", row.Line, row.Error, field)
+		}
+	}
+}
+
+func TestReadCSVTasksMalformed(t *testing.T) {
+	ctx := validate.NewContext(context.Background(), validate.NewValidationFactory())
+
+	for name, data := range map[string]string{
+		"empty":          "",
+		"missing column": "name,description,answer\nCapital,The capital of France?,PARIS\n",
+		"bad quote":      "name,description,duration,answer\nCapital,\"The capital\" of France?,30,PARIS\n",
+	} {
+		_, _, err := readCSVTasks(ctx, []byte(data))
+		if e, ok := err.(api.Error); !ok || e.Kind != api.ErrMalformedRequest {
+			t.Errorf("%s: got %v, want %s", name, err, api.ErrMalformedRequest)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"net/http"
+	"party-buddy/internal/db"
+	"party-buddy/internal/schemas"
+	"party-buddy/internal/schemas/api"
+)
+
+// createGame stores the game with its tasks, owned by owner, and returns the id of the game.
+// The images are looked up in imgs by their img-requests; the negative img-requests mean no image.
+func createGame(
+	ctx context.Context,
+	tx pgx.Tx,
+	owner uuid.UUID,
+	gameInfo schemas.FullGameInfo,
+	imgs map[api.ImgRequest]uuid.NullUUID,
+) (uuid.UUID, error) {
+	imgID := func(req api.ImgRequest) uuid.NullUUID {
+		if req < 0 {
+			return uuid.NullUUID{}
+		}
+		return imgs[req]
+	}
+
+	gameID, err := db.CreateGame(ctx, tx, owner, *gameInfo.Name, *gameInfo.Description, imgID(*gameInfo.ImgRequest))
+	if err != nil {
+		return uuid.UUID{}, api.ErrorFromConverters{
+			ApiError:   api.Errorf(api.ErrInternal, ""),
+			StatusCode: http.StatusInternalServerError,
+			LogMessage: fmt.Sprintf("failed to create game: %s", err),
+		}
+	}
+
+	for i, task := range *gameInfo.Tasks {
+		taskID, err := createTask(ctx, tx, owner, task, imgID(*task.ImgRequest))
+		if err != nil {
+			return uuid.UUID{}, err
+		}
+		if err = db.CreateGameTask(ctx, tx, gameID, taskID, i); err != nil {
+			return uuid.UUID{}, api.ErrorFromConverters{
+				ApiError:   api.Errorf(api.ErrInternal, ""),
+				StatusCode: http.StatusInternalServerError,
+				LogMessage: fmt.Sprintf("failed to add task to game: %s", err),
+			}
+		}
+	}
+
+	return gameID, nil
+}
+
+func createTask(
+	ctx context.Context,
+	tx pgx.Tx,
+	owner uuid.UUID,
+	task schemas.BaseTaskWithImgRequest,
+	imgID uuid.NullUUID,
+) (uuid.UUID, error) {
+	if task.Type == nil {
+		panic("unexpected nil for task type while converting received task to task entity")
+	}
+
+	entity := db.TaskEntity{
+		OwnerID:          uuid.NullUUID{UUID: owner, Valid: true},
+		ImageID:          imgID,
+		Name:             *task.Name,
+		Description:      *task.Description,
+		DurationSeconds:  int(task.Duration.Secs),
+		PollDurationType: db.Fixed,
+		TaskKind:         db.TaskKind(*task.Type),
+	}
+	switch *task.Type {
+	case schemas.Photo, schemas.Text:
+		entity.PollDurationType = toDBPollDurationType(task.PollDuration.Kind)
+		entity.PollDurationSeconds = int(task.PollDuration.Secs)
+
+	case schemas.CheckedText, schemas.Choice:
+
+	default:
+		return uuid.UUID{}, api.ErrorFromConverters{
+			ApiError:   api.Errorf(api.ErrTaskInvalid, "unknown task type: %s", *task.Type),
+			StatusCode: http.StatusBadRequest,
+			LogMessage: fmt.Sprintf("unknown task type: %s", *task.Type),
+		}
+	}
+
+	taskID, err := db.CreateTask(ctx, tx, entity)
+	if err == nil {
+		switch *task.Type {
+		case schemas.CheckedText:
+			err = db.CreateCheckedTextTask(ctx, tx, taskID, *task.Answer)
+
+		case schemas.Choice:
+			for i := 0; i < len(*task.Options) && err == nil; i++ {
+				err = db.CreateChoiceTaskOption(ctx, tx, taskID, (*task.Options)[i], i == int(*task.AnswerIndex))
+			}
+		}
+	}
+	if err != nil {
+		return uuid.UUID{}, api.ErrorFromConverters{
+			ApiError:   api.Errorf(api.ErrInternal, ""),
+			StatusCode: http.StatusInternalServerError,
+			LogMessage: fmt.Sprintf("failed to create task: %s", err),
+		}
+	}
+
+	return taskID, nil
+}
+
+func toDBPollDurationType(kind schemas.DurationKind) db.PollDurationType {
+	switch kind {
+	case schemas.Fixed:
+		return db.Fixed
+
+	case schemas.Dynamic:
+		return db.Dynamic
+
+	default:
+		panic(fmt.Sprintf("unknown poll duration kind %v", kind))
+	}
+}
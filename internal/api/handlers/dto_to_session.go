@@ -48,7 +48,7 @@ func toSessionGame(
 
 	imgResps := make([]api.ImgReqResponse, 0)
 	for k, v := range imgs {
-		imgResps = append(imgResps, api.ImgReqResponse{ImgRequest: k, ImgURI: configuration.GenImgURI(v)})
+		imgResps = append(imgResps, api.ImgReqResponse{ImgRequest: k, ImgURI: configuration.GenImgURI(v, uuid.NullUUID{})})
 	}
 
 	game := session.Game{
@@ -118,6 +118,44 @@ func toSessionTask(
 	}
 }
 
+// toSessionOptions converts the settings common to all session creation requests.
+func toSessionOptions(req schemas.BaseCreateSessionRequest) session.SessionOptions {
+	secs := func(s *uint16) time.Duration {
+		if s == nil {
+			return 0
+		}
+		return time.Duration(*s) * time.Second
+	}
+
+	opts := session.SessionOptions{
+		Timings: session.Timings{
+			NoOwnerTimeout:     secs(req.NoOwnerTimeout),
+			GameStartedTimeout: secs(req.GameStartedTimeout),
+			TaskEndTimeout:     secs(req.TaskEndTimeout),
+		},
+		LateJoin: req.LateJoin != nil && *req.LateJoin,
+	}
+	if req.LateJoinScore != nil && *req.LateJoinScore == schemas.LateJoinMinimum {
+		opts.LateJoinScore = session.LateJoinMinScore
+	}
+
+	// the seed is chosen here so that it can be returned to the owner
+	seed := session.NewTaskSeed()
+	if req.TaskSeed != nil {
+		seed = *req.TaskSeed
+	}
+	opts.Tasks = session.TaskSelection{
+		Shuffle:  req.ShuffleTasks != nil && *req.ShuffleTasks,
+		Stratify: req.StratifyTasks != nil && *req.StratifyTasks,
+		Seed:     &seed,
+	}
+	if req.TaskCount != nil {
+		opts.Tasks.Count = *req.TaskCount
+	}
+
+	return opts
+}
+
 func toSessionPollDuration(duration schemas.PollDuration) session.PollDurationer {
 	dur := time.Second * time.Duration(duration.Secs)
 	switch duration.Kind {
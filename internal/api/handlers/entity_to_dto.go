@@ -27,8 +27,11 @@ func gameIDToIDGameInfo(ctx context.Context, tx pgx.Tx, gameID uuid.UUID) (schem
 	gameInfo.Name = gameEntity.Name
 	gameInfo.Description = gameEntity.Description
 	gameInfo.DateChanged = gameEntity.UpdatedAt
+	if gameEntity.ForkedFrom.Valid {
+		gameInfo.ForkedFrom = &gameEntity.ForkedFrom.UUID
+	}
 	if gameEntity.ImageID.Valid {
-		gameInfo.ImgURI = configuration.GenImgURI(gameEntity.ImageID.UUID)
+		gameInfo.ImgURI = configuration.GenImgURI(gameEntity.ImageID.UUID, uuid.NullUUID{})
 	}
 
 	taskEntities, err := db.GetGameTasksByID(ctx, tx, gameID)
@@ -62,7 +65,7 @@ func entityToSchemaTask(entity db.TaskEntity) (schemas.BaseTaskWithImgAndID, err
 	}
 	baseTask.ID = entity.ID.UUID
 	if entity.ImageID.Valid {
-		baseTask.ImgURI = configuration.GenImgURI(entity.ImageID.UUID)
+		baseTask.ImgURI = configuration.GenImgURI(entity.ImageID.UUID, uuid.NullUUID{})
 	}
 	// TODO baseTask.LastUpdated =
 	switch entity.TaskKind {
@@ -106,3 +109,127 @@ func dbToSchemasPollDuration(durationType db.PollDurationType, secs int) schemas
 		panic(fmt.Sprintf("unknown poll duration in db %v", durationType))
 	}
 }
+
+// gameToBundleManifest converts the game with its tasks to a bundle manifest.
+//
+// Each image referenced by the game is given an img-request, in the order of appearance.
+// The images that haven't been uploaded are left out.
+// Returns the manifest and the ids of the images, indexed by their img-requests.
+func gameToBundleManifest(
+	ctx context.Context,
+	tx pgx.Tx,
+	gameEntity db.GameEntity,
+	uploaded map[uuid.UUID]bool,
+) (schemas.GameBundleManifest, []uuid.UUID, error) {
+	imgIDs := make([]uuid.UUID, 0)
+	imgReqs := make(map[uuid.UUID]api.ImgRequest)
+	imgReqFor := func(imgID uuid.NullUUID) *api.ImgRequest {
+		req := api.ImgRequest(-1)
+		if !imgID.Valid || !uploaded[imgID.UUID] {
+			return &req
+		}
+		if r, ok := imgReqs[imgID.UUID]; ok {
+			req = r
+			return &req
+		}
+		req = api.ImgRequest(len(imgIDs))
+		imgReqs[imgID.UUID] = req
+		imgIDs = append(imgIDs, imgID.UUID)
+		return &req
+	}
+
+	taskEntities, err := db.GetGameTasksByID(ctx, tx, gameEntity.ID.UUID)
+	if err != nil {
+		return schemas.GameBundleManifest{}, nil, api.ErrorFromConverters{
+			ApiError:   api.Errorf(api.ErrInternal, "internal error"),
+			StatusCode: http.StatusInternalServerError,
+			LogMessage: fmt.Sprintf("failed to get tasks for game with id %v with err: %v", gameEntity.ID.UUID, err),
+		}
+	}
+
+	game := schemas.FullGameInfo{
+		Name:        &gameEntity.Name,
+		Description: &gameEntity.Description,
+		ImgRequest:  imgReqFor(gameEntity.ImageID),
+	}
+	tasks := make([]schemas.BaseTaskWithImgRequest, 0, len(taskEntities))
+	for _, e := range taskEntities {
+		t, err := entityToBundleTask(ctx, tx, e, imgReqFor(e.ImageID))
+		if err != nil {
+			return schemas.GameBundleManifest{}, nil, err
+		}
+		tasks = append(tasks, t)
+	}
+	game.Tasks = &tasks
+
+	images := make([]schemas.GameBundleImage, len(imgIDs))
+	for i := range imgIDs {
+		req := api.ImgRequest(i)
+		file := bundleImageFile(req)
+		images[i] = schemas.GameBundleImage{ImgRequest: &req, File: &file}
+	}
+
+	version := configuration.GameBundleVersion
+	return schemas.GameBundleManifest{Version: &version, Game: &game, Images: &images}, imgIDs, nil
+}
+
+func entityToBundleTask(
+	ctx context.Context,
+	tx pgx.Tx,
+	entity db.TaskEntity,
+	imgReq *api.ImgRequest,
+) (schemas.BaseTaskWithImgRequest, error) {
+	kind := schemas.TaskType(entity.TaskKind)
+	task := schemas.BaseTaskWithImgRequest{
+		Name:        &entity.Name,
+		Description: &entity.Description,
+		Duration:    &schemas.PollDuration{Kind: schemas.Fixed, Secs: uint16(entity.DurationSeconds)},
+		Type:        &kind,
+		ImgRequest:  imgReq,
+	}
+
+	switch entity.TaskKind {
+	case db.Text, db.Photo:
+		pollDuration := dbToSchemasPollDuration(entity.PollDurationType, entity.PollDurationSeconds)
+		task.PollDuration = &pollDuration
+
+	case db.CheckedText:
+		answerEntity, err := db.GetTextAnswerForTaskByID(ctx, tx, entity.ID.UUID)
+		if err != nil {
+			return schemas.BaseTaskWithImgRequest{}, api.ErrorFromConverters{
+				ApiError:   api.Errorf(api.ErrInternal, "internal error"),
+				StatusCode: http.StatusInternalServerError,
+				LogMessage: fmt.Sprintf("failed to get the answer for task with id %v with err: %v", entity.ID.UUID, err),
+			}
+		}
+		task.Answer = &answerEntity.Answer
+
+	case db.Choice:
+		choiceEntities, err := db.GetChoicesForTaskByID(ctx, tx, entity.ID.UUID)
+		if err != nil {
+			return schemas.BaseTaskWithImgRequest{}, api.ErrorFromConverters{
+				ApiError:   api.Errorf(api.ErrInternal, "internal error"),
+				StatusCode: http.StatusInternalServerError,
+				LogMessage: fmt.Sprintf("failed to get the options for task with id %v with err: %v", entity.ID.UUID, err),
+			}
+		}
+		var answerIdx uint8
+		options := make([]string, len(choiceEntities))
+		for i := 0; i < len(choiceEntities); i++ {
+			if choiceEntities[i].Correct {
+				answerIdx = uint8(i)
+			}
+			options[i] = choiceEntities[i].Alternative
+		}
+		task.Options = &options
+		task.AnswerIndex = &answerIdx
+
+	default:
+		return schemas.BaseTaskWithImgRequest{}, api.ErrorFromConverters{
+			ApiError:   api.Errorf(api.ErrInternal, ""),
+			StatusCode: http.StatusInternalServerError,
+			LogMessage: fmt.Sprintf("unknown task kind in database: %s", entity.TaskKind),
+		}
+	}
+	return task, nil
+}
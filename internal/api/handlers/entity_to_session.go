@@ -22,6 +22,7 @@ func gameIDToSessionGame(ctx context.Context, tx pgx.Tx, gameID uuid.UUID) (sess
 			LogMessage: fmt.Sprintf("failed to get game by id: %s", err),
 		}
 	}
+	game.ID = gameEntity.ID
 	game.Name = gameEntity.Name
 	game.Description = gameEntity.Description
 	game.DateChanged = gameEntity.UpdatedAt
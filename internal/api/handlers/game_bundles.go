@@ -0,0 +1,373 @@
+package handlers
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"io"
+	"log/slog"
+	"net/http"
+	"party-buddy/internal/api/base"
+	"party-buddy/internal/api/middleware"
+	"party-buddy/internal/configuration"
+	"party-buddy/internal/db"
+	"party-buddy/internal/imgstore"
+	"party-buddy/internal/schemas"
+	"party-buddy/internal/schemas/api"
+)
+
+// # Game bundles
+//
+// A game bundle is a zip archive holding a game with its tasks: the manifest (manifestFile)
+// is a versioned schemas.GameBundleManifest, and the images it references are stored next to it.
+// The manifest reuses the img-requests of schemas.FullGameInfo to refer to the image files.
+
+const manifestFile = "manifest.json"
+
+var errBundleFileTooLarge = errors.New("the file is too large")
+
+func bundleImageFile(req api.ImgRequest) string {
+	return fmt.Sprintf("images/%d.jpg", req)
+}
+
+type ExportGameHandler struct{}
+
+// ExportGameHandler writes the game as a bundle.
+// Only the owner may export the game since the bundle includes the answers.
+// The game must not include anyone else's tasks with answers either, as a clone sharing them does:
+// the answers of a task are only shown to its owner.
+func (ExportGameHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	gameID, ok := gameIDFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	tx := middleware.TxFromContext(r.Context())
+	authInfo := middleware.AuthInfoFromContext(r.Context())
+
+	gameEntity, err := db.GameByID(r.Context(), tx, gameID)
+	if err != nil {
+		msg := "game not found"
+		base.WriteErrorResponse(w, http.StatusNotFound, api.ErrNotFound, msg)
+		slog.InfoContext(r.Context(), "request failed", "err", msg, "cause", err)
+		return
+	}
+
+	if gameEntity.OwnerID.UUID != authInfo.ID {
+		msg := "only the owner may export the game"
+		base.WriteErrorResponse(w, http.StatusForbidden, api.ErrOnlyOwnerAllowed, msg)
+		slog.InfoContext(r.Context(), "request failed", "err", msg)
+		return
+	}
+
+	hidden, err := db.GameHasHiddenAnswers(r.Context(), tx, gameID, authInfo.ID)
+	if err != nil {
+		base.WriteErrorResponse(w, http.StatusInternalServerError, api.ErrInternal, "internal error")
+		slog.ErrorContext(r.Context(), "failed to check the owners of the game tasks", "err", err)
+		return
+	}
+	if hidden {
+		msg := "the game has tasks with answers owned by someone else"
+		base.WriteErrorResponse(w, http.StatusForbidden, api.ErrOnlyOwnerAllowed, msg)
+		slog.InfoContext(r.Context(), "request failed", "err", msg)
+		return
+	}
+
+	uploaded, err := uploadedGameImages(r, gameEntity)
+	if err != nil {
+		base.WriteErrorResponse(w, http.StatusInternalServerError, api.ErrInternal, "internal error")
+		slog.ErrorContext(r.Context(), "failed to get the game images", "err", err)
+		return
+	}
+
+	manifest, imgIDs, err := gameToBundleManifest(r.Context(), tx, gameEntity, uploaded)
+	if err != nil {
+		var errConv api.ErrorFromConverters
+		errors.As(err, &errConv)
+		slog.ErrorContext(r.Context(), "request failed", "err", errConv)
+		base.WriteErrorResponse(w, errConv.StatusCode, errConv.ApiError.Kind, errConv.ApiError.Message)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="game-%s.zip"`, gameID))
+	w.WriteHeader(http.StatusOK)
+
+	// from here on the errors can only be logged.
+	// the archive is left without its central directory, so the client won't take it for a complete one
+	zw := zip.NewWriter(w)
+	mw, err := zw.Create(manifestFile)
+	if err == nil {
+		encoder := json.NewEncoder(mw)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(manifest)
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "failed to write the bundle manifest", "err", err)
+		return
+	}
+
+	storage := middleware.ImgStorageFromContext(r.Context())
+	for i, imgID := range imgIDs {
+		if err = writeBundleImage(r, zw, bundleImageFile(api.ImgRequest(i)), imgID, storage); err != nil {
+			slog.ErrorContext(r.Context(), "failed to write a bundle image", "img_id", imgID, "err", err)
+			return
+		}
+	}
+
+	if err = zw.Close(); err != nil {
+		slog.ErrorContext(r.Context(), "failed to write the bundle", "err", err)
+		return
+	}
+	slog.InfoContext(r.Context(), "request handled")
+}
+
+// uploadedGameImages returns the set of the uploaded images used by the game or its tasks.
+func uploadedGameImages(r *http.Request, gameEntity db.GameEntity) (map[uuid.UUID]bool, error) {
+	tx := middleware.TxFromContext(r.Context())
+
+	taskEntities, err := db.GetGameTasksByID(r.Context(), tx, gameEntity.ID.UUID)
+	if err != nil {
+		return nil, err
+	}
+	imgIDs := []uuid.NullUUID{gameEntity.ImageID}
+	for _, t := range taskEntities {
+		imgIDs = append(imgIDs, t.ImageID)
+	}
+
+	imgEntities, err := db.GetImageMetadataByIDs(tx, r.Context(), imgIDs)
+	if err != nil {
+		return nil, err
+	}
+	uploaded := make(map[uuid.UUID]bool)
+	for _, img := range imgEntities {
+		if img.Uploaded {
+			uploaded[img.ID.UUID] = true
+		}
+	}
+	return uploaded, nil
+}
+
+func writeBundleImage(
+	r *http.Request,
+	zw *zip.Writer,
+	name string,
+	imgID uuid.UUID,
+	storage imgstore.Storage,
+) error {
+	img, err := storage.Get(r.Context(), imgID)
+	if err != nil {
+		return err
+	}
+	defer img.Close()
+
+	// the JPEG images are compressed already
+	fw, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Store})
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(fw, img)
+	return err
+}
+
+type ImportGameHandler struct{}
+
+// ImportGameHandler creates a game from a bundle, owned by the client.
+//
+// The manifest is validated with the same rules as the games sent along with the session requests.
+// The images are re-encoded to JPEG, and become read-only.
+// Either the whole game is created, or nothing is.
+func (ImportGameHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, configuration.MaxGameBundleSize))
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			msg := "the bundle is too large"
+			base.WriteErrorResponse(w, http.StatusRequestEntityTooLarge, api.ErrBundleTooLarge, msg)
+			slog.InfoContext(r.Context(), "request failed", "err", msg)
+			return
+		}
+		base.WriteErrorResponse(w, http.StatusInternalServerError, api.ErrInternal, "failed to read request body")
+		slog.ErrorContext(r.Context(), "failed to read request body", "err", err)
+		return
+	}
+
+	manifest, imgs, err := readGameBundle(r, data)
+	if err != nil {
+		var errConv api.ErrorFromConverters
+		errors.As(err, &errConv)
+		slog.InfoContext(r.Context(), "request failed", "err", errConv)
+		base.WriteErrorResponse(w, errConv.StatusCode, errConv.ApiError.Kind, errConv.ApiError.Message)
+		return
+	}
+
+	tx := middleware.TxFromContext(r.Context())
+	authInfo := middleware.AuthInfoFromContext(r.Context())
+	storage := middleware.ImgStorageFromContext(r.Context())
+
+	imgIDs := make(map[api.ImgRequest]uuid.NullUUID)
+	stored := make([]uuid.UUID, 0, len(imgs))
+	// the storage is not a part of the transaction: the images are removed if it fails
+	cleanUp := func() {
+		for _, imgID := range stored {
+			if err := storage.Delete(r.Context(), imgID); err != nil {
+				slog.ErrorContext(r.Context(), "failed to delete an imported image", "img_id", imgID, "err", err)
+			}
+		}
+	}
+
+	for req, img := range imgs {
+		imgID, err := db.CreateImageMetadata(tx, r.Context(), authInfo.ID)
+		if err == nil {
+			err = db.SetImageUploaded(tx, r.Context(), imgID, true)
+		}
+		if err == nil {
+			err = db.SetImageReadOnly(tx, r.Context(), imgID, true)
+		}
+		if err == nil {
+			err = storage.Put(r.Context(), imgID.UUID, bytes.NewReader(img), int64(len(img)))
+		}
+		if err != nil {
+			cleanUp()
+			base.WriteErrorResponse(w, http.StatusInternalServerError, api.ErrInternal, "failed to store image")
+			slog.ErrorContext(r.Context(), "request failed", "err", err)
+			return
+		}
+		stored = append(stored, imgID.UUID)
+		imgIDs[req] = imgID
+	}
+
+	gameID, err := createGame(r.Context(), tx, authInfo.ID, *manifest.Game, imgIDs)
+	if err != nil {
+		cleanUp()
+		var errConv api.ErrorFromConverters
+		errors.As(err, &errConv)
+		slog.ErrorContext(r.Context(), "request failed", "err", errConv)
+		base.WriteErrorResponse(w, errConv.StatusCode, errConv.ApiError.Kind, errConv.ApiError.Message)
+		return
+	}
+
+	if err = tx.Commit(r.Context()); err != nil {
+		cleanUp()
+		base.WriteErrorResponse(w, http.StatusInternalServerError, api.ErrInternal, "failed to import game")
+		slog.ErrorContext(r.Context(), "request failed", "err", err)
+		return
+	}
+
+	encoder := json.NewEncoder(w)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = encoder.Encode(api.GameImportResponse{GameID: gameID})
+	slog.InfoContext(r.Context(), "request handled")
+}
+
+// readGameBundle parses and validates the bundle.
+// Returns the manifest and the images it references, re-encoded to JPEG, by their img-requests.
+func readGameBundle(r *http.Request, data []byte) (schemas.GameBundleManifest, map[api.ImgRequest][]byte, error) {
+	invalid := func(format string, a ...any) error {
+		msg := fmt.Sprintf(format, a...)
+		return api.ErrorFromConverters{
+			ApiError:   api.Errorf(api.ErrBundleInvalid, msg),
+			StatusCode: http.StatusBadRequest,
+			LogMessage: msg,
+		}
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return schemas.GameBundleManifest{}, nil, invalid("the bundle is not a zip archive")
+	}
+	files := make(map[string]*zip.File)
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+
+	f, ok := files[manifestFile]
+	if !ok {
+		return schemas.GameBundleManifest{}, nil, invalid("the bundle has no %s", manifestFile)
+	}
+	manifestData, err := readBundleFile(f, configuration.MaxGameManifestSize)
+	if err != nil {
+		return schemas.GameBundleManifest{}, nil, invalid("failed to read %s: %s", manifestFile, err)
+	}
+
+	var manifest schemas.GameBundleManifest
+	if err = api.Parse(r.Context(), &manifest, manifestData, false); err != nil {
+		var dto api.Error
+		errors.As(err, &dto)
+		return schemas.GameBundleManifest{}, nil, api.ErrorFromConverters{
+			ApiError:   dto,
+			StatusCode: http.StatusBadRequest,
+			LogMessage: dto.Message,
+		}
+	}
+
+	imgFiles := make(map[api.ImgRequest]string)
+	for _, img := range *manifest.Images {
+		imgFiles[*img.ImgRequest] = *img.File
+	}
+
+	imgReqs := []api.ImgRequest{*manifest.Game.ImgRequest}
+	for _, task := range *manifest.Game.Tasks {
+		imgReqs = append(imgReqs, *task.ImgRequest)
+	}
+
+	imgs := make(map[api.ImgRequest][]byte)
+	for _, req := range imgReqs {
+		if _, done := imgs[req]; req < 0 || done {
+			continue
+		}
+
+		name, ok := imgFiles[req]
+		if !ok {
+			return schemas.GameBundleManifest{}, nil, invalid("no image for img-request %d", req)
+		}
+		f, ok := files[name]
+		if !ok {
+			return schemas.GameBundleManifest{}, nil, invalid("the bundle has no %s", name)
+		}
+		imgData, err := readBundleFile(f, configuration.MaxImageSize)
+		if errors.Is(err, errBundleFileTooLarge) {
+			return schemas.GameBundleManifest{}, nil, api.ErrorFromConverters{
+				ApiError:   api.Errorf(api.ErrImgTooLarge, "the image %s is too large", name),
+				StatusCode: http.StatusRequestEntityTooLarge,
+				LogMessage: fmt.Sprintf("failed to read %s: %s", name, err),
+			}
+		}
+		if err != nil {
+			return schemas.GameBundleManifest{}, nil, invalid("failed to read %s: %s", name, err)
+		}
+		buf, err := toJPEG(imgData)
+		if err != nil {
+			return schemas.GameBundleManifest{}, nil, err
+		}
+		imgs[req] = buf.Bytes()
+	}
+
+	return manifest, imgs, nil
+}
+
+// readBundleFile reads a file of the bundle, failing if it's larger than limit.
+func readBundleFile(f *zip.File, limit int64) ([]byte, error) {
+	if f.UncompressedSize64 > uint64(limit) {
+		return nil, errBundleFileTooLarge
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	// the header might lie about the size
+	data, err := io.ReadAll(io.LimitReader(rc, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > limit {
+		return nil, errBundleFileTooLarge
+	}
+	return data, nil
+}
@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"party-buddy/internal/schemas/api"
+	"party-buddy/internal/validate"
+	"testing"
+)
+
+const testManifest = `{
+  "version": 1,
+  "game": {
+    "name": "Quiz",
+    "description": "A test game",
+    "img-request": 0,
+    "tasks": [
+      {
+        "name": "Capital",
+        "description": "The capital of France?",
+        "duration": {"kind": "fixed", "secs": 30},
+        "type": "checked-text",
+        "img-request": -1,
+        "answer": "PARIS"
+      },
+      {
+        "name": "Colour",
+        "description": "The colour of the sky?",
+        "duration": {"kind": "fixed", "secs": 15},
+        "type": "choice",
+        "img-request": 0,
+        "options": ["red", "blue", "green", "black"],
+        "answer-idx": 1
+      }
+    ]
+  },
+  "images": [{"img-request": 0, "file": "images/0.png"}]
+}`
+
+func makeBundle(t *testing.T, files map[string][]byte) []byte {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, data := range files {
+		fw, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = fw.Write(data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func makePNG(t *testing.T) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	img.Set(1, 1, color.White)
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func bundleRequest() *http.Request {
+	r := httptest.NewRequest(http.MethodPost, "/api/v1/games/import", nil)
+	return r.WithContext(validate.NewContext(r.Context(), validate.NewValidationFactory()))
+}
+
+func TestReadGameBundle(t *testing.T) {
+	data := makeBundle(t, map[string][]byte{
+		manifestFile:   []byte(testManifest),
+		"images/0.png": makePNG(t),
+	})
+
+	manifest, imgs, err := readGameBundle(bundleRequest(), data)
+	if err != nil {
+		t.Fatalf("readGameBundle failed: %v", err)
+	}
+	if got := len(*manifest.Game.Tasks); got != 2 {
+		t.Errorf("got %d tasks, want 2", got)
+	}
+	if len(imgs) != 1 {
+		t.Fatalf("got %d images, want 1", len(imgs))
+	}
+	if _, err = jpeg.Decode(bytes.NewReader(imgs[0])); err != nil {
+		t.Errorf("the image is not re-encoded to JPEG: %v", err)
+	}
+}
+
+func TestReadGameBundleInvalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string][]byte
+		want  api.ErrorKind
+	}{
+		{
+			name:  "no manifest",
+			files: map[string][]byte{"images/0.png": makePNG(t)},
+			want:  api.ErrBundleInvalid,
+		},
+		{
+			name:  "missing image",
+			files: map[string][]byte{manifestFile: []byte(testManifest)},
+			want:  api.ErrBundleInvalid,
+		},
+		{
+			name: "unknown version",
+			files: map[string][]byte{
+				manifestFile:   bytes.Replace([]byte(testManifest), []byte(`"version": 1`), []byte(`"version": 2`), 1),
+				"images/0.png": makePNG(t),
+			},
+			want: api.ErrMalformedRequest,
+		},
+		{
+			name: "invalid task",
+			files: map[string][]byte{
+				manifestFile:   bytes.Replace([]byte(testManifest), []byte(`"answer-idx": 1`), []byte(`"answer-idx": 7`), 1),
+				"images/0.png": makePNG(t),
+			},
+			want: api.ErrMalformedRequest,
+		},
+		{
+			name: "not an image",
+			files: map[string][]byte{
+				manifestFile:   []byte(testManifest),
+				"images/0.png": []byte("hello"),
+			},
+			want: api.ErrImgFormatUnsupported,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := readGameBundle(bundleRequest(), makeBundle(t, tt.files))
+			var errConv api.ErrorFromConverters
+			if !errors.As(err, &errConv) {
+				t.Fatalf("got %v, want an api error", err)
+			}
+			if errConv.ApiError.Kind != tt.want {
+				t.Errorf("got %s, want %s", errConv.ApiError.Kind, tt.want)
+			}
+		})
+	}
+
+	_, _, err := readGameBundle(bundleRequest(), []byte("not a zip"))
+	var errConv api.ErrorFromConverters
+	if !errors.As(err, &errConv) || errConv.ApiError.Kind != api.ErrBundleInvalid {
+		t.Errorf("got %v for a non-zip bundle, want %s", err, api.ErrBundleInvalid)
+	}
+}
@@ -1,33 +1,48 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
-	"log"
+	"github.com/jackc/pgx/v5"
+	"io"
+	"log/slog"
 	"net/http"
 	"party-buddy/internal/api/base"
 	"party-buddy/internal/api/middleware"
+	"party-buddy/internal/db"
+	"party-buddy/internal/schemas"
 	"party-buddy/internal/schemas/api"
 )
 
-type GetGameHandler struct{}
-
-func (GetGameHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+// gameIDFromRequest extracts the game id from the route.
+// On failure writes an error response and returns false.
+func gameIDFromRequest(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
 	val, ok := mux.Vars(r)["game-id"]
 	if !ok {
 		msg := "game-id not provided"
 		base.WriteErrorResponse(w, http.StatusNotFound, api.ErrNotFound, msg)
-		log.Printf("request: %v %s -> err: %v", r.Method, r.URL, msg)
-		return
+		slog.InfoContext(r.Context(), "request failed", "err", msg)
+		return uuid.UUID{}, false
 	}
-
 	gameID, err := uuid.Parse(val)
 	if err != nil {
 		msg := "invalid game-id"
 		base.WriteErrorResponse(w, http.StatusNotFound, api.ErrNotFound, msg)
-		log.Printf("request: %v %s -> err: %v", r.Method, r.URL, msg)
+		slog.InfoContext(r.Context(), "request failed", "err", msg)
+		return uuid.UUID{}, false
+	}
+	return gameID, true
+}
+
+type GetGameHandler struct{}
+
+func (GetGameHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	gameID, ok := gameIDFromRequest(w, r)
+	if !ok {
 		return
 	}
 
@@ -36,7 +51,7 @@ func (GetGameHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		var errConv api.ErrorFromConverters
 		errors.As(err, &errConv)
-		log.Printf("request: %v %s -> err: %v", r.Method, r.URL, errConv)
+		slog.InfoContext(r.Context(), "request failed", "err", errConv)
 		base.WriteErrorResponse(w, errConv.StatusCode, errConv.ApiError.Kind, errConv.ApiError.Message)
 		return
 	}
@@ -45,5 +60,140 @@ func (GetGameHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	_ = encoder.Encode(gameInfo)
-	log.Printf("request: %v %s -> OK", r.Method, r.URL)
+	slog.InfoContext(r.Context(), "request handled")
+}
+
+type CloneGameHandler struct{}
+
+// CloneGameHandler copies the game under the client.
+//
+// The clone either shares the tasks with the original game or gets its own copies of them.
+// The answers of a task are only shown to its owner, so the tasks with answers owned by someone else
+// are shared even if copies are requested: copying them would hand the answers over with the copies.
+// Either way the images are not copied: both games refer to the same ones,
+// which become read-only so that the original owner can't change them under the clone.
+// The clone records the game it has been forked from.
+func (CloneGameHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	gameID, ok := gameIDFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	bytes, err := io.ReadAll(r.Body)
+	if err != nil {
+		base.WriteErrorResponse(w, http.StatusInternalServerError, api.ErrInternal, "failed to read request body")
+		slog.ErrorContext(r.Context(), "failed to read request body", "err", err)
+		return
+	}
+
+	var req schemas.CloneGameRequest
+	if len(bytes) > 0 {
+		if err = api.Parse(r.Context(), &req, bytes, false); err != nil {
+			var dto api.Error
+			errors.As(err, &dto)
+			base.WriteErrorResponse(w, http.StatusBadRequest, dto.Kind, dto.Message)
+			slog.InfoContext(r.Context(), "request failed", "err", dto)
+			return
+		}
+	}
+	mode := schemas.CloneShared
+	if req.Tasks != nil {
+		mode = *req.Tasks
+	}
+
+	tx := middleware.TxFromContext(r.Context())
+	authInfo := middleware.AuthInfoFromContext(r.Context())
+
+	forkID, err := cloneGame(r.Context(), tx, gameID, authInfo.ID, mode)
+	if err != nil {
+		var errConv api.ErrorFromConverters
+		errors.As(err, &errConv)
+		if errConv.StatusCode == http.StatusInternalServerError {
+			slog.ErrorContext(r.Context(), "request failed", "err", errConv)
+		} else {
+			slog.InfoContext(r.Context(), "request failed", "err", errConv)
+		}
+		base.WriteErrorResponse(w, errConv.StatusCode, errConv.ApiError.Kind, errConv.ApiError.Message)
+		return
+	}
+
+	if err = tx.Commit(r.Context()); err != nil {
+		base.WriteErrorResponse(w, http.StatusInternalServerError, api.ErrInternal, "failed to clone game")
+		slog.ErrorContext(r.Context(), "request failed", "err", err)
+		return
+	}
+
+	encoder := json.NewEncoder(w)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = encoder.Encode(api.GameCloneResponse{GameID: forkID})
+	slog.InfoContext(r.Context(), "request handled")
+}
+
+// cloneGame copies the game with its task list under the owner and returns the id of the clone.
+// If mode is schemas.CloneCopied, the tasks are copied as well unless they have answers owned by someone else.
+func cloneGame(
+	ctx context.Context,
+	tx pgx.Tx,
+	gameID uuid.UUID,
+	owner uuid.UUID,
+	mode schemas.CloneTasks,
+) (uuid.UUID, error) {
+	internalErr := func(format string, a ...any) error {
+		return api.ErrorFromConverters{
+			ApiError:   api.Errorf(api.ErrInternal, "internal error"),
+			StatusCode: http.StatusInternalServerError,
+			LogMessage: fmt.Sprintf(format, a...),
+		}
+	}
+
+	forkID, err := db.ForkGame(ctx, tx, gameID, owner)
+	var notFound db.RecordNotFound
+	if errors.As(err, &notFound) {
+		return uuid.UUID{}, api.ErrorFromConverters{
+			ApiError:   api.Errorf(api.ErrNotFound, "game not found"),
+			StatusCode: http.StatusNotFound,
+			LogMessage: fmt.Sprintf("game with id %v not found", gameID),
+		}
+	}
+	if err != nil {
+		return uuid.UUID{}, internalErr("failed to fork game with id %v: %s", gameID, err)
+	}
+
+	refs, err := db.GetGameTaskRefs(ctx, tx, gameID)
+	if err != nil {
+		return uuid.UUID{}, internalErr("failed to get tasks for game with id %v: %s", gameID, err)
+	}
+	taskEntities, err := db.GetGameTasksByID(ctx, tx, gameID)
+	if err != nil {
+		return uuid.UUID{}, internalErr("failed to get tasks for game with id %v: %s", gameID, err)
+	}
+	tasks := make(map[uuid.UUID]db.TaskEntity, len(taskEntities))
+	for _, e := range taskEntities {
+		tasks[e.ID.UUID] = e
+	}
+
+	for _, ref := range refs {
+		taskID := ref.TaskID.UUID
+		if mode == schemas.CloneCopied && copyableTask(tasks[taskID], owner) {
+			if taskID, err = db.CopyTask(ctx, tx, taskID, owner); err != nil {
+				return uuid.UUID{}, internalErr("failed to copy task with id %v: %s", ref.TaskID.UUID, err)
+			}
+		}
+		if err = db.CreateGameTask(ctx, tx, forkID, taskID, ref.TaskIndex); err != nil {
+			return uuid.UUID{}, internalErr("failed to add task to game: %s", err)
+		}
+	}
+
+	if err = db.SetGameImagesReadOnly(tx, ctx, forkID); err != nil {
+		return uuid.UUID{}, internalErr("failed to make the images of game with id %v read-only: %s", forkID, err)
+	}
+
+	return forkID, nil
+}
+
+// copyableTask returns true if the task may be copied by the client:
+// either they own it, or it has no answers to hide from them.
+func copyableTask(task db.TaskEntity, client uuid.UUID) bool {
+	return task.OwnerID.UUID == client || !task.HasAnswers()
 }
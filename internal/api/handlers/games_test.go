@@ -0,0 +1,27 @@
+package handlers
+
+import (
+	"party-buddy/internal/db"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestCopyableTask(t *testing.T) {
+	author, cloner := uuid.New(), uuid.New()
+
+	for kind, want := range map[db.TaskKind]bool{
+		db.Photo:       true,
+		db.Text:        true,
+		db.CheckedText: false,
+		db.Choice:      false,
+	} {
+		task := db.TaskEntity{OwnerID: uuid.NullUUID{UUID: author, Valid: true}, TaskKind: kind}
+		if got := copyableTask(task, cloner); got != want {
+			t.Errorf("%s task of another author: got %v, want %v", kind, got, want)
+		}
+		if !copyableTask(task, author) {
+			t.Errorf("%s task: the author may not copy it", kind)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"party-buddy/internal/health"
+	"party-buddy/internal/schemas/api"
+)
+
+func writeHealthResponse(w http.ResponseWriter, status int, resp api.HealthResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+type HealthzHandler struct{}
+
+// HealthzHandler is the liveness probe: it succeeds as long as the server handles requests.
+func (h HealthzHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	writeHealthResponse(w, http.StatusOK, api.HealthResponse{Status: api.HealthOK})
+}
+
+type ReadyzHandler struct {
+	Checker *health.Checker
+}
+
+// ReadyzHandler is the readiness probe.
+// It runs every check and responds with 503 if any check fails or the server is draining.
+func (h ReadyzHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	resp := api.HealthResponse{
+		Status: api.HealthOK,
+		Checks: make(map[string]api.HealthCheck),
+	}
+
+	for name, err := range h.Checker.Run(r.Context()) {
+		if err == nil {
+			resp.Checks[name] = api.HealthCheck{Status: api.HealthOK}
+			continue
+		}
+
+		// the probe is public: the error only goes to the log
+		resp.Checks[name] = api.HealthCheck{Status: api.HealthFail}
+		resp.Status = api.HealthFail
+		slog.WarnContext(r.Context(), "readiness check failed", "check", name, "err", err)
+	}
+
+	if h.Checker.Draining() {
+		resp.Status = api.HealthDraining
+	}
+
+	status := http.StatusOK
+	if resp.Status != api.HealthOK {
+		status = http.StatusServiceUnavailable
+	}
+	writeHealthResponse(w, status, resp)
+}
@@ -1,35 +1,128 @@
 package handlers
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
+	"image"
 	"image/jpeg"
-	"log"
+	_ "image/png"
+	"io"
+	"log/slog"
 	"net/http"
 	"party-buddy/internal/api/base"
 	"party-buddy/internal/api/middleware"
+	"party-buddy/internal/configuration"
 	"party-buddy/internal/db"
+	"party-buddy/internal/imgstore"
+	"party-buddy/internal/metrics"
 	"party-buddy/internal/schemas/api"
-)
+	"party-buddy/internal/session"
 
-type GetImageHandler struct{}
+	"golang.org/x/exp/slices"
+)
 
-// GetImageHandler get an image from fs.
-// Before reading file it uses r.Context() to get transaction and context to check if image is uploaded
-func (g GetImageHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	val, ok := vars["img-id"]
+// imgIDFromRequest extracts the image id from the route.
+// On failure writes an error response and returns false.
+func imgIDFromRequest(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	val, ok := mux.Vars(r)["img-id"]
 	if !ok {
 		msg := "img-id not provided"
 		base.WriteErrorResponse(w, http.StatusNotFound, api.ErrNotFound, msg)
-		log.Printf("request: %v %s -> err: %v", r.Method, r.URL, msg)
-		return
+		slog.InfoContext(r.Context(), "request failed", "err", msg)
+		return uuid.UUID{}, false
 	}
 	imgID, err := uuid.Parse(val)
 	if err != nil {
 		msg := "invalid url"
 		base.WriteErrorResponse(w, http.StatusNotFound, api.ErrNotFound, msg)
-		log.Printf("request: %v %s -> err: %v", r.Method, r.URL, msg)
+		slog.InfoContext(r.Context(), "request failed", "err", msg)
+		return uuid.UUID{}, false
+	}
+	return imgID, true
+}
+
+// canAccessImage checks if the authenticated client may download the image.
+//
+// The rules are:
+//   - the owner can always see their own images;
+//   - an image used by a public game (as a game or task image) is visible to everyone;
+//   - an image referenced by a live session is visible to the players of that session.
+func canAccessImage(r *http.Request, imgMetadata db.ImageEntity) (bool, error) {
+	authInfo := middleware.AuthInfoFromContext(r.Context())
+	if imgMetadata.OwnerID.Valid && imgMetadata.OwnerID.UUID == authInfo.ID {
+		return true, nil
+	}
+
+	tx := middleware.TxFromContext(r.Context())
+
+	public, err := db.IsImagePublic(tx, r.Context(), imgMetadata.ID)
+	if err != nil {
+		return false, err
+	}
+	if public {
+		return true, nil
+	}
+
+	sids, err := db.GetSessionsByImageRef(r.Context(), tx, imgMetadata.ID.UUID)
+	if err != nil {
+		return false, err
+	}
+
+	manager := middleware.ManagerFromContext(r.Context())
+	clientID := session.ClientID(authInfo.ID)
+	allowed := false
+	manager.Storage().Atomically(func(s *session.UnsafeStorage) {
+		for _, sid := range sids {
+			if s.ClientIsPlayer(session.SessionID(sid), clientID) {
+				allowed = true
+				return
+			}
+		}
+	})
+
+	return allowed, nil
+}
+
+// canAccessSignedImage checks if the image may be downloaded with a signed URI.
+//
+// The signature itself proves the URI was handed out to someone allowed to see the image.
+// URIs scoped to a session additionally stop working once the session ends.
+func canAccessSignedImage(r *http.Request, imgMetadata db.ImageEntity, access middleware.SignedImgAccess) (bool, error) {
+	if !access.Sid.Valid {
+		return true, nil
+	}
+
+	tx := middleware.TxFromContext(r.Context())
+	sids, err := db.GetSessionsByImageRef(r.Context(), tx, imgMetadata.ID.UUID)
+	if err != nil {
+		return false, err
+	}
+	if !slices.Contains(sids, access.Sid.UUID) {
+		return false, nil
+	}
+
+	manager := middleware.ManagerFromContext(r.Context())
+	live := false
+	manager.Storage().Atomically(func(s *session.UnsafeStorage) {
+		live = s.SessionExists(session.SessionID(access.Sid.UUID))
+	})
+
+	return live, nil
+}
+
+type GetImageHandler struct{}
+
+// GetImageHandler gets an image from the image storage.
+// Before reading the image it uses r.Context() to get transaction and context to check if image is uploaded.
+//
+// The request is authorized either by a signed URI (see canAccessSignedImage) or by the bearer token (see canAccessImage).
+// If the storage supports presigned URLs, the client is redirected there instead.
+func (g GetImageHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	imgID, ok := imgIDFromRequest(w, r)
+	if !ok {
 		return
 	}
 
@@ -40,29 +133,198 @@ func (g GetImageHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		msg := "not found"
 		base.WriteErrorResponse(w, http.StatusNotFound, api.ErrNotFound, msg)
-		log.Printf("request: %v %s -> err: %v", r.Method, r.URL, msg)
+		slog.InfoContext(r.Context(), "request failed", "err", msg)
 		return
 	}
 
-	// TODO: middleware.AuthInfoFromContext(r.Context())
+	var allowed bool
+	if access, ok := middleware.SignedImgAccessFromContext(r.Context()); ok {
+		allowed, err = canAccessSignedImage(r, imgMetadata, access)
+	} else {
+		allowed, err = canAccessImage(r, imgMetadata)
+	}
+	if err != nil {
+		base.WriteErrorResponse(w, http.StatusInternalServerError, api.ErrInternal, "internal error")
+		slog.ErrorContext(r.Context(), "failed to check image access", "err", err)
+		return
+	}
+	if !allowed {
+		msg := "not enough privileges to access the image"
+		base.WriteErrorResponse(w, http.StatusForbidden, api.ErrNotEnoughPrivileges, msg)
+		slog.InfoContext(r.Context(), "request failed", "err", msg)
+		return
+	}
 
 	if !imgMetadata.Uploaded {
 		msg := "image is not uploaded"
 		base.WriteErrorResponse(w, http.StatusNotFound, api.ErrNotFound, msg)
-		log.Printf("request: %v %s -> err: %v", r.Method, r.URL, msg)
+		slog.InfoContext(r.Context(), "request failed", "err", msg)
 		return
 	}
 
-	img, err := db.GetImageFromFS(imgMetadata.ID.UUID)
+	storage := middleware.ImgStorageFromContext(r.Context())
+
+	if presigner, ok := storage.(imgstore.Presigner); ok {
+		u, err := presigner.PresignGet(r.Context(), imgID)
+		if err != nil {
+			msg := "failed to presign image url"
+			base.WriteErrorResponse(w, http.StatusInternalServerError, api.ErrInternal, msg)
+			slog.ErrorContext(r.Context(), "request failed", "err", msg, "cause", err)
+			return
+		}
+		http.Redirect(w, r, u.String(), http.StatusTemporaryRedirect)
+		slog.InfoContext(r.Context(), "request redirected")
+		return
+	}
+
+	img, err := storage.Get(r.Context(), imgID)
 	if err != nil {
 		msg := "image not found in storage"
 		base.WriteErrorResponse(w, http.StatusInternalServerError, api.ErrInternal, msg)
-		log.Printf("request: %v %s -> err: %v", r.Method, r.URL, msg)
+		slog.ErrorContext(r.Context(), "request failed", "err", msg, "cause", err)
 		return
 	}
+	defer img.Close()
 
-	w.Header().Set("Content-Type", "image/jpeg")
+	w.Header().Set("Content-Type", imgstore.ContentType)
 	w.WriteHeader(http.StatusOK)
-	_ = jpeg.Encode(w, img, nil)
-	log.Printf("request: %v %s -> OK", r.Method, r.URL)
+	n, _ := io.Copy(w, img)
+	metrics.ImgDownloadedBytes.Add(float64(n))
+	slog.InfoContext(r.Context(), "request handled")
+}
+
+type UploadImageHandler struct{}
+
+// UploadImageHandler stores an image sent in the request body.
+// JPEG and PNG images are accepted; either is re-encoded to JPEG before being stored.
+//
+// Only the owner of the image metadata may upload the image, and only until it becomes read-only.
+func (u UploadImageHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	imgID, ok := imgIDFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	tx := middleware.TxFromContext(r.Context())
+	authInfo := middleware.AuthInfoFromContext(r.Context())
+
+	imgMetadata, err := db.GetImageMetadataByID(tx, r.Context(), uuid.NullUUID{UUID: imgID, Valid: true})
+	if err != nil {
+		msg := "not found"
+		base.WriteErrorResponse(w, http.StatusNotFound, api.ErrNotFound, msg)
+		slog.InfoContext(r.Context(), "request failed", "err", msg)
+		return
+	}
+
+	if imgMetadata.OwnerID.UUID != authInfo.ID {
+		msg := "only the owner may upload the image"
+		base.WriteErrorResponse(w, http.StatusForbidden, api.ErrOnlyOwnerAllowed, msg)
+		slog.InfoContext(r.Context(), "request failed", "err", msg)
+		return
+	}
+
+	if imgMetadata.ReadOnly {
+		msg := "the image is read-only"
+		base.WriteErrorResponse(w, http.StatusForbidden, api.ErrImgUploadForbidden, msg)
+		slog.InfoContext(r.Context(), "request failed", "err", msg)
+		return
+	}
+
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, configuration.MaxImageSize))
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			msg := "the image is too large"
+			base.WriteErrorResponse(w, http.StatusRequestEntityTooLarge, api.ErrImgTooLarge, msg)
+			slog.InfoContext(r.Context(), "request failed", "err", msg)
+			return
+		}
+		base.WriteErrorResponse(w, http.StatusInternalServerError, api.ErrInternal, "failed to read request body")
+		slog.ErrorContext(r.Context(), "failed to read request body", "err", err)
+		return
+	}
+	if len(data) == 0 {
+		msg := "no image provided"
+		base.WriteErrorResponse(w, http.StatusBadRequest, api.ErrImgNotProvided, msg)
+		slog.InfoContext(r.Context(), "request failed", "err", msg)
+		return
+	}
+
+	buf, err := toJPEG(data)
+	if err != nil {
+		var apiErr api.ErrorFromConverters
+		errors.As(err, &apiErr)
+		base.WriteErrorResponse(w, apiErr.StatusCode, apiErr.ApiError.Kind, apiErr.ApiError.Message)
+		if apiErr.StatusCode == http.StatusInternalServerError {
+			slog.ErrorContext(r.Context(), "request failed", "err", apiErr.LogMessage)
+		} else {
+			slog.InfoContext(r.Context(), "request failed", "err", apiErr.ApiError.Message, "cause", apiErr.LogMessage)
+		}
+		return
+	}
+
+	storage := middleware.ImgStorageFromContext(r.Context())
+	size := buf.Len()
+	if err = storage.Put(r.Context(), imgID, buf, int64(size)); err != nil {
+		base.WriteErrorResponse(w, http.StatusInternalServerError, api.ErrInternal, "failed to store image")
+		slog.ErrorContext(r.Context(), "request failed", "err", err)
+		return
+	}
+
+	if err = db.SetImageUploaded(tx, r.Context(), imgMetadata.ID, true); err != nil {
+		base.WriteErrorResponse(w, http.StatusInternalServerError, api.ErrInternal, "failed to store image")
+		slog.ErrorContext(r.Context(), "request failed", "err", err)
+		return
+	}
+	if err = tx.Commit(r.Context()); err != nil {
+		base.WriteErrorResponse(w, http.StatusInternalServerError, api.ErrInternal, "failed to store image")
+		slog.ErrorContext(r.Context(), "request failed", "err", err)
+		return
+	}
+
+	metrics.ImgUploadedBytes.Add(float64(size))
+	w.WriteHeader(http.StatusNoContent)
+	slog.InfoContext(r.Context(), "request handled")
+}
+
+// toJPEG decodes a JPEG or PNG image and re-encodes it to JPEG.
+// Images with more than configuration.MaxImagePixels pixels are rejected without being decoded.
+func toJPEG(data []byte) (*bytes.Buffer, error) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err == nil && int64(cfg.Width)*int64(cfg.Height) > configuration.MaxImagePixels {
+		return nil, api.ErrorFromConverters{
+			ApiError:   api.Errorf(api.ErrImgTooLarge, "the image has too many pixels"),
+			StatusCode: http.StatusRequestEntityTooLarge,
+			LogMessage: fmt.Sprintf("the image is %dx%d", cfg.Width, cfg.Height),
+		}
+	}
+
+	var img image.Image
+	if err == nil {
+		img, _, err = image.Decode(bytes.NewReader(data))
+	}
+	if err != nil {
+		if errors.Is(err, image.ErrFormat) {
+			return nil, api.ErrorFromConverters{
+				ApiError:   api.Errorf(api.ErrImgFormatUnsupported, "only JPEG and PNG images are supported"),
+				StatusCode: http.StatusUnsupportedMediaType,
+				LogMessage: err.Error(),
+			}
+		}
+		return nil, api.ErrorFromConverters{
+			ApiError:   api.Errorf(api.ErrImgMalformed, "the image is malformed"),
+			StatusCode: http.StatusBadRequest,
+			LogMessage: err.Error(),
+		}
+	}
+
+	var buf bytes.Buffer
+	if err = jpeg.Encode(&buf, img, nil); err != nil {
+		return nil, api.ErrorFromConverters{
+			ApiError:   api.Errorf(api.ErrInternal, "failed to encode image"),
+			StatusCode: http.StatusInternalServerError,
+			LogMessage: fmt.Sprintf("failed to encode image: %s", err),
+		}
+	}
+	return &buf, nil
 }
@@ -0,0 +1,33 @@
+package handlers
+
+import (
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"party-buddy/internal/schemas/api"
+	"testing"
+)
+
+// withDimensions rewrites the IHDR chunk of a PNG image to declare the given dimensions.
+func withDimensions(data []byte, width, height uint32) []byte {
+	patched := append([]byte(nil), data...)
+	// the signature (8 bytes), the chunk length (4) and type (4) precede the IHDR data
+	ihdr := patched[16 : 16+13]
+	binary.BigEndian.PutUint32(ihdr[0:4], width)
+	binary.BigEndian.PutUint32(ihdr[4:8], height)
+	crc := crc32.ChecksumIEEE(patched[12 : 16+13])
+	binary.BigEndian.PutUint32(patched[16+13:16+13+4], crc)
+	return patched
+}
+
+func TestToJPEG(t *testing.T) {
+	if _, err := toJPEG(makePNG(t)); err != nil {
+		t.Fatalf("toJPEG failed: %v", err)
+	}
+
+	_, err := toJPEG(withDimensions(makePNG(t), 50000, 50000))
+	var errConv api.ErrorFromConverters
+	if !errors.As(err, &errConv) || errConv.ApiError.Kind != api.ErrImgTooLarge {
+		t.Errorf("got %v for a 50000x50000 image, want %s", err, api.ErrImgTooLarge)
+	}
+}
@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/google/uuid"
+	"io"
+	"log/slog"
+	"net/http"
+	"party-buddy/internal/api/base"
+	"party-buddy/internal/api/middleware"
+	"party-buddy/internal/configuration"
+	"party-buddy/internal/schemas"
+	"party-buddy/internal/schemas/api"
+	"party-buddy/internal/session"
+)
+
+type LobbiesHandler struct{}
+
+// LobbiesHandler lists the public lobbies the client can join, most populated first.
+func (h LobbiesHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	authInfo := middleware.AuthInfoFromContext(r.Context())
+	manager := middleware.ManagerFromContext(r.Context())
+
+	var lobbies []session.Lobby
+	manager.Storage().Atomically(func(s *session.UnsafeStorage) {
+		lobbies = s.Lobbies(session.ClientID(authInfo.ID))
+	})
+
+	resp := make([]api.Lobby, 0, len(lobbies))
+	for _, lobby := range lobbies {
+		resp = append(resp, toLobby(lobby))
+	}
+
+	slog.InfoContext(r.Context(), "request handled")
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+type QuickJoinHandler struct{}
+
+// QuickJoinHandler finds a lobby for the client to join.
+// If there's none, a new listed session owned by the client is created for the requested game.
+func (h QuickJoinHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	bytes, err := io.ReadAll(r.Body)
+	if err != nil {
+		base.WriteErrorResponse(w, http.StatusInternalServerError, api.ErrInternal, "failed to read request body")
+		slog.ErrorContext(r.Context(), "failed to read request body", "err", err)
+		return
+	}
+
+	var req schemas.QuickJoinRequest
+	if err = api.Parse(r.Context(), &req, bytes, false); err != nil {
+		var dto api.Error
+		errors.As(err, &dto)
+		base.WriteErrorResponse(w, http.StatusBadRequest, dto.Kind, dto.Message)
+		slog.InfoContext(r.Context(), "request failed", "err", dto)
+		return
+	}
+
+	authInfo := middleware.AuthInfoFromContext(r.Context())
+	manager := middleware.ManagerFromContext(r.Context())
+	clientID := session.ClientID(authInfo.ID)
+
+	var gameID uuid.NullUUID
+	if req.GameID != nil {
+		gameID = uuid.NullUUID{UUID: *req.GameID, Valid: true}
+	}
+
+	var lobby session.Lobby
+	var found bool
+	manager.Storage().Atomically(func(s *session.UnsafeStorage) {
+		lobby, found = s.PickLobby(clientID, gameID)
+	})
+	if found {
+		writeQuickJoinResponse(w, r, api.QuickJoinResponse{SessionID: lobby.ID.UUID()})
+		return
+	}
+
+	if !gameID.Valid {
+		msg := "no lobby is available; provide a game-id to create one"
+		base.WriteErrorResponse(w, http.StatusNotFound, api.ErrNotFound, msg)
+		slog.InfoContext(r.Context(), "request failed", "err", msg)
+		return
+	}
+
+	tx := middleware.TxFromContext(r.Context())
+	game, err := gameIDToSessionGame(r.Context(), tx, gameID.UUID)
+	if err != nil {
+		var errConv api.ErrorFromConverters
+		errors.As(err, &errConv)
+		slog.InfoContext(r.Context(), "request failed", "err", errConv)
+		base.WriteErrorResponse(w, errConv.StatusCode, errConv.ApiError.Kind, errConv.ApiError.Message)
+		return
+	}
+
+	playerCount := configuration.QuickJoinPlayerCount
+	if req.PlayerCount != nil {
+		playerCount = *req.PlayerCount
+	}
+	requireReady := true
+	if req.RequireReady != nil {
+		requireReady = *req.RequireReady
+	}
+
+	sid, _, err := manager.NewSession(
+		r.Context(),
+		tx,
+		&game,
+		clientID,
+		requireReady,
+		int(playerCount),
+		session.SessionOptions{Listed: true})
+	if err != nil {
+		base.WriteErrorResponse(w, http.StatusInternalServerError, api.ErrInternal, "failed to create session")
+		slog.ErrorContext(r.Context(), "request failed", "err", err)
+		return
+	}
+	err = tx.Commit(r.Context())
+	if err != nil {
+		base.WriteErrorResponse(w, http.StatusInternalServerError, api.ErrInternal, "failed to create session")
+		slog.ErrorContext(r.Context(), "request failed", "err", err)
+		return
+	}
+
+	writeQuickJoinResponse(w, r, api.QuickJoinResponse{SessionID: sid.UUID(), Created: true})
+}
+
+func writeQuickJoinResponse(w http.ResponseWriter, r *http.Request, resp api.QuickJoinResponse) {
+	slog.InfoContext(r.Context(), "request handled", "sid", resp.SessionID, "created", resp.Created)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(resp)
+}
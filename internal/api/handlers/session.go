@@ -3,53 +3,117 @@ package handlers
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
 	"io"
-	"log"
+	"log/slog"
 	"net/http"
 	"party-buddy/internal/api/base"
 	"party-buddy/internal/api/middleware"
+	"party-buddy/internal/ratelimit"
 	"party-buddy/internal/schemas"
 	"party-buddy/internal/schemas/api"
+	schemaws "party-buddy/internal/schemas/ws"
 	"party-buddy/internal/session"
 	"party-buddy/internal/validate"
 	"party-buddy/internal/ws"
+	"strings"
 )
 
-type SessionConnectHandler struct{}
+type SessionConnectHandler struct {
+	// Limiter provides the limit on the messages received over the connection
+	Limiter *ratelimit.Limiter
+}
 
 func (sch SessionConnectHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	manager := middleware.ManagerFromContext(r.Context())
 
-	var sid session.SessionID
+	sid, ok := sessionFromQuery(w, r, manager)
+	if !ok {
+		return
+	}
+
+	authInfo := middleware.AuthInfoFromContext(r.Context())
+
+	if !websocket.IsWebSocketUpgrade(r) {
+		msg := "bad Upgrade Header"
+		base.WriteErrorResponse(w, http.StatusUpgradeRequired, api.ErrInvalidUpgrade, msg)
+		slog.InfoContext(r.Context(), "request failed", "err", msg)
+		return
+	}
+
+	offered := websocket.Subprotocols(r)
+	protocol, ok := schemaws.NegotiateProtocol(offered)
+	if !ok {
+		msg := unsupportedProtocolMessage(offered, schemaws.SupportedProtocols)
+		base.WriteErrorResponse(w, http.StatusBadRequest, api.ErrProtocolUnsupported, msg)
+		slog.InfoContext(r.Context(), "request failed", "err", msg)
+		return
+	}
+
+	var upgrader = websocket.Upgrader{
+		ReadBufferSize:  1024,
+		WriteBufferSize: 1024,
+		// a client that has offered no subprotocol gets none in the response
+		Subprotocols: []string{protocol.Subprotocol()},
+		Error: func(w http.ResponseWriter, r *http.Request, status int, cause error) {
+			base.WriteErrorResponse(w, status, api.ErrUpgradeFailed, cause.Error())
+		},
+	}
+
+	requestID := middleware.RequestIDFromContext(r.Context())
+	wsConn, err := upgrader.Upgrade(w, r, http.Header{middleware.RequestIDHeader: {requestID}})
+	if err != nil {
+		slog.InfoContext(r.Context(), "websocket upgrade failed", "err", err)
+		return
+	}
+
+	info := ws.NewConn(
+		slog.Default().With("request_id", requestID),
+		manager,
+		ws.NewWSTransport(wsConn),
+		session.ClientID(authInfo.ID),
+		sid,
+		sch.Limiter.Policy(ratelimit.WSMessage),
+		sch.Limiter.Policy(ratelimit.WSChat),
+		sch.Limiter.ClientIP(r),
+		protocol,
+	)
+	f, _ := validate.FromContext(r.Context())
+	info.StartReadAndWriteConn(f)
+	slog.InfoContext(r.Context(), "request handled")
+}
+
+// sessionFromQuery finds the session by the `session-id` or `invite-code` query parameter.
+// If there's no such session, the error is written to w and ok is false.
+func sessionFromQuery(w http.ResponseWriter, r *http.Request, manager *session.Manager) (sid session.SessionID, ok bool) {
 	strID := r.URL.Query().Get("session-id")
 	if strID == "" {
 		code := r.URL.Query().Get("invite-code")
 		if code == "" {
 			msg := "no query params provided"
 			base.WriteErrorResponse(w, http.StatusBadRequest, api.ErrParamMissing, msg)
-			log.Printf("request: %v %s -> err: %v", r.Method, r.URL, msg)
-			return
+			slog.InfoContext(r.Context(), "request failed", "err", msg)
+			return sid, false
 		}
 
-		var ok bool
 		manager.Storage().Atomically(func(s *session.UnsafeStorage) {
 			sid, ok = s.SidByInviteCode(session.InviteCode(code))
 		})
 		if !ok {
 			msg := "invalid invite code or session identifier"
 			base.WriteErrorResponse(w, http.StatusNotFound, api.ErrNotFound, msg)
-			log.Printf("request: %v %s -> err: %v", r.Method, r.URL, msg)
-			return
+			slog.InfoContext(r.Context(), "request failed", "err", msg)
+			return sid, false
 		}
 	} else {
 		id, err := uuid.Parse(strID)
 		if err != nil {
 			msg := "invalid invite code or session identifier"
 			base.WriteErrorResponse(w, http.StatusNotFound, api.ErrNotFound, msg)
-			log.Printf("request: %v %s -> err: %v", r.Method, r.URL, msg)
-			return
+			slog.InfoContext(r.Context(), "request failed", "err", msg)
+			return sid, false
 		}
 		sid = session.SessionID(id)
 
@@ -60,38 +124,22 @@ func (sch SessionConnectHandler) ServeHTTP(w http.ResponseWriter, r *http.Reques
 		if !exists {
 			msg := "invalid invite code or session identifier"
 			base.WriteErrorResponse(w, http.StatusNotFound, api.ErrNotFound, msg)
-			log.Printf("request: %v %s -> err: %v", r.Method, r.URL, msg)
-			return
+			slog.InfoContext(r.Context(), "request failed", "err", msg)
+			return sid, false
 		}
 	}
 
-	authInfo := middleware.AuthInfoFromContext(r.Context())
-
-	if !websocket.IsWebSocketUpgrade(r) {
-		msg := "bad Upgrade Header"
-		base.WriteErrorResponse(w, http.StatusUpgradeRequired, api.ErrInvalidUpgrade, msg)
-		log.Printf("request: %v %s -> err: %v", r.Method, r.URL, msg)
-		return
-	}
-
-	var upgrader = websocket.Upgrader{
-		ReadBufferSize:  1024,
-		WriteBufferSize: 1024,
-		Error: func(w http.ResponseWriter, r *http.Request, status int, cause error) {
-			base.WriteErrorResponse(w, status, api.ErrUpgradeFailed, cause.Error())
-		},
-	}
+	return sid, true
+}
 
-	wsConn, err := upgrader.Upgrade(w, r, nil)
-	if err != nil {
-		log.Printf("request: %v %v -> err after upgrade: %v", r.Method, r.URL.String(), err)
-		return
+// unsupportedProtocolMessage describes the failed protocol negotiation
+func unsupportedProtocolMessage(offered []string, supported []schemaws.Protocol) string {
+	names := make([]string, 0, len(supported))
+	for _, v := range supported {
+		names = append(names, v.Subprotocol())
 	}
-
-	info := ws.NewConn(log.Default(), manager, wsConn, session.ClientID(authInfo.ID), sid)
-	f, _ := validate.FromContext(r.Context())
-	info.StartReadAndWriteConn(f)
-	log.Printf("request: %v %v -> OK", r.Method, r.URL.String())
+	return fmt.Sprintf("none of the offered subprotocols (%s) is supported; supported subprotocols are: %s",
+		strings.Join(offered, ", "), strings.Join(names, ", "))
 }
 
 type SessionCreateHandler struct{}
@@ -100,7 +148,7 @@ func (sch SessionCreateHandler) ServeHTTP(w http.ResponseWriter, r *http.Request
 	bytes, err := io.ReadAll(r.Body)
 	if err != nil {
 		base.WriteErrorResponse(w, http.StatusInternalServerError, api.ErrInternal, "failed to read request body")
-		log.Printf("request: %v %s -> failed to read request body with err: %v", r.Method, r.URL, err)
+		slog.ErrorContext(r.Context(), "failed to read request body", "err", err)
 		return
 	}
 
@@ -110,7 +158,7 @@ func (sch SessionCreateHandler) ServeHTTP(w http.ResponseWriter, r *http.Request
 		var dto api.Error
 		errors.As(err, &dto)
 		base.WriteErrorResponse(w, http.StatusBadRequest, dto.Kind, dto.Message)
-		log.Printf("request: %v %s -> err: %v", r.Method, r.URL, dto)
+		slog.InfoContext(r.Context(), "request failed", "err", dto)
 		return
 	}
 
@@ -139,7 +187,7 @@ func (sch SessionCreateHandler) ServeHTTP(w http.ResponseWriter, r *http.Request
 	var dto api.Error
 	errors.As(err, &dto)
 	base.WriteErrorResponse(w, http.StatusBadRequest, dto.Kind, dto.Message)
-	log.Printf("request: %v %s -> err: %v", r.Method, r.URL, dto)
+	slog.InfoContext(r.Context(), "request failed", "err", dto)
 }
 
 func handlePublicReq(w http.ResponseWriter, r *http.Request, publicReq schemas.PublicCreateSessionRequest) {
@@ -150,11 +198,14 @@ func handlePublicReq(w http.ResponseWriter, r *http.Request, publicReq schemas.P
 	if err != nil {
 		var errConv api.ErrorFromConverters
 		errors.As(err, &errConv)
-		log.Printf("request: %v %s -> err: %v", r.Method, r.URL, errConv)
+		slog.InfoContext(r.Context(), "request failed", "err", errConv)
 		base.WriteErrorResponse(w, errConv.StatusCode, errConv.ApiError.Kind, errConv.ApiError.Message)
 		return
 	}
 
+	opts := toSessionOptions(publicReq.BaseCreateSessionRequest)
+	opts.Listed = publicReq.Listed != nil && *publicReq.Listed
+
 	authInfo := middleware.AuthInfoFromContext(r.Context())
 	manager := middleware.ManagerFromContext(r.Context())
 	_, code, err := manager.NewSession(
@@ -163,21 +214,22 @@ func handlePublicReq(w http.ResponseWriter, r *http.Request, publicReq schemas.P
 		&game,
 		session.ClientID(authInfo.ID),
 		*publicReq.RequireReady,
-		int(*publicReq.PlayerCount))
+		int(*publicReq.PlayerCount),
+		opts)
 	if err != nil {
 		base.WriteErrorResponse(w, http.StatusInternalServerError, api.ErrInternal, "failed to create session")
-		log.Printf("request: %v %s -> err: %v", r.Method, r.URL, err)
+		slog.ErrorContext(r.Context(), "request failed", "err", err)
 		return
 	}
 	err = tx.Commit(r.Context())
 	if err != nil {
 		base.WriteErrorResponse(w, http.StatusInternalServerError, api.ErrInternal, "failed to create session")
-		log.Printf("request: %v %s -> err: %v", r.Method, r.URL, err)
+		slog.ErrorContext(r.Context(), "request failed", "err", err)
 		return
 	}
 
-	req := api.SessionCreateResponse{InviteCode: string(code), ImgRequests: []api.ImgReqResponse{}}
-	log.Printf("request: %v %s -> OK", r.Method, r.URL)
+	req := api.SessionCreateResponse{InviteCode: string(code), ImgRequests: []api.ImgReqResponse{}, TaskSeed: *opts.Tasks.Seed}
+	slog.InfoContext(r.Context(), "request handled")
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	_ = encoder.Encode(req)
@@ -192,11 +244,12 @@ func handlePrivateReq(w http.ResponseWriter, r *http.Request, privateReq schemas
 	if err != nil {
 		var errConv api.ErrorFromConverters
 		errors.As(err, &errConv)
-		log.Printf("request: %v %s -> err: %v", r.Method, r.URL, errConv)
+		slog.InfoContext(r.Context(), "request failed", "err", errConv)
 		base.WriteErrorResponse(w, errConv.StatusCode, errConv.ApiError.Kind, errConv.ApiError.Message)
 		return
 	}
 
+	opts := toSessionOptions(privateReq.BaseCreateSessionRequest)
 	manager := middleware.ManagerFromContext(r.Context())
 	_, code, err := manager.NewSession(
 		r.Context(),
@@ -204,21 +257,22 @@ func handlePrivateReq(w http.ResponseWriter, r *http.Request, privateReq schemas
 		&game,
 		session.ClientID(authInfo.ID),
 		*privateReq.RequireReady,
-		int(*privateReq.PlayerCount))
+		int(*privateReq.PlayerCount),
+		opts)
 	if err != nil {
 		base.WriteErrorResponse(w, http.StatusInternalServerError, api.ErrInternal, "failed to create session")
-		log.Printf("request: %v %s -> err: %v", r.Method, r.URL, err)
+		slog.ErrorContext(r.Context(), "request failed", "err", err)
 		return
 	}
 	err = tx.Commit(r.Context())
 	if err != nil {
 		base.WriteErrorResponse(w, http.StatusInternalServerError, api.ErrInternal, "failed to create session")
-		log.Printf("request: %v %s -> err: %v", r.Method, r.URL, err)
+		slog.ErrorContext(r.Context(), "request failed", "err", err)
 		return
 	}
 
-	req := api.SessionCreateResponse{InviteCode: string(code), ImgRequests: imgResps}
-	log.Printf("request: %v %s -> OK", r.Method, r.URL)
+	req := api.SessionCreateResponse{InviteCode: string(code), ImgRequests: imgResps, TaskSeed: *opts.Tasks.Seed}
+	slog.InfoContext(r.Context(), "request handled")
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	_ = encoder.Encode(req)
@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"party-buddy/internal/api/base"
+	"party-buddy/internal/api/middleware"
+	"party-buddy/internal/ratelimit"
+	"party-buddy/internal/schemas/api"
+	schemaws "party-buddy/internal/schemas/ws"
+	"party-buddy/internal/session"
+	"party-buddy/internal/validate"
+	"party-buddy/internal/ws"
+)
+
+// MaxSSEMessageSize limits the size of a client message sent over the SSE transport
+const MaxSSEMessageSize = 64 << 10
+
+// SessionEventsHandler connects to the session over the SSE transport,
+// which is a fallback for the networks blocking WebSocket upgrades.
+// The server messages are streamed as Server-Sent Events,
+// the client messages are handled by SessionMessageHandler.
+type SessionEventsHandler struct {
+	// Limiter provides the limit on the messages received over the connection
+	Limiter *ratelimit.Limiter
+
+	Registry *ws.SSERegistry
+}
+
+func (seh SessionEventsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	manager := middleware.ManagerFromContext(r.Context())
+
+	sid, ok := sessionFromQuery(w, r, manager)
+	if !ok {
+		return
+	}
+
+	authInfo := middleware.AuthInfoFromContext(r.Context())
+
+	// SSE cannot carry binary messages
+	offered := r.URL.Query()["protocol"]
+	protocol, ok := schemaws.NegotiateTextProtocol(offered)
+	if !ok {
+		msg := unsupportedProtocolMessage(offered, schemaws.TextProtocols())
+		base.WriteErrorResponse(w, http.StatusBadRequest, api.ErrProtocolUnsupported, msg)
+		slog.InfoContext(r.Context(), "request failed", "err", msg)
+		return
+	}
+
+	// the stream may last for the whole game, it must not hold a db connection
+	_ = middleware.TxFromContext(r.Context()).Rollback(r.Context())
+
+	client := session.ClientID(authInfo.ID)
+	token, transport, err := seh.Registry.Open(w, client, protocol)
+	if err != nil {
+		base.WriteErrorResponse(w, http.StatusInternalServerError, api.ErrInternal, "failed to open the event stream")
+		slog.ErrorContext(r.Context(), "failed to open the event stream", "err", err)
+		return
+	}
+	defer seh.Registry.Remove(token)
+
+	requestID := middleware.RequestIDFromContext(r.Context())
+	conn := ws.NewConn(
+		slog.Default().With("request_id", requestID, "transport", "sse"),
+		manager,
+		transport,
+		client,
+		sid,
+		seh.Limiter.Policy(ratelimit.WSMessage),
+		seh.Limiter.Policy(ratelimit.WSChat),
+		seh.Limiter.ClientIP(r),
+		protocol,
+	)
+	f, _ := validate.FromContext(r.Context())
+	conn.StartReadAndWriteConn(f)
+	slog.InfoContext(r.Context(), "event stream opened")
+
+	select {
+	case <-transport.Done():
+		// closed by the server
+	case <-r.Context().Done():
+		// the client has gone away
+	}
+
+	slog.InfoContext(r.Context(), "request handled")
+}
+
+// SessionMessageHandler passes a client message to the SSE connection identified by the connection token.
+// The responses to the message, including errors, are sent over the event stream.
+type SessionMessageHandler struct {
+	Registry *ws.SSERegistry
+}
+
+func (smh SessionMessageHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	token := r.Header.Get(ws.SSEConnectionTokenHeader)
+	if token == "" {
+		msg := "no connection token provided"
+		base.WriteErrorResponse(w, http.StatusBadRequest, api.ErrParamMissing, msg)
+		slog.InfoContext(r.Context(), "request failed", "err", msg)
+		return
+	}
+
+	authInfo := middleware.AuthInfoFromContext(r.Context())
+	transport, ok := smh.Registry.Lookup(token, session.ClientID(authInfo.ID))
+	if !ok {
+		msg := "unknown connection token"
+		base.WriteErrorResponse(w, http.StatusNotFound, api.ErrNotFound, msg)
+		slog.InfoContext(r.Context(), "request failed", "err", msg)
+		return
+	}
+
+	bytes, err := io.ReadAll(http.MaxBytesReader(w, r.Body, MaxSSEMessageSize))
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			msg := "the message is too large"
+			base.WriteErrorResponse(w, http.StatusRequestEntityTooLarge, api.ErrMalformedRequest, msg)
+			slog.InfoContext(r.Context(), "request failed", "err", msg)
+			return
+		}
+		base.WriteErrorResponse(w, http.StatusInternalServerError, api.ErrInternal, "failed to read request body")
+		slog.ErrorContext(r.Context(), "failed to read request body", "err", err)
+		return
+	}
+
+	err = transport.Deliver(r.Context(), bytes)
+	switch {
+	case errors.Is(err, ws.ErrTransportClosed):
+		msg := "the connection has been closed"
+		base.WriteErrorResponse(w, http.StatusGone, api.ErrConnectionClosed, msg)
+		slog.InfoContext(r.Context(), "request failed", "err", msg)
+		return
+
+	case err != nil:
+		slog.InfoContext(r.Context(), "request failed", "err", err)
+		return
+	}
+
+	slog.InfoContext(r.Context(), "request handled")
+	w.WriteHeader(http.StatusAccepted)
+}
@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"github.com/google/uuid"
+	"party-buddy/internal/configuration"
+	"party-buddy/internal/schemas/api"
+	"party-buddy/internal/session"
+	"time"
+
+	"golang.org/x/exp/slices"
+)
+
+func toAdminSessionSummary(summary session.SessionSummary) api.AdminSessionSummary {
+	return api.AdminSessionSummary{
+		SessionID:   summary.ID.UUID(),
+		State:       summary.State,
+		GameName:    summary.GameName,
+		PlayerCount: uint8(summary.PlayerCount),
+		MaxPlayers:  uint8(summary.PlayersMax),
+		CreatedAt:   summary.CreatedAt,
+		AgeSecs:     int64(time.Since(summary.CreatedAt).Seconds()),
+	}
+}
+
+func toAdminAnswer(playerID session.PlayerID, answer session.TaskAnswer, sid session.SessionID) api.AdminAnswer {
+	dto := api.AdminAnswer{PlayerID: uint32(playerID)}
+
+	switch a := answer.(type) {
+	case session.PhotoTaskAnswer:
+		dto.Type = "photo"
+		dto.Value = configuration.GenImgURI(a.UUID, sid.NullUUID())
+	case session.TextTaskAnswer:
+		dto.Type = "text"
+		dto.Value = string(a)
+	case session.CheckedTextAnswer:
+		dto.Type = "checked-text"
+		dto.Value = string(a)
+	case session.ChoiceTaskAnswer:
+		dto.Type = "choice"
+		dto.Value = int(a)
+	}
+
+	return dto
+}
+
+func toAdminSessionDetails(details session.SessionDetails) api.AdminSessionDetails {
+	dto := api.AdminSessionDetails{
+		AdminSessionSummary: toAdminSessionSummary(details.SessionSummary),
+		TaskIdx:             details.TaskIdx,
+		Deadline:            details.Deadline,
+		Players:             make([]api.AdminPlayer, 0, len(details.Players)),
+		Answers:             make([]api.AdminAnswer, 0, len(details.Answers)),
+		Banned:              make([]uuid.UUID, 0, len(details.Banned)),
+		TaskSeed:            details.TaskSeed,
+	}
+
+	owner := details.Owner.UUID()
+	dto.Owner = &owner
+	if details.InviteCode != nil {
+		code := string(*details.InviteCode)
+		dto.InviteCode = &code
+	}
+
+	for _, player := range details.Players {
+		dto.Players = append(dto.Players, api.AdminPlayer{
+			PlayerID: uint32(player.ID),
+			ClientID: player.ClientID.UUID(),
+			Nickname: player.Nickname,
+			Score:    uint32(details.Scoreboard[player.ID]),
+		})
+	}
+	slices.SortFunc(dto.Players, func(a, b api.AdminPlayer) int {
+		return int(a.PlayerID) - int(b.PlayerID)
+	})
+
+	for playerID, answer := range details.Answers {
+		dto.Answers = append(dto.Answers, toAdminAnswer(playerID, answer, details.ID))
+	}
+	slices.SortFunc(dto.Answers, func(a, b api.AdminAnswer) int {
+		return int(a.PlayerID) - int(b.PlayerID)
+	})
+
+	for _, clientID := range details.Banned {
+		dto.Banned = append(dto.Banned, clientID.UUID())
+	}
+
+	return dto
+}
+
+func toLobby(lobby session.Lobby) api.Lobby {
+	dto := api.Lobby{
+		SessionID:   lobby.ID.UUID(),
+		GameName:    lobby.GameName,
+		PlayerCount: uint8(lobby.PlayerCount),
+		MaxPlayers:  uint8(lobby.PlayersMax),
+		CreatedAt:   lobby.CreatedAt,
+	}
+	if lobby.GameID.Valid {
+		dto.GameID = &lobby.GameID.UUID
+	}
+	return dto
+}
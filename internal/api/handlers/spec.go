@@ -0,0 +1,18 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+)
+
+// SpecHandler serves an API specification document
+type SpecHandler struct {
+	Spec []byte
+}
+
+func (sh SpecHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/yaml")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(sh.Spec)
+	slog.InfoContext(r.Context(), "request handled")
+}
@@ -2,10 +2,16 @@ package middleware
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"github.com/google/uuid"
-	"log"
+	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v5"
+	"log/slog"
 	"net/http"
 	"party-buddy/internal/api/base"
+	"party-buddy/internal/auth"
+	"party-buddy/internal/configuration"
 	"party-buddy/internal/db"
 	"party-buddy/internal/schemas/api"
 	"strings"
@@ -20,39 +26,51 @@ type AuthInfo struct {
 	Role db.UserRole
 }
 
-// AuthMiddleware must be applied after DBUsingMiddleware
-func AuthMiddleware(next http.Handler) http.Handler {
+// AuthUsingMiddleware authenticates requests by the access token in the Authorization header
+type AuthUsingMiddleware struct {
+	Issuer *auth.Issuer
+}
+
+// Middleware verifies the access token and puts the AuthInfo to request context.
+// It must be applied after DBUsingMiddleware
+func (am AuthUsingMiddleware) Middleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		val := r.Header.Get("Authorization")
 		if val == "" {
 			msg := "authentication required"
 			base.WriteErrorResponse(w, http.StatusUnauthorized, api.ErrAuthRequired, msg)
-			log.Printf("request: %v %s -> err: %v", r.Method, r.URL, msg)
+			slog.InfoContext(r.Context(), "request failed", "err", msg)
 			return
 		}
-		strUUID, found := strings.CutPrefix(val, "Bearer ")
+		token, found := strings.CutPrefix(val, "Bearer ")
 		if !found {
-			msg := "provided user id is not valid"
-			base.WriteErrorResponse(w, http.StatusUnauthorized, api.ErrUserIDInvalid, msg)
-			log.Printf("request: %v %s -> err: %v", r.Method, r.URL, msg)
+			msg := "provided access token is not valid"
+			base.WriteErrorResponse(w, http.StatusUnauthorized, api.ErrTokenInvalid, msg)
+			slog.InfoContext(r.Context(), "request failed", "err", msg)
 			return
 		}
 
-		userID, err := uuid.Parse(strUUID)
+		tx := TxFromContext(r.Context())
+
+		userID, err := am.userIDFromToken(r.Context(), tx, token)
 		if err != nil {
-			msg := "provided user id is not valid"
-			base.WriteErrorResponse(w, http.StatusUnauthorized, api.ErrUserIDInvalid, msg)
-			log.Printf("request: %v %s -> err: %v", r.Method, r.URL, msg)
+			kind, msg := api.ErrTokenInvalid, "provided access token is not valid"
+			switch {
+			case errors.Is(err, auth.ErrTokenExpired):
+				kind, msg = api.ErrTokenExpired, "provided access token has expired"
+			case !errors.Is(err, auth.ErrTokenInvalid):
+				kind, msg = api.ErrInternal, "internal server error while checking access token"
+			}
+			base.WriteErrorResponse(w, http.StatusUnauthorized, kind, msg)
+			slog.InfoContext(r.Context(), "request failed", "err", err)
 			return
 		}
 
-		tx := TxFromContext(r.Context())
-
 		entity, err := db.GetUserByID(r.Context(), tx, userID)
 		if err != nil {
 			msg := "internal server error while getting user"
 			base.WriteErrorResponse(w, http.StatusUnauthorized, api.ErrInternal, msg)
-			log.Printf("request: %v %s -> err: %v", r.Method, r.URL, err)
+			slog.ErrorContext(r.Context(), "request failed", "err", err)
 			return
 		}
 		authInfo := AuthInfo{ID: entity.ID.UUID, Role: entity.Role}
@@ -63,6 +81,101 @@ func AuthMiddleware(next http.Handler) http.Handler {
 	})
 }
 
+// userIDFromToken verifies the access token and returns the user id it was issued for.
+//
+// While legacy ids are allowed (see auth.Issuer.LegacyIDsAllowed), a bare user id is accepted as well
+// unless the user has registered: registered users (including admins) must always present a signed token.
+func (am AuthUsingMiddleware) userIDFromToken(ctx context.Context, tx pgx.Tx, token string) (uuid.UUID, error) {
+	if am.Issuer.LegacyIDsAllowed() {
+		if userID, err := uuid.Parse(token); err == nil {
+			registered, err := db.UserExists(ctx, tx, userID)
+			if err != nil {
+				return uuid.UUID{}, err
+			}
+			if registered {
+				return uuid.UUID{}, fmt.Errorf("%w: registered user %s presented a legacy id", auth.ErrTokenInvalid, userID)
+			}
+			return userID, nil
+		}
+	}
+
+	return am.Issuer.VerifyAccessToken(token)
+}
+
+// AdminMiddleware authenticates the request like Middleware
+// and additionally rejects users who are not administrators.
+func (am AuthUsingMiddleware) AdminMiddleware(next http.Handler) http.Handler {
+	return am.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if AuthInfoFromContext(r.Context()).Role != db.Admin {
+			msg := "only administrators are allowed"
+			base.WriteErrorResponse(w, http.StatusForbidden, api.ErrNotEnoughPrivileges, msg)
+			slog.InfoContext(r.Context(), "request failed", "err", msg)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	}))
+}
+
 func AuthInfoFromContext(ctx context.Context) AuthInfo {
 	return ctx.Value(authKey).(AuthInfo)
 }
+
+type signedImgKeyType int
+
+var signedImgKey signedImgKeyType
+
+// SignedImgAccess describes access granted by a signed image URI.
+type SignedImgAccess struct {
+	// Sid is the session the URI is scoped to.
+	// If it is not valid, the URI is not scoped to any session.
+	Sid uuid.NullUUID
+}
+
+// SignedImgMiddleware authenticates requests for images.
+// If the request URI carries a signature (see configuration.GenImgURI), it's verified
+// against the img-id route variable and the resulting SignedImgAccess is put to request context.
+// Otherwise, the request is handed over to Middleware.
+//
+// Must be applied after DBUsingMiddleware.
+func (am AuthUsingMiddleware) SignedImgMiddleware(next http.Handler) http.Handler {
+	withAuth := am.Middleware(next)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if !configuration.HasImgSignature(q) {
+			withAuth.ServeHTTP(w, r)
+			return
+		}
+
+		imgID, err := uuid.Parse(mux.Vars(r)["img-id"])
+		if err != nil {
+			msg := "invalid url"
+			base.WriteErrorResponse(w, http.StatusNotFound, api.ErrNotFound, msg)
+			slog.InfoContext(r.Context(), "request failed", "err", msg)
+			return
+		}
+
+		sid, err := configuration.VerifyImgSignature(imgID, q)
+		if err != nil {
+			kind, msg := api.ErrSignatureInvalid, "the signature is not valid"
+			if errors.Is(err, configuration.ErrImgSignatureExpired) {
+				kind, msg = api.ErrSignatureExpired, "the signature has expired"
+			}
+			base.WriteErrorResponse(w, http.StatusForbidden, kind, msg)
+			slog.InfoContext(r.Context(), "request failed", "err", msg)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), signedImgKey, SignedImgAccess{Sid: sid})
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// SignedImgAccessFromContext returns the access granted by a signed image URI.
+// If the request was not signed, ok is false.
+func SignedImgAccessFromContext(ctx context.Context) (access SignedImgAccess, ok bool) {
+	access, ok = ctx.Value(signedImgKey).(SignedImgAccess)
+	return
+}
@@ -3,7 +3,7 @@ package middleware
 import (
 	"context"
 	"github.com/jackc/pgx/v5"
-	"log"
+	"log/slog"
 	"net/http"
 	"party-buddy/internal/api/base"
 	"party-buddy/internal/db"
@@ -25,7 +25,7 @@ func (dbm DBUsingMiddleware) Middleware(next http.Handler) http.Handler {
 		tx, err := dbm.Pool.Pool().Begin(r.Context())
 		if err != nil {
 			base.WriteErrorResponse(w, http.StatusInternalServerError, api.ErrInternal, "internal server error")
-			log.Printf("request: %v %s -> falided to start transaction with err: %v", r.Method, r.URL, err)
+			slog.ErrorContext(r.Context(), "failed to start transaction", "err", err)
 			return
 		}
 		defer tx.Rollback(r.Context())
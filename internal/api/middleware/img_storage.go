@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"party-buddy/internal/imgstore"
+)
+
+type imgStorageKeyType int
+
+var imgStorageKey imgStorageKeyType
+
+// ImgStorageUsingMiddleware is a middleware for image storage usage
+type ImgStorageUsingMiddleware struct {
+	Storage imgstore.Storage
+}
+
+// Middleware puts the image storage (imgstore.Storage) to request context
+func (ism ImgStorageUsingMiddleware) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), imgStorageKey, ism.Storage)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func ImgStorageFromContext(ctx context.Context) imgstore.Storage {
+	return ctx.Value(imgStorageKey).(imgstore.Storage)
+}
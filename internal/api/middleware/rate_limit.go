@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"log/slog"
+	"math"
+	"net/http"
+	"party-buddy/internal/api/base"
+	"party-buddy/internal/ratelimit"
+	"party-buddy/internal/schemas/api"
+	"strconv"
+)
+
+// RateLimitMiddleware limits requests per client and per IP address
+type RateLimitMiddleware struct {
+	Limiter *ratelimit.Limiter
+}
+
+// Middleware rejects the request with 429 if the client or their address has exceeded the action's limit.
+// The Retry-After header tells when the request may be retried.
+//
+// It must be applied after AuthUsingMiddleware unless the action is only limited per IP:
+// unauthenticated requests all share the zero client id.
+func (rl RateLimitMiddleware) Middleware(action ratelimit.Action, next http.Handler) http.Handler {
+	policy := rl.Limiter.Policy(action)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		info, _ := r.Context().Value(authKey).(AuthInfo)
+		clientID := info.ID.String()
+		ip := rl.Limiter.ClientIP(r)
+
+		if ok, retryAfter := policy.Allow(clientID, ip); !ok {
+			msg := "too many requests"
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+			base.WriteErrorResponse(w, http.StatusTooManyRequests, api.ErrRateLimited, msg)
+			slog.InfoContext(r.Context(), "request failed", "err", msg, "action", action, "ip", ip)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"log/slog"
+	"net/http"
+	"party-buddy/internal/logging"
+	"regexp"
+)
+
+// RequestIDHeader carries the request id in both the request and the response
+const RequestIDHeader = "X-Request-ID"
+
+// requestIDReg restricts the request ids accepted from upstream proxies
+var requestIDReg = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+type requestIDKeyType int
+
+var requestIDKey requestIDKeyType
+
+// RequestIDMiddleware assigns an id to every request
+type RequestIDMiddleware struct{}
+
+// Middleware reuses the request id set by a proxy in the X-Request-ID header or generates a new one.
+// The id is returned in the response header and added to the logging context
+// along with the request method and URL
+func (rm RequestIDMiddleware) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if !requestIDReg.MatchString(id) {
+			id = uuid.NewString()
+		}
+		w.Header().Set(RequestIDHeader, id)
+
+		ctx := context.WithValue(r.Context(), requestIDKey, id)
+		ctx = logging.NewContext(ctx,
+			slog.String("request_id", id),
+			slog.String("method", r.Method),
+			slog.String("url", r.URL.String()),
+		)
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// RequestIDFromContext returns the id of the request
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
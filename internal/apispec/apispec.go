@@ -0,0 +1,18 @@
+// Package apispec holds the machine-readable specifications of the API:
+// the OpenAPI document for the HTTP routes and the AsyncAPI document for the session protocol.
+//
+// The documents are maintained by hand.
+// The contract tests check them against the routes and the structs in the schemas packages.
+package apispec
+
+import _ "embed"
+
+// OpenAPI is the OpenAPI 3.1 document describing the HTTP routes
+//
+//go:embed openapi.yaml
+var OpenAPI []byte
+
+// AsyncAPI is the AsyncAPI 2.6 document describing the session protocol messages
+//
+//go:embed asyncapi.yaml
+var AsyncAPI []byte
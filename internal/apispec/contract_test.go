@@ -0,0 +1,566 @@
+package apispec
+
+import (
+	"encoding/json"
+	"fmt"
+	"party-buddy/internal/schemas"
+	"party-buddy/internal/schemas/api"
+	schemaws "party-buddy/internal/schemas/ws"
+	"party-buddy/internal/ws"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// The contract tests check the JSON shape of the structs against the schemas in the documents.
+//
+// Every field of a struct is set to a non-zero value, the struct is encoded, and the result must match its schema:
+// the fields not described by the schema are rejected, and so are the described fields missing from the encoding.
+// The values themselves (enums, formats, bounds) are not checked.
+
+const (
+	openAPIFile  = "openapi.yaml"
+	asyncAPIFile = "asyncapi.yaml"
+)
+
+var documents = map[string][]byte{
+	openAPIFile:  OpenAPI,
+	asyncAPIFile: AsyncAPI,
+}
+
+// openAPISchemas maps the schemas of the OpenAPI document to the structs
+var openAPISchemas = map[string]any{
+	"Error":                       api.Error{},
+	"HealthCheck":                 api.HealthCheck{},
+	"HealthResponse":              api.HealthResponse{},
+	"RegisterDeviceRequest":       schemas.RegisterDeviceRequest{},
+	"RefreshTokenRequest":         schemas.RefreshTokenRequest{},
+	"TokenResponse":               api.TokenResponse{},
+	"PollDuration":                schemas.PollDuration{},
+	"BaseTaskWithImgRequest":      schemas.BaseTaskWithImgRequest{},
+	"FullGameInfo":                schemas.FullGameInfo{},
+	"GameBundleManifest":          schemas.GameBundleManifest{},
+	"GameBundleImage":             schemas.GameBundleImage{},
+	"GameImportResponse":          api.GameImportResponse{},
+	"CSVImportResponse":           api.CSVImportResponse{},
+	"CloneGameRequest":            schemas.CloneGameRequest{},
+	"GameCloneResponse":           api.GameCloneResponse{},
+	"CSVRowReport":                api.CSVRowReport{},
+	"PublicCreateSessionRequest":  schemas.PublicCreateSessionRequest{},
+	"PrivateCreateSessionRequest": schemas.PrivateCreateSessionRequest{},
+	"ImgReqResponse":              api.ImgReqResponse{},
+	"SessionCreateResponse":       api.SessionCreateResponse{},
+	"SSEConnectionEvent":          ws.SSEConnectionEvent{},
+	"Lobby":                       api.Lobby{},
+	"QuickJoinRequest":            schemas.QuickJoinRequest{},
+	"QuickJoinResponse":           api.QuickJoinResponse{},
+	"BaseGameInfo":                schemas.BaseGameInfo{},
+	"BaseTaskWithImg":             schemas.BaseTaskWithImg{},
+	"BaseTaskWithImgAndID":        schemas.BaseTaskWithImgAndID{},
+	"GameDetails":                 schemas.GameDetails{},
+	"IDGameInfo":                  schemas.IDGameInfo{},
+	"AdminSessionSummary":         api.AdminSessionSummary{},
+	"AdminPlayer":                 api.AdminPlayer{},
+	"AdminAnswer":                 api.AdminAnswer{},
+	"AdminSessionDetails":         api.AdminSessionDetails{},
+	"AdminCloseSessionRequest":    schemas.AdminCloseSessionRequest{},
+}
+
+// asyncAPISchemas maps the schemas of the AsyncAPI document to the structs
+var asyncAPISchemas = map[string]any{
+	"BaseMessage":            schemaws.BaseMessage{},
+	"MessageError":           schemaws.MessageError{},
+	"MessageJoin":            schemaws.MessageJoin{},
+	"MessageJoined":          schemaws.MessageJoined{},
+	"Player":                 schemaws.Player{},
+	"MessageGameStatus":      schemaws.MessageGameStatus{},
+	"MessageReady":           schemaws.MessageReady{},
+	"MessageKick":            schemaws.MessageKick{},
+	"MessageLeave":           schemaws.MessageLeave{},
+	"MessageTaskStart":       schemaws.MessageTaskStart{},
+	"RecvAnswer":             schemaws.RecvAnswer{},
+	"MessageTaskAnswer":      schemaws.MessageTaskAnswer{},
+	"MessagePollChoose":      schemaws.MessagePollChoose{},
+	"CheckedWordAnswer":      schemaws.CheckedWordAnswer{},
+	"PhotoAnswer":            schemaws.PhotoAnswer{},
+	"WordAnswer":             schemaws.WordAnswer{},
+	"TaskOptionAnswer":       schemaws.TaskOptionAnswer{},
+	"TaskPlayerScore":        schemaws.TaskPlayerScore{},
+	"MessageTaskEnd":         schemaws.MessageTaskEnd{},
+	"MessageGameStart":       schemaws.MessageGameStart{},
+	"MessageWaiting":         schemaws.MessageWaiting{},
+	"GamePlayerScore":        schemaws.GamePlayerScore{},
+	"MessageGameEnd":         schemaws.MessageGameEnd{},
+	"MessageChat":            schemaws.MessageChat{},
+	"MessageReact":           schemaws.MessageReact{},
+	"MessageMuteChat":        schemaws.MessageMuteChat{},
+	"ChatEntry":              schemaws.ChatEntry{},
+	"MessageChatMessage":     schemaws.MessageChatMessage{},
+	"MessageReaction":        schemaws.MessageReaction{},
+	"MessageChatMuted":       schemaws.MessageChatMuted{},
+	"MessageChatHistory":     schemaws.MessageChatHistory{},
+	"MessageTransferOwner":   schemaws.MessageTransferOwner{},
+	"MessageOwnerChanged":    schemaws.MessageOwnerChanged{},
+	"MessagePause":           schemaws.MessagePause{},
+	"MessageResume":          schemaws.MessageResume{},
+	"MessageSkipTask":        schemaws.MessageSkipTask{},
+	"MessageAddTime":         schemaws.MessageAddTime{},
+	"MessageDeadlineChanged": schemaws.MessageDeadlineChanged{},
+}
+
+// unimplementedSchemas are the object schemas without a struct
+var unimplementedSchemas = map[string]bool{
+	// the polls are not supported yet
+	"MessagePollStart": true,
+}
+
+func TestOpenAPISchemas(t *testing.T) {
+	testSchemas(t, openAPIFile, openAPISchemas)
+}
+
+func TestAsyncAPISchemas(t *testing.T) {
+	testSchemas(t, asyncAPIFile, asyncAPISchemas)
+}
+
+func testSchemas(t *testing.T, file string, structs map[string]any) {
+	s := loadSpec(t)
+
+	components := s.lookup(t, file, "#/components/schemas")
+	for _, name := range sortedKeys(components) {
+		schema := components[name].(map[string]any)
+		_, hasStruct := structs[name]
+		if !hasStruct && s.isObject(file, schema) && !unimplementedSchemas[name] {
+			t.Errorf("%s: the schema %s is not matched to a struct", file, name)
+		}
+	}
+
+	for _, name := range sortedKeys(structs) {
+		schema, ok := components[name].(map[string]any)
+		if !ok {
+			t.Errorf("%s: there's no schema %s", file, name)
+			continue
+		}
+
+		value := sample(reflect.TypeOf(structs[name]))
+		for _, err := range s.check(file, schema, value, name) {
+			t.Errorf("%s: %T does not match the schema: %s", file, structs[name], err)
+		}
+	}
+}
+
+// The answers in `task-end` are interfaces, so they're missing from the sample
+func TestTaskEndAnswers(t *testing.T) {
+	s := loadSpec(t)
+	schema := s.lookup(t, asyncAPIFile, "#/components/schemas/MessageTaskEnd")
+
+	msg := sample(reflect.TypeOf(schemaws.MessageTaskEnd{})).(map[string]any)
+	for _, answer := range []schemaws.Answer{
+		&schemaws.CheckedWordAnswer{},
+		&schemaws.PhotoAnswer{},
+		&schemaws.WordAnswer{},
+		&schemaws.TaskOptionAnswer{},
+	} {
+		msg["answers"] = []any{sample(reflect.TypeOf(answer).Elem())}
+		for _, err := range s.check(asyncAPIFile, schema, msg, "MessageTaskEnd") {
+			t.Errorf("%T does not match the schema: %s", answer, err)
+		}
+	}
+}
+
+func TestAsyncAPIMessages(t *testing.T) {
+	s := loadSpec(t)
+	messages := s.lookup(t, asyncAPIFile, "#/components/messages")
+
+	byKind := make(map[string]map[string]any)
+	for _, name := range sortedKeys(messages) {
+		message := messages[name].(map[string]any)
+		kind, _ := message["name"].(string)
+		byKind[kind] = message
+	}
+
+	for _, kind := range schemaws.MessageKinds {
+		message, ok := byKind[string(kind)]
+		if !ok {
+			t.Errorf("the message kind `%s` is not described", kind)
+			continue
+		}
+
+		file, payload, err := s.resolve(asyncAPIFile, message["payload"].(map[string]any))
+		if err != nil {
+			t.Errorf("the payload of the message `%s`: %v", kind, err)
+			continue
+		}
+		obj := s.object(file, payload)
+		prop, ok := obj.props["kind"]
+		if !ok || prop.schema["const"] != string(kind) {
+			t.Errorf("the payload of the message `%s` must have the `kind` property with the const value", kind)
+		}
+	}
+
+	for kind := range byKind {
+		if !slices.Contains(schemaws.MessageKinds, schemaws.MessageKind(kind)) {
+			t.Errorf("the described message kind `%s` does not exist", kind)
+		}
+	}
+}
+
+func TestRefsResolve(t *testing.T) {
+	s := loadSpec(t)
+
+	for _, file := range sortedKeys(s.docs) {
+		var walk func(node any, path string)
+		walk = func(node any, path string) {
+			switch n := node.(type) {
+			case map[string]any:
+				if ref, ok := n["$ref"].(string); ok {
+					if _, _, err := s.lookupRef(file, ref); err != nil {
+						t.Errorf("%s: %s: %v", file, path, err)
+					}
+				}
+				for _, k := range sortedKeys(n) {
+					walk(n[k], path+"/"+k)
+				}
+			case []any:
+				for i, item := range n {
+					walk(item, fmt.Sprintf("%s/%d", path, i))
+				}
+			}
+		}
+		walk(s.docs[file], "#")
+	}
+}
+
+type spec struct {
+	docs map[string]map[string]any
+}
+
+func loadSpec(t *testing.T) spec {
+	t.Helper()
+
+	s := spec{docs: make(map[string]map[string]any)}
+	for file, data := range documents {
+		var doc map[string]any
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			t.Fatalf("could not parse %s: %v", file, err)
+		}
+		s.docs[file] = doc
+	}
+	return s
+}
+
+func (s spec) lookup(t *testing.T, file string, ref string) map[string]any {
+	t.Helper()
+
+	_, node, err := s.lookupRef(file, ref)
+	if err != nil {
+		t.Fatalf("%s: %v", file, err)
+	}
+	return node
+}
+
+// lookupRef finds the node referenced from the file.
+// The reference is either local (`#/a/b`) or points to another document (`openapi.yaml#/a/b`).
+func (s spec) lookupRef(file string, ref string) (string, map[string]any, error) {
+	target, pointer, _ := strings.Cut(ref, "#")
+	if target != "" {
+		file = target
+	}
+
+	var node any = s.docs[file]
+	if node == nil {
+		return "", nil, fmt.Errorf("unknown document in `%s`", ref)
+	}
+	for _, key := range strings.Split(strings.TrimPrefix(pointer, "/"), "/") {
+		m, ok := node.(map[string]any)
+		if !ok {
+			return "", nil, fmt.Errorf("unresolved reference `%s`", ref)
+		}
+		if node, ok = m[key]; !ok {
+			return "", nil, fmt.Errorf("unresolved reference `%s`", ref)
+		}
+	}
+
+	m, ok := node.(map[string]any)
+	if !ok {
+		return "", nil, fmt.Errorf("`%s` does not refer to an object", ref)
+	}
+	return file, m, nil
+}
+
+// resolve follows the references until it reaches a schema without one
+func (s spec) resolve(file string, schema map[string]any) (string, map[string]any, error) {
+	for {
+		ref, ok := schema["$ref"].(string)
+		if !ok {
+			return file, schema, nil
+		}
+		var err error
+		if file, schema, err = s.lookupRef(file, ref); err != nil {
+			return "", nil, err
+		}
+	}
+}
+
+type located struct {
+	file   string
+	schema map[string]any
+}
+
+// objectSchema is an object schema with allOf merged
+type objectSchema struct {
+	props      map[string]located
+	required   []string
+	additional *located
+}
+
+func (s spec) isObject(file string, schema map[string]any) bool {
+	// the unresolved references are reported by check
+	file, schema, _ = s.resolve(file, schema)
+	if _, ok := schema["allOf"]; ok {
+		return true
+	}
+	if _, ok := schema["properties"]; ok {
+		return true
+	}
+	return schemaTypes(schema)["object"]
+}
+
+func (s spec) object(file string, schema map[string]any) objectSchema {
+	obj := objectSchema{props: make(map[string]located)}
+
+	var merge func(file string, schema map[string]any)
+	merge = func(file string, schema map[string]any) {
+		file, schema, err := s.resolve(file, schema)
+		if err != nil {
+			return
+		}
+
+		if parts, ok := schema["allOf"].([]any); ok {
+			for _, part := range parts {
+				merge(file, part.(map[string]any))
+			}
+		}
+		if props, ok := schema["properties"].(map[string]any); ok {
+			for name, prop := range props {
+				// the later parts refine the earlier ones (e.g. the `kind` of a message)
+				if prev, ok := obj.props[name]; ok {
+					merged := make(map[string]any)
+					for k, v := range prev.schema {
+						merged[k] = v
+					}
+					for k, v := range prop.(map[string]any) {
+						merged[k] = v
+					}
+					obj.props[name] = located{file: file, schema: merged}
+				} else {
+					obj.props[name] = located{file: file, schema: prop.(map[string]any)}
+				}
+			}
+		}
+		required, _ := schema["required"].([]any)
+		for _, name := range required {
+			obj.required = append(obj.required, name.(string))
+		}
+		if additional, ok := schema["additionalProperties"].(map[string]any); ok {
+			obj.additional = &located{file: file, schema: additional}
+		}
+	}
+
+	merge(file, schema)
+	return obj
+}
+
+func schemaTypes(schema map[string]any) map[string]bool {
+	types := make(map[string]bool)
+	switch t := schema["type"].(type) {
+	case string:
+		types[t] = true
+	case []any:
+		for _, name := range t {
+			types[name.(string)] = true
+		}
+	}
+	return types
+}
+
+func jsonType(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		if v == float64(int64(v)) {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	default:
+		panic(fmt.Sprintf("unexpected JSON value %T", v))
+	}
+}
+
+// check returns the mismatches between the decoded JSON value and the schema
+func (s spec) check(file string, schema map[string]any, v any, path string) []string {
+	file, schema, err := s.resolve(file, schema)
+	if err != nil {
+		return []string{fmt.Sprintf("%s: %v", path, err)}
+	}
+
+	for _, key := range []string{"oneOf", "anyOf"} {
+		if branches, ok := schema[key].([]any); ok {
+			for _, branch := range branches {
+				if len(s.check(file, branch.(map[string]any), v, path)) == 0 {
+					return nil
+				}
+			}
+			return []string{fmt.Sprintf("%s: matches none of the %s schemas", path, key)}
+		}
+	}
+
+	isObject := s.isObject(file, schema)
+	types := schemaTypes(schema)
+	if isObject {
+		types["object"] = true
+	}
+
+	actual := jsonType(v)
+	switch {
+	case len(types) == 0 || types[actual]:
+	case actual == "integer" && types["number"]:
+	default:
+		return []string{fmt.Sprintf("%s: expected %v, got %s", path, sortedKeys(types), actual)}
+	}
+
+	var errs []string
+	switch v := v.(type) {
+	case []any:
+		if items, ok := schema["items"].(map[string]any); ok {
+			for i, item := range v {
+				errs = append(errs, s.check(file, items, item, fmt.Sprintf("%s[%d]", path, i))...)
+			}
+		}
+
+	case map[string]any:
+		if !isObject {
+			break
+		}
+		obj := s.object(file, schema)
+		for _, name := range obj.required {
+			if _, ok := v[name]; !ok {
+				errs = append(errs, fmt.Sprintf("%s: the required field `%s` is missing", path, name))
+			}
+		}
+		for _, name := range sortedKeys(obj.props) {
+			if _, ok := v[name]; !ok && obj.additional == nil {
+				errs = append(errs, fmt.Sprintf("%s: the field `%s` is not encoded", path, name))
+			}
+		}
+		for _, name := range sortedKeys(v) {
+			prop, ok := obj.props[name]
+			switch {
+			case ok:
+				errs = append(errs, s.check(prop.file, prop.schema, v[name], path+"."+name)...)
+			case obj.additional != nil:
+				errs = append(errs, s.check(obj.additional.file, obj.additional.schema, v[name], path+"."+name)...)
+			default:
+				errs = append(errs, fmt.Sprintf("%s: the field `%s` is not described", path, name))
+			}
+		}
+	}
+
+	return errs
+}
+
+// sample encodes a value of the type with every field set and decodes it back to a generic JSON value
+func sample(t reflect.Type) any {
+	v := reflect.New(t)
+	fill(v.Elem())
+
+	data, err := json.Marshal(v.Interface())
+	if err != nil {
+		panic(err)
+	}
+
+	var decoded any
+	if err = json.Unmarshal(data, &decoded); err != nil {
+		panic(err)
+	}
+	return decoded
+}
+
+func fill(v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Pointer:
+		v.Set(reflect.New(v.Type().Elem()))
+		fill(v.Elem())
+
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			// e.g. time.Time
+			if v.Field(i).CanSet() {
+				fill(v.Field(i))
+			}
+		}
+
+	case reflect.Slice:
+		if elem := v.Type().Elem(); elem.Kind() == reflect.Interface && elem.NumMethod() > 0 {
+			// the implementation is unknown
+			v.Set(reflect.MakeSlice(v.Type(), 0, 0))
+			return
+		}
+		v.Set(reflect.MakeSlice(v.Type(), 1, 1))
+		fill(v.Index(0))
+
+	case reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			fill(v.Index(i))
+		}
+
+	case reflect.Map:
+		v.Set(reflect.MakeMap(v.Type()))
+		key := reflect.New(v.Type().Key()).Elem()
+		fill(key)
+		value := reflect.New(v.Type().Elem()).Elem()
+		fill(value)
+		v.SetMapIndex(key, value)
+
+	case reflect.Interface:
+		if v.NumMethod() == 0 {
+			v.Set(reflect.ValueOf("value"))
+		}
+
+	case reflect.String:
+		v.SetString("value")
+
+	case reflect.Bool:
+		v.SetBool(true)
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		v.SetInt(1)
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		v.SetUint(1)
+
+	case reflect.Float32, reflect.Float64:
+		v.SetFloat(0.5)
+	}
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,155 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/spf13/viper"
+	"party-buddy/internal/configuration"
+	"time"
+)
+
+const (
+	DefaultAccessTokenTTL  = 15 * time.Minute
+	DefaultRefreshTokenTTL = 30 * 24 * time.Hour
+
+	tokenIssuer = "party-buddy"
+)
+
+var (
+	ErrTokenInvalid = errors.New("token-invalid")
+	ErrTokenExpired = errors.New("token-expired")
+)
+
+// An Issuer issues and verifies access tokens.
+//
+// Access tokens are short-lived signed JWTs whose subject is the user id.
+// Refresh tokens are opaque random strings: only their hashes are stored in the DB.
+type Issuer struct {
+	secret          []byte
+	accessTokenTTL  time.Duration
+	refreshTokenTTL time.Duration
+	legacyIDs       bool
+	legacyIDsUntil  time.Time
+}
+
+// NewIssuerFromConfig creates an Issuer configured by the auth.* config values.
+//
+// Legacy ids may only be enabled together with auth.legacy-ids-until, the end of the migration period.
+func NewIssuerFromConfig() (*Issuer, error) {
+	secret := viper.GetString("auth.secret")
+	if secret == "" {
+		return nil, configuration.ErrAuthSecretNotProvided
+	}
+
+	issuer := &Issuer{
+		secret:          []byte(secret),
+		accessTokenTTL:  viper.GetDuration("auth.access-token-ttl"),
+		refreshTokenTTL: viper.GetDuration("auth.refresh-token-ttl"),
+		legacyIDs:       viper.GetBool("auth.legacy-ids"),
+		legacyIDsUntil:  viper.GetTime("auth.legacy-ids-until"),
+	}
+	if issuer.legacyIDs && issuer.legacyIDsUntil.IsZero() {
+		return nil, configuration.ErrLegacyIDsCutoffNotProvided
+	}
+	if issuer.accessTokenTTL <= 0 {
+		issuer.accessTokenTTL = DefaultAccessTokenTTL
+	}
+	if issuer.refreshTokenTTL <= 0 {
+		issuer.refreshTokenTTL = DefaultRefreshTokenTTL
+	}
+
+	return issuer, nil
+}
+
+// NewIssuer creates an Issuer with the given secret and the default token lifetimes.
+func NewIssuer(secret []byte) *Issuer {
+	return &Issuer{
+		secret:          secret,
+		accessTokenTTL:  DefaultAccessTokenTTL,
+		refreshTokenTTL: DefaultRefreshTokenTTL,
+	}
+}
+
+// LegacyIDsAllowed tells whether clients from before token authentication are still accepted.
+//
+// While it's enabled, unregistered clients may keep using their client-generated ids as bearer tokens
+// and may register a device keeping that id.
+// It's disabled automatically once the migration period ends.
+func (i *Issuer) LegacyIDsAllowed() bool {
+	return i.legacyIDs && time.Now().Before(i.legacyIDsUntil)
+}
+
+// LegacyIDsUntil returns the end of the migration period.
+func (i *Issuer) LegacyIDsUntil() time.Time {
+	return i.legacyIDsUntil
+}
+
+// RefreshTokenTTL returns the lifetime of refresh tokens.
+func (i *Issuer) RefreshTokenTTL() time.Duration {
+	return i.refreshTokenTTL
+}
+
+// IssueAccessToken issues a new access token for the user.
+func (i *Issuer) IssueAccessToken(userID uuid.UUID) (token string, expiresAt time.Time, err error) {
+	now := time.Now()
+	expiresAt = now.Add(i.accessTokenTTL)
+
+	claims := jwt.RegisteredClaims{
+		Issuer:    tokenIssuer,
+		Subject:   userID.String(),
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(expiresAt),
+	}
+
+	token, err = jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(i.secret)
+	return
+}
+
+// VerifyAccessToken checks the token's signature and expiry and returns the user id it was issued for.
+func (i *Issuer) VerifyAccessToken(token string) (uuid.UUID, error) {
+	var claims jwt.RegisteredClaims
+	_, err := jwt.ParseWithClaims(
+		token,
+		&claims,
+		func(*jwt.Token) (any, error) { return i.secret, nil },
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithIssuer(tokenIssuer),
+		jwt.WithExpirationRequired(),
+	)
+	if errors.Is(err, jwt.ErrTokenExpired) {
+		return uuid.UUID{}, ErrTokenExpired
+	}
+	if err != nil {
+		return uuid.UUID{}, fmt.Errorf("%w: %w", ErrTokenInvalid, err)
+	}
+
+	userID, err := uuid.Parse(claims.Subject)
+	if err != nil {
+		return uuid.UUID{}, fmt.Errorf("%w: bad subject: %w", ErrTokenInvalid, err)
+	}
+
+	return userID, nil
+}
+
+// NewRefreshToken generates a new refresh token.
+// Returns the token to give to the client and its hash to store.
+func NewRefreshToken() (token string, hash []byte) {
+	data := make([]byte, 32)
+	if _, err := rand.Read(data); err != nil {
+		panic(fmt.Sprintf("could not generate refresh token: %v", err))
+	}
+
+	token = base64.RawURLEncoding.EncodeToString(data)
+	return token, HashRefreshToken(token)
+}
+
+// HashRefreshToken returns the hash under which a refresh token is stored.
+func HashRefreshToken(token string) []byte {
+	sum := sha256.Sum256([]byte(token))
+	return sum[:]
+}
@@ -0,0 +1,93 @@
+package auth
+
+import (
+	"errors"
+	"party-buddy/internal/configuration"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/spf13/viper"
+)
+
+func Test_AccessToken_Verified(t *testing.T) {
+	issuer := NewIssuer([]byte("secret"))
+	userID := uuid.New()
+
+	token, _, err := issuer.IssueAccessToken(userID)
+	if err != nil {
+		t.Fatalf("failed to issue token: %v", err)
+	}
+
+	got, err := issuer.VerifyAccessToken(token)
+	if err != nil {
+		t.Fatalf("valid token rejected: %v", err)
+	}
+	if got != userID {
+		t.Fatalf("got user id %v, expected %v", got, userID)
+	}
+}
+
+func Test_AccessToken_RejectsForeignSignature(t *testing.T) {
+	token, _, err := NewIssuer([]byte("other secret")).IssueAccessToken(uuid.New())
+	if err != nil {
+		t.Fatalf("failed to issue token: %v", err)
+	}
+
+	if _, err = NewIssuer([]byte("secret")).VerifyAccessToken(token); !errors.Is(err, ErrTokenInvalid) {
+		t.Fatalf("expected ErrTokenInvalid, got %v", err)
+	}
+}
+
+func Test_AccessToken_RejectsBareUUID(t *testing.T) {
+	if _, err := NewIssuer([]byte("secret")).VerifyAccessToken(uuid.NewString()); !errors.Is(err, ErrTokenInvalid) {
+		t.Fatalf("expected ErrTokenInvalid, got %v", err)
+	}
+}
+
+func Test_AccessToken_Expired(t *testing.T) {
+	issuer := NewIssuer([]byte("secret"))
+	issuer.accessTokenTTL = -time.Minute
+
+	token, _, err := issuer.IssueAccessToken(uuid.New())
+	if err != nil {
+		t.Fatalf("failed to issue token: %v", err)
+	}
+
+	if _, err = issuer.VerifyAccessToken(token); !errors.Is(err, ErrTokenExpired) {
+		t.Fatalf("expected ErrTokenExpired, got %v", err)
+	}
+}
+
+func Test_LegacyIDs_Cutoff(t *testing.T) {
+	issuer := NewIssuer([]byte("secret"))
+	if issuer.LegacyIDsAllowed() {
+		t.Fatal("legacy ids are allowed by default")
+	}
+
+	issuer.legacyIDs = true
+	issuer.legacyIDsUntil = time.Now().Add(time.Hour)
+	if !issuer.LegacyIDsAllowed() {
+		t.Fatal("legacy ids are rejected before the cutoff")
+	}
+
+	issuer.legacyIDsUntil = time.Now().Add(-time.Hour)
+	if issuer.LegacyIDsAllowed() {
+		t.Fatal("legacy ids are allowed after the cutoff")
+	}
+}
+
+func Test_LegacyIDs_RequireCutoff(t *testing.T) {
+	viper.Set("auth.secret", "secret")
+	viper.Set("auth.legacy-ids", true)
+	t.Cleanup(viper.Reset)
+
+	if _, err := NewIssuerFromConfig(); !errors.Is(err, configuration.ErrLegacyIDsCutoffNotProvided) {
+		t.Fatalf("expected ErrLegacyIDsCutoffNotProvided, got %v", err)
+	}
+
+	viper.Set("auth.legacy-ids-until", "2026-12-31T00:00:00Z")
+	if _, err := NewIssuerFromConfig(); err != nil {
+		t.Fatalf("failed to create the issuer: %v", err)
+	}
+}
@@ -0,0 +1,76 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"log/slog"
+	"os"
+	"party-buddy/internal/db"
+)
+
+const usage = `usage:
+  party-buddy                           start the server
+  party-buddy set-role <user-id> <role> set the role of a user (admin | base)
+`
+
+// runCommand runs a server administration command given on the command line.
+//
+// These commands are the only way to grant privileged roles:
+// the HTTP API never changes roles.
+func runCommand(ctx context.Context, args []string) {
+	switch args[0] {
+	case "set-role":
+		if len(args) != 3 {
+			fmt.Fprint(os.Stderr, usage)
+			os.Exit(2)
+		}
+		if err := setRole(ctx, args[1], args[2]); err != nil {
+			slog.Error("Failed to set role", "err", err)
+			os.Exit(1)
+		}
+
+	case "help", "-h", "--help":
+		fmt.Print(usage)
+
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n%s", args[0], usage)
+		os.Exit(2)
+	}
+}
+
+func setRole(ctx context.Context, rawUserID string, rawRole string) error {
+	userID, err := uuid.Parse(rawUserID)
+	if err != nil {
+		return fmt.Errorf("invalid user id: %w", err)
+	}
+
+	role := db.UserRole(rawRole)
+	if role != db.Admin && role != db.Base {
+		return fmt.Errorf("unknown role %q", rawRole)
+	}
+
+	dbPoolConf, err := db.GetDBConfig()
+	if err != nil {
+		return err
+	}
+	dbpool, err := db.InitDBPool(ctx, dbPoolConf)
+	if err != nil {
+		return err
+	}
+	defer dbpool.Dispose()
+
+	err = dbpool.AcquireTx(ctx, func(tx pgx.Tx) error {
+		if err := db.SetUserRole(ctx, tx, userID, role); err != nil {
+			return err
+		}
+		return tx.Commit(ctx)
+	})
+	if err != nil {
+		return err
+	}
+
+	slog.Info("the role of the user has been changed", "user_id", userID, "role", role)
+	return nil
+}
@@ -4,22 +4,29 @@ import (
 	"fmt"
 	"github.com/google/uuid"
 	"github.com/spf13/viper"
-	"log"
+	"log/slog"
 	"os"
+	"party-buddy/internal/ratelimit"
 	"strings"
 )
 
 const (
-	appEnvPrefix         = "PARTY_BUDDY"
-	appEnvDbPrefix       = "PARTY_BUDDY_DB"
-	appEnvImgPrefix      = "PARTY_BUDDY_IMG"
-	appEnvExternalPrefix = "PARTY_BUDDY_EXTERNAL"
+	appEnvPrefix          = "PARTY_BUDDY"
+	appEnvDbPrefix        = "PARTY_BUDDY_DB"
+	appEnvImgPrefix       = "PARTY_BUDDY_IMG"
+	appEnvS3Prefix        = "PARTY_BUDDY_IMG_S3"
+	appEnvExternalPrefix  = "PARTY_BUDDY_EXTERNAL"
+	appEnvAuthPrefix      = "PARTY_BUDDY_AUTH"
+	appEnvLogPrefix       = "PARTY_BUDDY_LOG"
+	appEnvRateLimitPrefix = "PARTY_BUDDY_RATELIMIT"
+	appEnvSessionPrefix   = "PARTY_BUDDY_SESSION"
 )
 
 // configureEnvs maps the config values to proper environment variables
 func configureEnvs() {
 	_ = viper.BindEnv("server.host", appEnvPrefix+"_HOST")
 	_ = viper.BindEnv("server.port", appEnvPrefix+"_PORT")
+	_ = viper.BindEnv("server.drain-period", appEnvPrefix+"_DRAIN_PERIOD")
 
 	_ = viper.BindEnv("db.host", appEnvDbPrefix+"_HOST")
 	_ = viper.BindEnv("db.port", appEnvDbPrefix+"_PORT")
@@ -29,6 +36,42 @@ func configureEnvs() {
 	_ = viper.BindEnv("db.password", appEnvDbPrefix+"_PASSWORD")
 
 	_ = viper.BindEnv("img.path", appEnvImgPrefix+"_PATH")
+	_ = viper.BindEnv("img.storage", appEnvImgPrefix+"_STORAGE")
+	_ = viper.BindEnv("img.signing.keys", appEnvImgPrefix+"_SIGNING_KEYS")
+	_ = viper.BindEnv("img.signing.ttl", appEnvImgPrefix+"_SIGNING_TTL")
+
+	_ = viper.BindEnv("img.s3.endpoint", appEnvS3Prefix+"_ENDPOINT")
+	_ = viper.BindEnv("img.s3.region", appEnvS3Prefix+"_REGION")
+	_ = viper.BindEnv("img.s3.bucket", appEnvS3Prefix+"_BUCKET")
+	_ = viper.BindEnv("img.s3.access-key", appEnvS3Prefix+"_ACCESS_KEY")
+	_ = viper.BindEnv("img.s3.secret-key", appEnvS3Prefix+"_SECRET_KEY")
+	_ = viper.BindEnv("img.s3.use-ssl", appEnvS3Prefix+"_USE_SSL")
+	_ = viper.BindEnv("img.s3.prefix", appEnvS3Prefix+"_PREFIX")
+	_ = viper.BindEnv("img.s3.presign-expiry", appEnvS3Prefix+"_PRESIGN_EXPIRY")
+
+	_ = viper.BindEnv("auth.secret", appEnvAuthPrefix+"_SECRET")
+	_ = viper.BindEnv("auth.access-token-ttl", appEnvAuthPrefix+"_ACCESS_TOKEN_TTL")
+	_ = viper.BindEnv("auth.refresh-token-ttl", appEnvAuthPrefix+"_REFRESH_TOKEN_TTL")
+	_ = viper.BindEnv("auth.legacy-ids", appEnvAuthPrefix+"_LEGACY_IDS")
+	_ = viper.BindEnv("auth.legacy-ids-until", appEnvAuthPrefix+"_LEGACY_IDS_UNTIL")
+
+	_ = viper.BindEnv("log.format", appEnvLogPrefix+"_FORMAT")
+	_ = viper.BindEnv("log.level", appEnvLogPrefix+"_LEVEL")
+
+	_ = viper.BindEnv("session.idle.warn-after", appEnvSessionPrefix+"_IDLE_WARN_AFTER")
+	_ = viper.BindEnv("session.idle.remove-after", appEnvSessionPrefix+"_IDLE_REMOVE_AFTER")
+
+	_ = viper.BindEnv("ratelimit.trust-forwarded-for", appEnvRateLimitPrefix+"_TRUST_FORWARDED_FOR")
+	_ = viper.BindEnv("ratelimit.trusted-proxies", appEnvRateLimitPrefix+"_TRUSTED_PROXIES")
+	// e.g. ratelimit.session-create.client.rate is bound to PARTY_BUDDY_RATELIMIT_SESSION_CREATE_CLIENT_RATE
+	for _, action := range ratelimit.Actions {
+		for _, scope := range []string{"client", "ip"} {
+			for _, param := range []string{"rate", "burst"} {
+				env := strings.ToUpper(strings.ReplaceAll(fmt.Sprintf("%s_%s_%s", action, scope, param), "-", "_"))
+				_ = viper.BindEnv(ratelimit.ConfigKey(action, scope, param), appEnvRateLimitPrefix+"_"+env)
+			}
+		}
+	}
 
 	_ = viper.BindEnv("external.host", appEnvExternalPrefix+"_HOST")
 	_ = viper.BindEnv("external.port", appEnvExternalPrefix+"_PORT")
@@ -50,7 +93,7 @@ func ConfigureApp() {
 	viper.AddConfigPath("./configs")
 
 	if err := viper.ReadInConfig(); err != nil {
-		log.Printf("config were not provided")
+		slog.Warn("config were not provided")
 	}
 
 	configureEnvs()
@@ -75,7 +118,12 @@ func GetImgDirectory() string {
 	return imgPath
 }
 
-func GenImgURI(imgID uuid.UUID) string {
+// GenImgURI returns the URI an image can be downloaded from.
+//
+// If image signing keys are configured, the URI is signed and expires after img.signing.ttl,
+// so it can be used without the Authorization header (e.g. in img tags).
+// If sid is valid, the URI is scoped to the session and stops working once the session ends.
+func GenImgURI(imgID uuid.UUID, sid uuid.NullUUID) string {
 	host := viper.GetString("external.host")
 	if host == "" {
 		host = viper.GetString("server.host")
@@ -85,5 +133,10 @@ func GenImgURI(imgID uuid.UUID) string {
 		port = viper.GetString("server.port")
 	}
 
-	return fmt.Sprintf("http://%v:%v/api/v1/images/%v", host, port, imgID.String())
+	uri := fmt.Sprintf("http://%v:%v/api/v1/images/%v", host, port, imgID.String())
+	if q := genImgSignature(imgID, sid); q != nil {
+		uri += "?" + q.Encode()
+	}
+
+	return uri
 }
@@ -6,6 +6,9 @@ const (
 	PlayerMin int8 = 2
 	PlayerMax int8 = 20
 
+	// QuickJoinPlayerCount is the size of the lobbies created by quick-join unless requested otherwise.
+	QuickJoinPlayerCount int8 = 8
+
 	BaseTextFieldTemplate string = "[a-zA-Zа-яА-Я0-9,./?<>()\\-_+=|;:!@#$%^&*{}\\[\\]\"'\\\\№`~ ]"
 
 	MaxNameLength        = 20
@@ -14,6 +17,9 @@ const (
 	MinTaskCount = 1
 	MaxTaskCount = 100
 
+	// MaxTaskSeed bounds the task selection seeds so that they are represented exactly in JSON numbers.
+	MaxTaskSeed int64 = 1<<53 - 1
+
 	MaxCheckedTextAnswerLength        = 20
 	CheckedTextAnswerTemplate  string = "[A-ZА-Я0-9,./?<>()\\-_+=|;:!@#$%^&*{}\\[\\]\"'\\\\№`~ ]"
 
@@ -21,6 +27,37 @@ const (
 	MaxOptionLength = 20
 
 	MaxTextAnswerLength = 255
+
+	MaxChatMessageLength = 200
+
+	// The bounds on the time the owner can add to the current deadline at once, in seconds.
+	MinAddedSeconds uint16 = 1
+	MaxAddedSeconds uint16 = 300
+
+	// The bounds on the session timings the owner can choose, in seconds.
+	MinNoOwnerTimeout     uint16 = 30
+	MaxNoOwnerTimeout     uint16 = 30 * 60
+	MinGameStartedTimeout uint16 = 1
+	MaxGameStartedTimeout uint16 = 60
+	MinTaskEndTimeout     uint16 = 3
+	MaxTaskEndTimeout     uint16 = 120
+
+	// MaxImageSize is the maximum size of an uploaded image in bytes.
+	MaxImageSize = 5 << 20
+
+	// MaxImagePixels is the maximum number of pixels (width × height) of an uploaded image.
+	// A small file may declare huge dimensions, so they're checked before the image is decoded.
+	MaxImagePixels = 40_000_000
+
+	// GameBundleVersion is the version of the game bundle manifest written on export and accepted on import.
+	GameBundleVersion uint16 = 1
+
+	// The limits on the size of an imported game bundle and of its manifest, in bytes.
+	MaxGameBundleSize   = 64 << 20
+	MaxGameManifestSize = 1 << 20
+
+	// MaxCSVImportSize is the maximum size of an imported CSV file in bytes.
+	MaxCSVImportSize = 1 << 20
 )
 
 var (
@@ -8,3 +8,26 @@ var (
 	ErrDBPortNotProvided     = errors.New("db-port-not-provided")
 	ErrDBNameNotProvided     = errors.New("db-name-not-provided")
 )
+
+var (
+	ErrImgPathNotProvided       = errors.New("img-path-not-provided")
+	ErrImgStorageUnknown        = errors.New("img-storage-unknown")
+	ErrS3EndpointNotProvided    = errors.New("s3-endpoint-not-provided")
+	ErrS3BucketNotProvided      = errors.New("s3-bucket-not-provided")
+	ErrS3CredentialsNotProvided = errors.New("s3-credentials-not-provided")
+)
+
+var (
+	ErrLogFormatUnknown = errors.New("log-format-unknown")
+	ErrLogLevelUnknown  = errors.New("log-level-unknown")
+)
+
+var (
+	ErrAuthSecretNotProvided      = errors.New("auth-secret-not-provided")
+	ErrLegacyIDsCutoffNotProvided = errors.New("legacy-ids-cutoff-not-provided")
+)
+
+var (
+	ErrImgSignatureInvalid = errors.New("img-signature-invalid")
+	ErrImgSignatureExpired = errors.New("img-signature-expired")
+)
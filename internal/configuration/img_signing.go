@@ -0,0 +1,139 @@
+package configuration
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/spf13/viper"
+	"log/slog"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultImgURITTL is how long signed image URIs stay valid unless img.signing.ttl is set.
+const DefaultImgURITTL = 2 * time.Hour
+
+type imgSigningKey struct {
+	ID     string `mapstructure:"id"`
+	Secret string `mapstructure:"secret"`
+}
+
+var (
+	imgSigningKeys     []imgSigningKey
+	imgSigningKeysOnce sync.Once
+)
+
+// getImgSigningKeys returns the image URI signing keys.
+// The first key is used for signing, the rest are only accepted for verification.
+// This allows rotating keys: prepend a new key and remove the old one once the URIs it signed expire.
+//
+// The keys are read from img.signing.keys, which is either a list of {id, secret} maps
+// or a string of the form "id1:secret1,id2:secret2" (convenient for the environment variable).
+func getImgSigningKeys() []imgSigningKey {
+	imgSigningKeysOnce.Do(func() {
+		if raw := viper.GetString("img.signing.keys"); raw != "" {
+			for _, pair := range strings.Split(raw, ",") {
+				id, secret, found := strings.Cut(strings.TrimSpace(pair), ":")
+				if !found || id == "" || secret == "" {
+					slog.Warn("ignoring a malformed image signing key", "kid", id)
+					continue
+				}
+				imgSigningKeys = append(imgSigningKeys, imgSigningKey{ID: id, Secret: secret})
+			}
+			return
+		}
+
+		if err := viper.UnmarshalKey("img.signing.keys", &imgSigningKeys); err != nil {
+			slog.Error("could not read image signing keys", "err", err)
+			imgSigningKeys = nil
+		}
+	})
+
+	return imgSigningKeys
+}
+
+func getImgURITTL() time.Duration {
+	if ttl := viper.GetDuration("img.signing.ttl"); ttl > 0 {
+		return ttl
+	}
+	return DefaultImgURITTL
+}
+
+func signImg(key imgSigningKey, imgID uuid.UUID, sid uuid.NullUUID, expires int64) string {
+	var scope string
+	if sid.Valid {
+		scope = sid.UUID.String()
+	}
+
+	mac := hmac.New(sha256.New, []byte(key.Secret))
+	_, _ = fmt.Fprintf(mac, "v1\n%s\n%s\n%d", imgID, scope, expires)
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// genImgSignature returns the query parameters of a signed image URI, or nil if signing is not configured.
+func genImgSignature(imgID uuid.UUID, sid uuid.NullUUID) url.Values {
+	keys := getImgSigningKeys()
+	if len(keys) == 0 {
+		return nil
+	}
+
+	expires := time.Now().Add(getImgURITTL()).Unix()
+	q := url.Values{}
+	q.Set("exp", strconv.FormatInt(expires, 10))
+	if sid.Valid {
+		q.Set("sid", sid.UUID.String())
+	}
+	q.Set("kid", keys[0].ID)
+	q.Set("sig", signImg(keys[0], imgID, sid, expires))
+	return q
+}
+
+// HasImgSignature returns true iff the query contains a signature, whether valid or not.
+func HasImgSignature(q url.Values) bool {
+	return q.Has("sig")
+}
+
+// VerifyImgSignature checks the signature of an image URI query produced by GenImgURI.
+// Returns the session the URI is scoped to (if any).
+func VerifyImgSignature(imgID uuid.UUID, q url.Values) (sid uuid.NullUUID, err error) {
+	expires, err := strconv.ParseInt(q.Get("exp"), 10, 64)
+	if err != nil {
+		return sid, ErrImgSignatureInvalid
+	}
+
+	if raw := q.Get("sid"); raw != "" {
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			return sid, ErrImgSignatureInvalid
+		}
+		sid = uuid.NullUUID{UUID: id, Valid: true}
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(q.Get("sig"))
+	if err != nil {
+		return sid, ErrImgSignatureInvalid
+	}
+
+	kid := q.Get("kid")
+	for _, key := range getImgSigningKeys() {
+		if key.ID != kid {
+			continue
+		}
+
+		expected, _ := base64.RawURLEncoding.DecodeString(signImg(key, imgID, sid, expires))
+		if !hmac.Equal(sig, expected) {
+			return sid, ErrImgSignatureInvalid
+		}
+		if time.Now().Unix() > expires {
+			return sid, ErrImgSignatureExpired
+		}
+		return sid, nil
+	}
+
+	return sid, ErrImgSignatureInvalid
+}
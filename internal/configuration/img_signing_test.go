@@ -0,0 +1,92 @@
+package configuration
+
+import (
+	"errors"
+	"net/url"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func setImgSigningKeys(keys ...imgSigningKey) {
+	imgSigningKeysOnce = sync.Once{}
+	imgSigningKeysOnce.Do(func() {
+		imgSigningKeys = keys
+	})
+}
+
+func Test_ImgSignature_Verified(t *testing.T) {
+	setImgSigningKeys(imgSigningKey{ID: "k1", Secret: "secret"})
+	imgID := uuid.New()
+	sid := uuid.NullUUID{UUID: uuid.New(), Valid: true}
+
+	q := genImgSignature(imgID, sid)
+	got, err := VerifyImgSignature(imgID, q)
+	if err != nil {
+		t.Fatalf("valid signature rejected: %v", err)
+	}
+	if got != sid {
+		t.Fatalf("got sid %v, expected %v", got, sid)
+	}
+}
+
+func Test_ImgSignature_RejectsOtherImageAndScope(t *testing.T) {
+	setImgSigningKeys(imgSigningKey{ID: "k1", Secret: "secret"})
+	imgID := uuid.New()
+	q := genImgSignature(imgID, uuid.NullUUID{UUID: uuid.New(), Valid: true})
+
+	if _, err := VerifyImgSignature(uuid.New(), q); !errors.Is(err, ErrImgSignatureInvalid) {
+		t.Fatalf("signature accepted for another image: %v", err)
+	}
+
+	q.Set("sid", uuid.NewString())
+	if _, err := VerifyImgSignature(imgID, q); !errors.Is(err, ErrImgSignatureInvalid) {
+		t.Fatalf("signature accepted for another session: %v", err)
+	}
+
+	q.Del("sid")
+	if _, err := VerifyImgSignature(imgID, q); !errors.Is(err, ErrImgSignatureInvalid) {
+		t.Fatalf("signature accepted without a scope: %v", err)
+	}
+}
+
+func Test_ImgSignature_Expired(t *testing.T) {
+	key := imgSigningKey{ID: "k1", Secret: "secret"}
+	setImgSigningKeys(key)
+	imgID := uuid.New()
+	expires := time.Now().Add(-time.Minute).Unix()
+
+	q := url.Values{}
+	q.Set("exp", strconv.FormatInt(expires, 10))
+	q.Set("kid", key.ID)
+	q.Set("sig", signImg(key, imgID, uuid.NullUUID{}, expires))
+
+	if _, err := VerifyImgSignature(imgID, q); !errors.Is(err, ErrImgSignatureExpired) {
+		t.Fatalf("expected ErrImgSignatureExpired, got %v", err)
+	}
+}
+
+func Test_ImgSignature_KeyRotation(t *testing.T) {
+	oldKey := imgSigningKey{ID: "old", Secret: "old secret"}
+	newKey := imgSigningKey{ID: "new", Secret: "new secret"}
+	imgID := uuid.New()
+
+	setImgSigningKeys(oldKey)
+	q := genImgSignature(imgID, uuid.NullUUID{})
+
+	setImgSigningKeys(newKey, oldKey)
+	if _, err := VerifyImgSignature(imgID, q); err != nil {
+		t.Fatalf("signature made with a retired key rejected: %v", err)
+	}
+	if kid := genImgSignature(imgID, uuid.NullUUID{}).Get("kid"); kid != newKey.ID {
+		t.Fatalf("signed with key %q, expected %q", kid, newKey.ID)
+	}
+
+	setImgSigningKeys(newKey)
+	if _, err := VerifyImgSignature(imgID, q); !errors.Is(err, ErrImgSignatureInvalid) {
+		t.Fatalf("signature made with a removed key accepted: %v", err)
+	}
+}
@@ -0,0 +1,33 @@
+package db
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+type AdminAction string
+
+const (
+	AdminListSessions AdminAction = "list-sessions"
+	AdminViewSession  AdminAction = "view-session"
+	AdminCloseSession AdminAction = "close-session"
+	AdminKickPlayer   AdminAction = "kick-player"
+	AdminBanPlayer    AdminAction = "ban-player"
+)
+
+// CreateAdminAuditEntry records an action performed by an administrator
+func CreateAdminAuditEntry(
+	ctx context.Context,
+	tx pgx.Tx,
+	adminID uuid.UUID,
+	action AdminAction,
+	sessionID uuid.NullUUID,
+	details string,
+) error {
+	_, err := tx.Exec(ctx, `
+		INSERT INTO admin_audit_log (admin_id, action, session_id, details) VALUES ($1, $2, $3, $4)
+		`, uuid.NullUUID{UUID: adminID, Valid: true}, action, sessionID, details)
+
+	return err
+}
@@ -48,12 +48,27 @@ const (
 	Base  UserRole = "base"
 )
 
-// UserEntity - info about user roles
+// UserEntity - info about registered users and their roles
 // Table - users
 type UserEntity struct {
 	ID uuid.NullUUID `db:"id"`
 
 	Role UserRole `db:"role"`
+
+	CreatedAt time.Time `db:"created_at"`
+}
+
+// RefreshTokenEntity - a refresh token issued to a device
+// Table - refresh_tokens
+type RefreshTokenEntity struct {
+	// TokenHash is the SHA-256 hash of the token
+	TokenHash []byte `db:"token_hash"`
+
+	UserID uuid.NullUUID `db:"user_id"`
+
+	CreatedAt time.Time  `db:"created_at"`
+	ExpiresAt time.Time  `db:"expires_at"`
+	RevokedAt *time.Time `db:"revoked_at"`
 }
 
 type TaskKind string
@@ -90,6 +105,11 @@ type TaskEntity struct {
 	TaskKind TaskKind `db:"task_kind"`
 }
 
+// HasAnswers returns true if the task has answers, which only its owner may see.
+func (t TaskEntity) HasAnswers() bool {
+	return t.TaskKind == CheckedText || t.TaskKind == Choice
+}
+
 // CheckedTextTaskEntity - task with TaskKind == CheckedText.
 // Relationship 1:1
 // Relative table - checked_text_tasks
@@ -125,6 +145,9 @@ type GameEntity struct {
 	OwnerID uuid.NullUUID `db:"owner_id"`
 	ImageID uuid.NullUUID `db:"image_id"` // may be nil
 
+	// ForkedFrom is the game this one has been cloned from, if any
+	ForkedFrom uuid.NullUUID `db:"forked_from"`
+
 	CreatedAt time.Time `db:"created_at"`
 	UpdatedAt time.Time `db:"updated_at"`
 }
@@ -139,3 +162,20 @@ type GameTaskEntity struct {
 	// TaskIndex is used to define tasks order in game
 	TaskIndex int `db:"task_idx"`
 }
+
+// AdminAuditEntity is a record of an action performed by an administrator.
+// Table - admin_audit_log
+type AdminAuditEntity struct {
+	ID int64 `db:"id"`
+
+	AdminID uuid.NullUUID `db:"admin_id"`
+
+	Action AdminAction `db:"action"`
+
+	// SessionID is the session the action was performed on, if any.
+	SessionID uuid.NullUUID `db:"session_id"`
+
+	Details string `db:"details"`
+
+	CreatedAt time.Time `db:"created_at"`
+}
@@ -24,3 +24,88 @@ func GameByID(ctx context.Context, tx pgx.Tx, gameID uuid.UUID) (GameEntity, err
 	}
 	return entities[0], nil
 }
+
+// CreateGame creates a new game record in db and returns its id.
+// The game has no tasks until they're added with CreateGameTask.
+func CreateGame(
+	ctx context.Context,
+	tx pgx.Tx,
+	owner uuid.UUID,
+	name string,
+	description string,
+	imgID uuid.NullUUID,
+) (uuid.UUID, error) {
+	gameID, err := uuid.NewRandom()
+	if err != nil {
+		return uuid.UUID{}, err
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO games (id, name, owner_id, description, image_id) VALUES ($1, $2, $3, $4, $5)
+		`, uuid.NullUUID{UUID: gameID, Valid: true}, name, uuid.NullUUID{UUID: owner, Valid: true}, description, imgID)
+	if err != nil {
+		return uuid.UUID{}, err
+	}
+	return gameID, nil
+}
+
+// CreateGameTask adds the task to the game at the position taskIdx
+func CreateGameTask(ctx context.Context, tx pgx.Tx, gameID uuid.UUID, taskID uuid.UUID, taskIdx int) error {
+	_, err := tx.Exec(ctx, `
+		INSERT INTO game_tasks (game_id, task_idx, task_id) VALUES ($1, $2, $3)
+		`, uuid.NullUUID{UUID: gameID, Valid: true}, taskIdx, uuid.NullUUID{UUID: taskID, Valid: true})
+	return err
+}
+
+// ForkGame copies the game record with id gameID under the owner and returns the id of the copy.
+// The copy has no tasks; its forked_from refers to the original game.
+func ForkGame(ctx context.Context, tx pgx.Tx, gameID uuid.UUID, owner uuid.UUID) (uuid.UUID, error) {
+	forkID, err := uuid.NewRandom()
+	if err != nil {
+		return uuid.UUID{}, err
+	}
+
+	tag, err := tx.Exec(ctx, `
+		INSERT INTO games (id, name, owner_id, description, image_id, forked_from)
+			SELECT $1, name, $2, description, image_id, id FROM games WHERE id = $3
+		`, uuid.NullUUID{UUID: forkID, Valid: true}, uuid.NullUUID{UUID: owner, Valid: true},
+		uuid.NullUUID{UUID: gameID, Valid: true})
+	if err != nil {
+		return uuid.UUID{}, err
+	}
+	if tag.RowsAffected() != 1 {
+		return uuid.UUID{}, RecordNotFound{}
+	}
+	return forkID, nil
+}
+
+// GameHasHiddenAnswers returns true iff the game has tasks with answers not owned by the user
+func GameHasHiddenAnswers(ctx context.Context, tx pgx.Tx, gameID uuid.UUID, user uuid.UUID) (bool, error) {
+	var hidden bool
+	err := tx.QueryRow(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM game_tasks gt JOIN tasks t ON t.id = gt.task_id
+			WHERE gt.game_id = $1 AND t.owner_id <> $2 AND t.task_kind IN ($3, $4)
+		)
+		`, uuid.NullUUID{UUID: gameID, Valid: true}, uuid.NullUUID{UUID: user, Valid: true},
+		CheckedText, Choice).Scan(&hidden)
+
+	return hidden, err
+}
+
+// GetGameTaskRefs returns the references of the game to its tasks, ordered by task_idx
+func GetGameTaskRefs(ctx context.Context, tx pgx.Tx, gameID uuid.UUID) ([]GameTaskEntity, error) {
+	rows, err := tx.Query(ctx, `
+		SELECT * FROM game_tasks WHERE game_id = $1 ORDER BY task_idx
+	`, uuid.NullUUID{UUID: gameID, Valid: true})
+
+	if err != nil {
+		return []GameTaskEntity{}, err
+	}
+
+	entities, err := pgx.CollectRows(rows, pgx.RowToStructByNameLax[GameTaskEntity])
+	if err != nil {
+		return []GameTaskEntity{}, err
+	}
+	return entities, nil
+}
@@ -5,10 +5,6 @@ import (
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
-	"image"
-	"image/jpeg"
-	"os"
-	"party-buddy/internal/configuration"
 )
 
 // CreateImageMetadata creates new image metadata record in db
@@ -69,30 +65,48 @@ func GetImageMetadataByID(tx pgx.Tx, ctx context.Context, imgID uuid.NullUUID) (
 
 // SetImageUploaded sets for image with id imgID field uploaded to value
 func SetImageUploaded(tx pgx.Tx, ctx context.Context, imgID uuid.NullUUID, value bool) error {
-	return tx.QueryRow(ctx, `
+	_, err := tx.Exec(ctx, `
 		UPDATE images SET uploaded = $2 WHERE id = $1
-		`, imgID, pgtype.Bool{Bool: value, Valid: true}).Scan()
+		`, imgID, pgtype.Bool{Bool: value, Valid: true})
+	return err
 }
 
 // SetImageReadOnly sets for image with id imgID field read_only to value
 func SetImageReadOnly(tx pgx.Tx, ctx context.Context, imgID uuid.NullUUID, value bool) error {
-	return tx.QueryRow(ctx, `
+	_, err := tx.Exec(ctx, `
 		UPDATE images SET read_only = $2 WHERE id = $1
-		`, imgID, pgtype.Bool{Bool: value, Valid: true}).Scan()
+		`, imgID, pgtype.Bool{Bool: value, Valid: true})
+	return err
 }
 
-func GetImageFromFS(imgID uuid.UUID) (image.Image, error) {
-	imgDir := configuration.GetImgDirectory()
-	imgPath := imgDir + imgID.String()
+// IsImagePublic returns true iff the image is used by a public game:
+// either as the game image or as an image of one of its tasks.
+func IsImagePublic(tx pgx.Tx, ctx context.Context, imgID uuid.NullUUID) (bool, error) {
+	var public bool
+	err := tx.QueryRow(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM games WHERE image_id = $1
+		) OR EXISTS (
+			SELECT 1 FROM tasks t
+			INNER JOIN game_tasks gt
+			ON t.id = gt.task_id
+			WHERE t.image_id = $1
+		)
+		`, imgID).Scan(&public)
 
-	file, err := os.Open(imgPath)
-	if err != nil {
-		return nil, err
-	}
+	return public, err
+}
 
-	img, err := jpeg.Decode(file)
-	if err != nil {
-		return nil, err
-	}
-	return img, nil
+// SetGameImagesReadOnly makes the image of the game and those of its tasks read-only
+func SetGameImagesReadOnly(tx pgx.Tx, ctx context.Context, gameID uuid.UUID) error {
+	_, err := tx.Exec(ctx, `
+		UPDATE images SET read_only = true WHERE id IN (
+			SELECT image_id FROM games WHERE id = $1
+			UNION
+			SELECT t.image_id FROM tasks t
+				INNER JOIN game_tasks gt ON t.id = gt.task_id
+				WHERE gt.game_id = $1
+		)
+		`, uuid.NullUUID{UUID: gameID, Valid: true})
+	return err
 }
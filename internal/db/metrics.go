@@ -0,0 +1,58 @@
+package db
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	poolAcquiredConnsDesc = prometheus.NewDesc(
+		"partybuddy_db_pool_acquired_conns", "Connections currently acquired from the pool.", nil, nil)
+	poolIdleConnsDesc = prometheus.NewDesc(
+		"partybuddy_db_pool_idle_conns", "Idle connections in the pool.", nil, nil)
+	poolTotalConnsDesc = prometheus.NewDesc(
+		"partybuddy_db_pool_total_conns", "Total connections in the pool.", nil, nil)
+	poolMaxConnsDesc = prometheus.NewDesc(
+		"partybuddy_db_pool_max_conns", "Maximum size of the pool.", nil, nil)
+	poolAcquiresDesc = prometheus.NewDesc(
+		"partybuddy_db_pool_acquires_total", "Successful connection acquisitions.", nil, nil)
+	poolEmptyAcquiresDesc = prometheus.NewDesc(
+		"partybuddy_db_pool_empty_acquires_total", "Acquisitions that had to wait for a connection.", nil, nil)
+	poolCanceledAcquiresDesc = prometheus.NewDesc(
+		"partybuddy_db_pool_canceled_acquires_total", "Acquisitions canceled by their context.", nil, nil)
+	poolAcquireSecondsDesc = prometheus.NewDesc(
+		"partybuddy_db_pool_acquire_seconds_total", "Total time spent acquiring connections.", nil, nil)
+)
+
+// poolCollector reports pgxpool statistics on scrape.
+type poolCollector struct {
+	d *DBPool
+}
+
+// Collector returns a Prometheus collector reporting the pool statistics.
+func (d *DBPool) Collector() prometheus.Collector {
+	return poolCollector{d: d}
+}
+
+func (c poolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- poolAcquiredConnsDesc
+	ch <- poolIdleConnsDesc
+	ch <- poolTotalConnsDesc
+	ch <- poolMaxConnsDesc
+	ch <- poolAcquiresDesc
+	ch <- poolEmptyAcquiresDesc
+	ch <- poolCanceledAcquiresDesc
+	ch <- poolAcquireSecondsDesc
+}
+
+func (c poolCollector) Collect(ch chan<- prometheus.Metric) {
+	stat := c.d.pool.Stat()
+
+	ch <- prometheus.MustNewConstMetric(poolAcquiredConnsDesc, prometheus.GaugeValue, float64(stat.AcquiredConns()))
+	ch <- prometheus.MustNewConstMetric(poolIdleConnsDesc, prometheus.GaugeValue, float64(stat.IdleConns()))
+	ch <- prometheus.MustNewConstMetric(poolTotalConnsDesc, prometheus.GaugeValue, float64(stat.TotalConns()))
+	ch <- prometheus.MustNewConstMetric(poolMaxConnsDesc, prometheus.GaugeValue, float64(stat.MaxConns()))
+	ch <- prometheus.MustNewConstMetric(poolAcquiresDesc, prometheus.CounterValue, float64(stat.AcquireCount()))
+	ch <- prometheus.MustNewConstMetric(poolEmptyAcquiresDesc, prometheus.CounterValue, float64(stat.EmptyAcquireCount()))
+	ch <- prometheus.MustNewConstMetric(poolCanceledAcquiresDesc, prometheus.CounterValue, float64(stat.CanceledAcquireCount()))
+	ch <- prometheus.MustNewConstMetric(poolAcquireSecondsDesc, prometheus.CounterValue, stat.AcquireDuration().Seconds())
+}
@@ -0,0 +1,41 @@
+package db
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"time"
+)
+
+// CreateRefreshToken stores the hash of a new refresh token issued to a user
+func CreateRefreshToken(ctx context.Context, tx pgx.Tx, tokenHash []byte, userID uuid.UUID, expiresAt time.Time) error {
+	_, err := tx.Exec(ctx, `
+		INSERT INTO refresh_tokens (token_hash, user_id, expires_at) VALUES ($1, $2, $3)
+		`, tokenHash, uuid.NullUUID{UUID: userID, Valid: true}, expiresAt)
+
+	return err
+}
+
+// RevokeRefreshToken revokes a valid (not expired and not revoked) refresh token
+// and returns the id of the user it was issued to.
+//
+// Returns RecordNotFound if there's no such valid token.
+func RevokeRefreshToken(ctx context.Context, tx pgx.Tx, tokenHash []byte) (uuid.UUID, error) {
+	rows, err := tx.Query(ctx, `
+		UPDATE refresh_tokens SET revoked_at = now()
+			WHERE token_hash = $1 AND revoked_at IS NULL AND expires_at > now()
+			RETURNING user_id
+		`, tokenHash)
+	if err != nil {
+		return uuid.UUID{}, err
+	}
+
+	userIDs, err := pgx.CollectRows(rows, pgx.RowTo[uuid.UUID])
+	if err != nil {
+		return uuid.UUID{}, err
+	}
+	if len(userIDs) != 1 {
+		return uuid.UUID{}, RecordNotFound{}
+	}
+	return userIDs[0], nil
+}
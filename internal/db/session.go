@@ -32,3 +32,18 @@ func RemoveSessionImageRefs(ctx context.Context, tx pgx.Tx, sid uuid.UUID) error
 
 	return err
 }
+
+// GetSessionsByImageRef returns the ids of all sessions referencing an image.
+func GetSessionsByImageRef(ctx context.Context, tx pgx.Tx, imageID uuid.UUID) ([]uuid.UUID, error) {
+	rows, err := tx.Query(ctx, `
+		SELECT session_id FROM session_image_refs
+			WHERE image_id = $1
+		`,
+		uuid.NullUUID{UUID: imageID, Valid: true},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, pgx.RowTo[uuid.UUID])
+}
@@ -48,7 +48,7 @@ func GetTextAnswerForTaskByID(ctx context.Context, tx pgx.Tx, taskID uuid.UUID)
 
 func GetChoicesForTaskByID(ctx context.Context, tx pgx.Tx, taskID uuid.UUID) ([]ChoiceTaskOptionsEntity, error) {
 	rows, err := tx.Query(ctx, `
-		SELECT * FROM choice_task_options WHERE task_id = $1
+		SELECT * FROM choice_task_options WHERE task_id = $1 ORDER BY id
 	`, uuid.NullUUID{UUID: taskID, Valid: true})
 
 	if err != nil {
@@ -61,3 +61,80 @@ func GetChoicesForTaskByID(ctx context.Context, tx pgx.Tx, taskID uuid.UUID) ([]
 	}
 	return entities, nil
 }
+
+// CreateTask creates a new task record in db and returns its id.
+// The ID of the entity is ignored.
+func CreateTask(ctx context.Context, tx pgx.Tx, entity TaskEntity) (uuid.UUID, error) {
+	taskID, err := uuid.NewRandom()
+	if err != nil {
+		return uuid.UUID{}, err
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO tasks (id, name, owner_id, description, image_id, duration_secs, poll_duration_secs, poll_duration_type, task_kind)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		`, uuid.NullUUID{UUID: taskID, Valid: true}, entity.Name, entity.OwnerID, entity.Description, entity.ImageID,
+		entity.DurationSeconds, entity.PollDurationSeconds, entity.PollDurationType, entity.TaskKind)
+	if err != nil {
+		return uuid.UUID{}, err
+	}
+	return taskID, nil
+}
+
+// CreateCheckedTextTask stores the answer for the checked-text task with id taskID
+func CreateCheckedTextTask(ctx context.Context, tx pgx.Tx, taskID uuid.UUID, answer string) error {
+	_, err := tx.Exec(ctx, `
+		INSERT INTO checked_text_tasks (task_id, answer) VALUES ($1, $2)
+		`, uuid.NullUUID{UUID: taskID, Valid: true}, answer)
+	return err
+}
+
+// CreateChoiceTaskOption adds an option to the choice task with id taskID.
+// The options are listed in the order they were added.
+func CreateChoiceTaskOption(ctx context.Context, tx pgx.Tx, taskID uuid.UUID, alternative string, correct bool) error {
+	_, err := tx.Exec(ctx, `
+		INSERT INTO choice_task_options (task_id, alternative, correct) VALUES ($1, $2, $3)
+		`, uuid.NullUUID{UUID: taskID, Valid: true}, alternative, correct)
+	return err
+}
+
+// CopyTask copies the task with id taskID under the owner, along with its answer or options,
+// and returns the id of the copy. The copy refers to the same image.
+func CopyTask(ctx context.Context, tx pgx.Tx, taskID uuid.UUID, owner uuid.UUID) (uuid.UUID, error) {
+	copyID, err := uuid.NewRandom()
+	if err != nil {
+		return uuid.UUID{}, err
+	}
+	copyNullID := uuid.NullUUID{UUID: copyID, Valid: true}
+	taskNullID := uuid.NullUUID{UUID: taskID, Valid: true}
+
+	tag, err := tx.Exec(ctx, `
+		INSERT INTO tasks (id, name, owner_id, description, image_id, duration_secs, poll_duration_secs, poll_duration_type, task_kind)
+			SELECT $1, name, $2, description, image_id, duration_secs, poll_duration_secs, poll_duration_type, task_kind
+			FROM tasks WHERE id = $3
+		`, copyNullID, uuid.NullUUID{UUID: owner, Valid: true}, taskNullID)
+	if err != nil {
+		return uuid.UUID{}, err
+	}
+	if tag.RowsAffected() != 1 {
+		return uuid.UUID{}, RecordNotFound{}
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO checked_text_tasks (task_id, answer)
+			SELECT $1, answer FROM checked_text_tasks WHERE task_id = $2
+		`, copyNullID, taskNullID)
+	if err != nil {
+		return uuid.UUID{}, err
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO choice_task_options (task_id, alternative, correct)
+			SELECT $1, alternative, correct FROM choice_task_options WHERE task_id = $2 ORDER BY id
+		`, copyNullID, taskNullID)
+	if err != nil {
+		return uuid.UUID{}, err
+	}
+
+	return copyID, nil
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
+	"time"
 )
 
 func GetUserByID(ctx context.Context, tx pgx.Tx, userID uuid.UUID) (UserEntity, error) {
@@ -27,3 +28,43 @@ func GetUserByID(ctx context.Context, tx pgx.Tx, userID uuid.UUID) (UserEntity,
 	}
 	return entities[0], nil
 }
+
+// UserExists returns true iff the user is registered
+func UserExists(ctx context.Context, tx pgx.Tx, userID uuid.UUID) (bool, error) {
+	var exists bool
+	err := tx.QueryRow(ctx, `
+		SELECT EXISTS (SELECT 1 FROM users WHERE id = $1)
+		`, uuid.NullUUID{UUID: userID, Valid: true}).Scan(&exists)
+
+	return exists, err
+}
+
+// LegacyIDSeen returns true iff the unregistered user owned any images or games created before the given time
+func LegacyIDSeen(ctx context.Context, tx pgx.Tx, userID uuid.UUID, before time.Time) (bool, error) {
+	var seen bool
+	err := tx.QueryRow(ctx, `
+		SELECT EXISTS (SELECT 1 FROM images WHERE owner_id = $1 AND created_at < $2)
+			OR EXISTS (SELECT 1 FROM games WHERE owner_id = $1 AND created_at < $2)
+		`, uuid.NullUUID{UUID: userID, Valid: true}, before).Scan(&seen)
+
+	return seen, err
+}
+
+// CreateUser registers a new user with the given role
+func CreateUser(ctx context.Context, tx pgx.Tx, userID uuid.UUID, role UserRole) error {
+	_, err := tx.Exec(ctx, `
+		INSERT INTO users (id, role) VALUES ($1, $2)
+		`, uuid.NullUUID{UUID: userID, Valid: true}, role)
+
+	return err
+}
+
+// SetUserRole sets the role of a user, registering them if necessary
+func SetUserRole(ctx context.Context, tx pgx.Tx, userID uuid.UUID, role UserRole) error {
+	_, err := tx.Exec(ctx, `
+		INSERT INTO users (id, role) VALUES ($1, $2)
+			ON CONFLICT (id) DO UPDATE SET role = EXCLUDED.role
+		`, uuid.NullUUID{UUID: userID, Valid: true}, role)
+
+	return err
+}
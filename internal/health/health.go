@@ -0,0 +1,73 @@
+// Package health runs the readiness checks reported by the /readyz endpoint.
+package health
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// CheckTimeout bounds the time a single check may take.
+const CheckTimeout = 2 * time.Second
+
+// A Check returns nil if the dependency it checks is usable.
+type Check func(ctx context.Context) error
+
+type namedCheck struct {
+	name  string
+	check Check
+}
+
+// A Checker holds the readiness checks and the draining flag.
+type Checker struct {
+	checks   []namedCheck
+	draining atomic.Bool
+}
+
+func NewChecker() *Checker {
+	return &Checker{}
+}
+
+// Add registers a named check.
+// Checks must be added before the checker is used concurrently.
+func (c *Checker) Add(name string, check Check) {
+	c.checks = append(c.checks, namedCheck{name: name, check: check})
+}
+
+// SetDraining marks the server as shutting down: it's no longer ready to accept traffic.
+func (c *Checker) SetDraining() {
+	c.draining.Store(true)
+}
+
+// Draining returns true once SetDraining has been called.
+func (c *Checker) Draining() bool {
+	return c.draining.Load()
+}
+
+// Run runs all checks concurrently, each with CheckTimeout.
+// The result maps the check names to their errors (nil for passing checks).
+func (c *Checker) Run(ctx context.Context) map[string]error {
+	results := make(map[string]error, len(c.checks))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	for _, check := range c.checks {
+		check := check
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			checkCtx, cancel := context.WithTimeout(ctx, CheckTimeout)
+			defer cancel()
+			err := check.check(checkCtx)
+
+			mu.Lock()
+			results[check.name] = err
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	return results
+}
@@ -0,0 +1,46 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	c := NewChecker()
+	errBroken := errors.New("broken")
+	c.Add("ok", func(ctx context.Context) error { return nil })
+	c.Add("broken", func(ctx context.Context) error { return errBroken })
+	c.Add("slow", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	results := c.Run(ctx)
+
+	if len(results) != 3 {
+		t.Fatalf("expected 3 results, got %v", results)
+	}
+	if results["ok"] != nil {
+		t.Errorf("ok: got %v", results["ok"])
+	}
+	if !errors.Is(results["broken"], errBroken) {
+		t.Errorf("broken: got %v", results["broken"])
+	}
+	if !errors.Is(results["slow"], context.Canceled) {
+		t.Errorf("slow: got %v", results["slow"])
+	}
+}
+
+func TestDraining(t *testing.T) {
+	c := NewChecker()
+	if c.Draining() {
+		t.Error("a new checker is draining")
+	}
+	c.SetDraining()
+	if !c.Draining() {
+		t.Error("SetDraining had no effect")
+	}
+}
@@ -0,0 +1,60 @@
+package imgstore
+
+import (
+	"fmt"
+	"github.com/spf13/viper"
+	"party-buddy/internal/configuration"
+)
+
+const (
+	KindFS = "fs"
+	KindS3 = "s3"
+)
+
+// NewFromConfig creates the image storage selected by the img.storage config value.
+// The local filesystem storage is used by default.
+func NewFromConfig() (Storage, error) {
+	switch kind := viper.GetString("img.storage"); kind {
+	case "", KindFS:
+		dir := configuration.GetImgDirectory()
+		if dir == "" {
+			return nil, configuration.ErrImgPathNotProvided
+		}
+		return NewFSStorage(dir)
+
+	case KindS3:
+		conf, err := getS3Config()
+		if err != nil {
+			return nil, err
+		}
+		return NewS3Storage(conf)
+
+	default:
+		return nil, fmt.Errorf("%w: %q", configuration.ErrImgStorageUnknown, kind)
+	}
+}
+
+func getS3Config() (S3Config, error) {
+	conf := S3Config{
+		Endpoint:      viper.GetString("img.s3.endpoint"),
+		Region:        viper.GetString("img.s3.region"),
+		Bucket:        viper.GetString("img.s3.bucket"),
+		AccessKey:     viper.GetString("img.s3.access-key"),
+		SecretKey:     viper.GetString("img.s3.secret-key"),
+		UseSSL:        viper.GetBool("img.s3.use-ssl"),
+		Prefix:        viper.GetString("img.s3.prefix"),
+		PresignExpiry: viper.GetDuration("img.s3.presign-expiry"),
+	}
+
+	if conf.Endpoint == "" {
+		return S3Config{}, configuration.ErrS3EndpointNotProvided
+	}
+	if conf.Bucket == "" {
+		return S3Config{}, configuration.ErrS3BucketNotProvided
+	}
+	if conf.AccessKey == "" || conf.SecretKey == "" {
+		return S3Config{}, configuration.ErrS3CredentialsNotProvided
+	}
+
+	return conf, nil
+}
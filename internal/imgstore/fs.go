@@ -0,0 +1,84 @@
+package imgstore
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/google/uuid"
+)
+
+// An FSStorage keeps images as files in a local directory.
+// Each file is named after its image id.
+type FSStorage struct {
+	dir string
+}
+
+// NewFSStorage returns a storage rooted at dir, creating the directory if necessary.
+func NewFSStorage(dir string) (*FSStorage, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	return &FSStorage{dir: dir}, nil
+}
+
+func (s *FSStorage) path(id uuid.UUID) string {
+	return filepath.Join(s.dir, id.String())
+}
+
+// Put writes the data into a temporary file first so that readers never observe a partially written image.
+func (s *FSStorage) Put(ctx context.Context, id uuid.UUID, r io.Reader, size int64) error {
+	file, err := os.CreateTemp(s.dir, ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+
+	if _, err = io.CopyN(file, r, size); err != nil {
+		_ = file.Close()
+		return err
+	}
+	if err = file.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(file.Name(), s.path(id))
+}
+
+func (s *FSStorage) Get(ctx context.Context, id uuid.UUID) (io.ReadCloser, error) {
+	file, err := os.Open(s.path(id))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return file, err
+}
+
+func (s *FSStorage) Delete(ctx context.Context, id uuid.UUID) error {
+	err := os.Remove(s.path(id))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// Check tries to create, write and remove a temporary file in the image directory.
+func (s *FSStorage) Check(ctx context.Context) error {
+	file, err := os.CreateTemp(s.dir, ".check-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+
+	if _, err = file.WriteString("hello world"); err != nil {
+		_ = file.Close()
+		return err
+	}
+	if err = file.Close(); err != nil {
+		return err
+	}
+
+	return os.Remove(file.Name())
+}
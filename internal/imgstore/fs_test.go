@@ -0,0 +1,79 @@
+package imgstore
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func Test_FSStorage_PutGetDelete(t *testing.T) {
+	s, err := NewFSStorage(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+
+	testStorageRoundTrip(t, s)
+}
+
+func Test_FSStorage_Check_LeavesNoFiles(t *testing.T) {
+	dir := t.TempDir()
+	s, err := NewFSStorage(dir)
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+
+	if err = s.Check(context.Background()); err != nil {
+		t.Fatalf("check failed: %v", err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("failed to read dir: %v", err)
+	}
+	if len(entries) != 0 {
+		t.Fatalf("check left %d files behind", len(entries))
+	}
+}
+
+// testStorageRoundTrip stores, reads back and deletes an image.
+func testStorageRoundTrip(t *testing.T, s Storage) {
+	ctx := context.Background()
+	id := uuid.New()
+	data := []byte("not really a jpeg")
+
+	if _, err := s.Get(ctx, id); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound for a missing image, got %v", err)
+	}
+
+	if err := s.Put(ctx, id, bytes.NewReader(data), int64(len(data))); err != nil {
+		t.Fatalf("put failed: %v", err)
+	}
+
+	r, err := s.Get(ctx, id)
+	if err != nil {
+		t.Fatalf("get failed: %v", err)
+	}
+	got, err := io.ReadAll(r)
+	_ = r.Close()
+	if err != nil {
+		t.Fatalf("read failed: %v", err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Fatalf("got %q, expected %q", got, data)
+	}
+
+	if err = s.Delete(ctx, id); err != nil {
+		t.Fatalf("delete failed: %v", err)
+	}
+	if _, err = s.Get(ctx, id); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound after delete, got %v", err)
+	}
+	if err = s.Delete(ctx, id); err != nil {
+		t.Fatalf("deleting a missing image failed: %v", err)
+	}
+}
@@ -0,0 +1,133 @@
+package imgstore
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// S3Config describes how to reach an S3-compatible object storage.
+type S3Config struct {
+	Endpoint  string
+	Region    string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+	UseSSL    bool
+
+	// Prefix is prepended to every object key.
+	Prefix string
+
+	// PresignExpiry is the lifetime of presigned download URLs.
+	// Presigning is disabled if it is zero.
+	PresignExpiry time.Duration
+}
+
+// An S3Storage keeps images as objects in an S3-compatible bucket.
+type S3Storage struct {
+	client *minio.Client
+	bucket string
+	prefix string
+}
+
+// NewS3Storage connects to the object storage described by conf.
+//
+// If conf.PresignExpiry is non-zero, the returned Storage also implements Presigner.
+func NewS3Storage(conf S3Config) (Storage, error) {
+	client, err := minio.New(conf.Endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(conf.AccessKey, conf.SecretKey, ""),
+		Secure: conf.UseSSL,
+		Region: conf.Region,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s := &S3Storage{
+		client: client,
+		bucket: conf.Bucket,
+		prefix: conf.Prefix,
+	}
+
+	if conf.PresignExpiry > 0 {
+		return &presigningS3Storage{S3Storage: s, expiry: conf.PresignExpiry}, nil
+	}
+	return s, nil
+}
+
+func (s *S3Storage) key(id uuid.UUID) string {
+	return s.prefix + id.String()
+}
+
+func (s *S3Storage) Put(ctx context.Context, id uuid.UUID, r io.Reader, size int64) error {
+	_, err := s.client.PutObject(ctx, s.bucket, s.key(id), r, size, minio.PutObjectOptions{
+		ContentType: ContentType,
+	})
+	return err
+}
+
+func (s *S3Storage) Get(ctx context.Context, id uuid.UUID) (io.ReadCloser, error) {
+	obj, err := s.client.GetObject(ctx, s.bucket, s.key(id), minio.GetObjectOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	// GetObject is lazy: the request is only made on the first read or stat
+	if _, err = obj.Stat(); err != nil {
+		_ = obj.Close()
+		if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return obj, nil
+}
+
+func (s *S3Storage) Delete(ctx context.Context, id uuid.UUID) error {
+	return s.client.RemoveObject(ctx, s.bucket, s.key(id), minio.RemoveObjectOptions{})
+}
+
+// Check makes sure the bucket exists and an object can be written to and removed from it.
+func (s *S3Storage) Check(ctx context.Context) error {
+	exists, err := s.client.BucketExists(ctx, s.bucket)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("bucket %q does not exist", s.bucket)
+	}
+
+	key := s.prefix + ".check-" + uuid.NewString()
+	data := []byte("hello world")
+	_, err = s.client.PutObject(ctx, s.bucket, key, bytes.NewReader(data), int64(len(data)), minio.PutObjectOptions{})
+	if err != nil {
+		return err
+	}
+
+	return s.client.RemoveObject(ctx, s.bucket, key, minio.RemoveObjectOptions{})
+}
+
+type presigningS3Storage struct {
+	*S3Storage
+
+	expiry time.Duration
+}
+
+func (s *presigningS3Storage) PresignGet(ctx context.Context, id uuid.UUID) (*url.URL, error) {
+	return s.client.PresignedGetObject(ctx, s.bucket, s.key(id), s.expiry, nil)
+}
+
+// assert the storages implement the interfaces
+var (
+	_ Storage   = &FSStorage{}
+	_ Storage   = &S3Storage{}
+	_ Presigner = &presigningS3Storage{}
+)
@@ -0,0 +1,102 @@
+package imgstore
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/minio/minio-go/v7"
+)
+
+// s3TestConfig returns the config of an S3-compatible storage to run the tests against.
+// The tests are skipped unless PB_TEST_S3_ENDPOINT is set,
+// e.g. to a MinIO instance started with `docker compose --profile s3 up minio`.
+func s3TestConfig(t *testing.T) S3Config {
+	endpoint := os.Getenv("PB_TEST_S3_ENDPOINT")
+	if endpoint == "" {
+		t.Skip("PB_TEST_S3_ENDPOINT is not set")
+	}
+
+	conf := S3Config{
+		Endpoint:  endpoint,
+		Bucket:    os.Getenv("PB_TEST_S3_BUCKET"),
+		AccessKey: os.Getenv("PB_TEST_S3_ACCESS_KEY"),
+		SecretKey: os.Getenv("PB_TEST_S3_SECRET_KEY"),
+		Prefix:    "test-" + uuid.NewString() + "/",
+	}
+	if conf.Bucket == "" {
+		conf.Bucket = "pb-images"
+	}
+	if conf.AccessKey == "" {
+		conf.AccessKey = "minioadmin"
+	}
+	if conf.SecretKey == "" {
+		conf.SecretKey = "minioadmin"
+	}
+
+	return conf
+}
+
+func newTestS3Storage(t *testing.T, conf S3Config) Storage {
+	s, err := NewS3Storage(conf)
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+
+	client := storageS3Client(s)
+	ctx := context.Background()
+	exists, err := client.BucketExists(ctx, conf.Bucket)
+	if err != nil {
+		t.Fatalf("failed to reach the storage: %v", err)
+	}
+	if !exists {
+		if err = client.MakeBucket(ctx, conf.Bucket, minio.MakeBucketOptions{}); err != nil {
+			t.Fatalf("failed to create bucket: %v", err)
+		}
+	}
+
+	return s
+}
+
+func storageS3Client(s Storage) *minio.Client {
+	switch s := s.(type) {
+	case *S3Storage:
+		return s.client
+	case *presigningS3Storage:
+		return s.client
+	}
+	panic("not an S3 storage")
+}
+
+func Test_S3Storage_PutGetDelete(t *testing.T) {
+	s := newTestS3Storage(t, s3TestConfig(t))
+	testStorageRoundTrip(t, s)
+}
+
+func Test_S3Storage_Check(t *testing.T) {
+	s := newTestS3Storage(t, s3TestConfig(t))
+	if err := s.Check(context.Background()); err != nil {
+		t.Fatalf("check failed: %v", err)
+	}
+}
+
+func Test_S3Storage_Presign(t *testing.T) {
+	conf := s3TestConfig(t)
+	conf.PresignExpiry = time.Minute
+
+	s := newTestS3Storage(t, conf)
+	presigner, ok := s.(Presigner)
+	if !ok {
+		t.Fatalf("expected the storage to implement Presigner")
+	}
+
+	u, err := presigner.PresignGet(context.Background(), uuid.New())
+	if err != nil {
+		t.Fatalf("presign failed: %v", err)
+	}
+	if u.Query().Get("X-Amz-Signature") == "" {
+		t.Fatalf("presigned url has no signature: %s", u)
+	}
+}
@@ -0,0 +1,46 @@
+package imgstore
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/url"
+
+	"github.com/google/uuid"
+)
+
+// ContentType is the MIME type of every image kept in a Storage.
+// Uploads are re-encoded to JPEG before they are stored.
+const ContentType = "image/jpeg"
+
+var (
+	ErrNotFound = errors.New("image not found in storage")
+)
+
+// A Storage keeps the image files referenced by the images table.
+//
+// Implementations must be safe for concurrent use.
+type Storage interface {
+	// Put stores the image data under the given id, replacing the previous contents if any.
+	// size is the exact length of the data.
+	Put(ctx context.Context, id uuid.UUID, r io.Reader, size int64) error
+
+	// Get opens the image data for reading.
+	// Returns ErrNotFound if no image with such id is stored.
+	Get(ctx context.Context, id uuid.UUID) (io.ReadCloser, error)
+
+	// Delete removes the image data.
+	// Deleting a missing image is not an error.
+	Delete(ctx context.Context, id uuid.UUID) error
+
+	// Check makes sure the storage is reachable and writable.
+	// It must not leave anything behind.
+	Check(ctx context.Context) error
+}
+
+// A Presigner is implemented by storages that can serve images directly to clients,
+// bypassing the backend.
+type Presigner interface {
+	// PresignGet returns a temporary URL the image can be downloaded from.
+	PresignGet(ctx context.Context, id uuid.UUID) (*url.URL, error)
+}
@@ -0,0 +1,89 @@
+// Package logging configures the structured logger used by the server.
+//
+// Attributes describing the current request (such as the request id) travel in the context:
+// use NewContext to add them and the *Context variants of the slog functions to log with them.
+package logging
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"party-buddy/internal/configuration"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// NewFromConfig creates a logger writing to stderr as configured by log.format and log.level.
+// The defaults are the text format and the info level.
+func NewFromConfig() (*slog.Logger, error) {
+	return New(os.Stderr, viper.GetString("log.format"), viper.GetString("log.level"))
+}
+
+// New creates a logger writing to w in the given format ("text" or "json") at the given level.
+func New(w io.Writer, format string, level string) (*slog.Logger, error) {
+	var lvl slog.Level
+	if level != "" {
+		if err := lvl.UnmarshalText([]byte(level)); err != nil {
+			return nil, configuration.ErrLogLevelUnknown
+		}
+	}
+
+	opts := &slog.HandlerOptions{Level: lvl}
+
+	var handler slog.Handler
+	switch strings.ToLower(format) {
+	case "", FormatText:
+		handler = slog.NewTextHandler(w, opts)
+	case FormatJSON:
+		handler = slog.NewJSONHandler(w, opts)
+	default:
+		return nil, configuration.ErrLogFormatUnknown
+	}
+
+	return slog.New(contextHandler{inner: handler}), nil
+}
+
+type attrsKeyType int
+
+var attrsKey attrsKeyType
+
+// NewContext returns a copy of ctx carrying the attributes in addition to those already there.
+// The attributes are added to every record logged with the context.
+func NewContext(ctx context.Context, attrs ...slog.Attr) context.Context {
+	parent, _ := ctx.Value(attrsKey).([]slog.Attr)
+	merged := make([]slog.Attr, 0, len(parent)+len(attrs))
+	merged = append(merged, parent...)
+	merged = append(merged, attrs...)
+	return context.WithValue(ctx, attrsKey, merged)
+}
+
+// contextHandler adds the attributes stored by NewContext to the records.
+type contextHandler struct {
+	inner slog.Handler
+}
+
+func (h contextHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.inner.Enabled(ctx, level)
+}
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if attrs, ok := ctx.Value(attrsKey).([]slog.Attr); ok {
+		r.AddAttrs(attrs...)
+	}
+	return h.inner.Handle(ctx, r)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{inner: h.inner.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{inner: h.inner.WithGroup(name)}
+}
@@ -0,0 +1,57 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"party-buddy/internal/configuration"
+	"testing"
+)
+
+func TestNewRejectsUnknownSettings(t *testing.T) {
+	if _, err := New(&bytes.Buffer{}, "xml", ""); !errors.Is(err, configuration.ErrLogFormatUnknown) {
+		t.Errorf("unknown format: got %v", err)
+	}
+	if _, err := New(&bytes.Buffer{}, "", "loud"); !errors.Is(err, configuration.ErrLogLevelUnknown) {
+		t.Errorf("unknown level: got %v", err)
+	}
+}
+
+func TestContextAttrs(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := New(&buf, FormatJSON, "debug")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := NewContext(context.Background(), slog.String("request_id", "abc"))
+	ctx = NewContext(ctx, slog.String("method", "GET"))
+	logger.With("component", "test").DebugContext(ctx, "hello")
+
+	var record map[string]any
+	if err = json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatal(err)
+	}
+	for key, want := range map[string]string{
+		"msg": "hello", "level": "DEBUG", "request_id": "abc", "method": "GET", "component": "test",
+	} {
+		if record[key] != want {
+			t.Errorf("%s: got %v, want %q", key, record[key], want)
+		}
+	}
+}
+
+func TestLevel(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := New(&buf, FormatText, "warn")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logger.Info("quiet")
+	if buf.Len() != 0 {
+		t.Errorf("info record logged at the warn level: %s", buf.String())
+	}
+}
@@ -3,75 +3,118 @@ package internal
 import (
 	"context"
 	"fmt"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/spf13/viper"
-	"log"
+	"log/slog"
 	"net/http"
 	"os"
+	"os/signal"
 	"party-buddy/internal/api/handlers"
+	"party-buddy/internal/auth"
 	"party-buddy/internal/configuration"
 	"party-buddy/internal/db"
+	"party-buddy/internal/health"
+	"party-buddy/internal/imgstore"
+	"party-buddy/internal/logging"
+	"party-buddy/internal/ratelimit"
 	"party-buddy/internal/session"
+	"party-buddy/internal/shutdown"
+	"party-buddy/internal/ws"
+	"syscall"
+	"time"
 )
 
-// isImagePathAccessible tries to create a file by provided image path
-func isImagePathAccessible() error {
-	imgDir := configuration.GetImgDirectory()
-	if _, err := os.Stat(imgDir); os.IsNotExist(err) {
-		if err := os.MkdirAll(imgDir, 0700); err != nil {
-			return err
-		}
-	}
+const (
+	// DefaultDrainPeriod is how long the server reports draining before it stops accepting connections
+	DefaultDrainPeriod = 5 * time.Second
+
+	// ShutdownTimeout bounds the time spent waiting for in-flight requests during shutdown
+	ShutdownTimeout = 30 * time.Second
+)
 
-	testFilePath := imgDir + string(os.PathSeparator) + "test.png"
+func Main() {
+	configuration.ConfigureApp()
 
-	file, err := os.OpenFile(testFilePath, os.O_RDWR|os.O_CREATE, 0700)
+	logger, err := logging.NewFromConfig()
 	if err != nil {
-		return err
+		slog.Error("Failed to init logging", "err", err)
+		os.Exit(1)
 	}
+	slog.SetDefault(logger)
 
-	_, err = file.WriteString("hello world")
-	if err != nil {
-		return err
+	ctx := context.Background()
+
+	if len(os.Args) > 1 {
+		runCommand(ctx, os.Args[1:])
+		return
 	}
 
-	err = file.Close()
+	slog.Info("init auth...")
+	issuer, err := auth.NewIssuerFromConfig()
 	if err != nil {
-		return err
+		slog.Error("Failed to init auth", "err", err)
+		os.Exit(1)
 	}
 
-	err = os.Remove(testFilePath)
-	return err
-}
+	slog.Info("init image storage...")
+	storage, err := imgstore.NewFromConfig()
+	if err != nil {
+		slog.Error("Failed to init image storage", "err", err)
+		os.Exit(1)
+	}
 
-func Main() {
-	configuration.ConfigureApp()
+	slog.Info("testing image storage accessibility...")
+	if err = storage.Check(ctx); err != nil {
+		slog.Error("Failed to test image storage accessibility", "err", err)
+		os.Exit(1)
+	}
 
-	log.Printf("testing image path accessibility...")
-	err := isImagePathAccessible()
+	slog.Info("init db config...")
+	dbPoolConf, err := db.GetDBConfig()
 	if err != nil {
-		log.Fatalf("Failed to test image path accessibility: %v", err.Error())
+		slog.Error("Failed to init db config", "err", err)
+		os.Exit(1)
 	}
 
-	log.Printf("init db config...")
-	dbPoolConf, err := db.GetDBConfig()
+	slog.Info("init db pool...")
+	dbpool, err := db.InitDBPool(ctx, dbPoolConf)
 	if err != nil {
-		log.Fatalf("Failed to init db config: %v", err.Error())
+		slog.Error("Failed to init db pool", "err", err)
+		os.Exit(1)
 	}
 
-	ctx := context.Background()
+	manager := session.NewManager(&dbpool, slog.Default().With("component", "manager"), idlePolicyFromConfig())
 
-	log.Printf("init db pool...")
-	dbpool, err := db.InitDBPool(ctx, dbPoolConf)
+	prometheus.MustRegister(manager.Collector(), dbpool.Collector())
+
+	checker := health.NewChecker()
+	checker.Add("db", func(ctx context.Context) error {
+		return dbpool.Pool().Ping(ctx)
+	})
+	checker.Add("img-storage", storage.Check)
+	checker.Add("session-manager", manager.Check)
+
+	limiter, err := ratelimit.NewFromConfig()
 	if err != nil {
-		log.Fatalf("Failed to init db pool: %v", err.Error())
+		slog.Error("Failed to init rate limits", "err", err)
+		os.Exit(1)
 	}
 
-	manager := session.NewManager(&dbpool, log.New(log.Writer(), "manager: ", log.Flags()))
+	sse := ws.NewSSERegistry()
+
+	handler := handlers.ConfigureMux(&dbpool, manager, storage, issuer, checker, limiter, sse)
 
-	handler := handlers.ConfigureMux(&dbpool, manager)
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	// TODO: run manager properly
-	go manager.Run(ctx)
+	managerCtx, stopManager := context.WithCancel(context.Background())
+	managerDone := make(chan struct{})
+	go func() {
+		defer close(managerDone)
+		if err := manager.Run(managerCtx); err != nil {
+			slog.Error("session manager stopped", "err", err)
+		}
+	}()
 
 	host := viper.GetString("server.host")
 	if host == "" {
@@ -82,7 +125,73 @@ func Main() {
 		viper.SetDefault("server.port", "8081")
 	}
 
-	log.Printf("Listening on port %s", port)
-	log.Printf("Open http://%s:%s in the browser", host, port)
-	log.Fatal(http.ListenAndServe(fmt.Sprintf("%s:%s", host, port), handler))
+	server := &http.Server{
+		Addr:    fmt.Sprintf("%s:%s", host, port),
+		Handler: handler,
+	}
+	// the SSE streams would otherwise keep the shutdown waiting
+	server.RegisterOnShutdown(sse.CloseAll)
+
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- server.ListenAndServe()
+	}()
+
+	slog.Info("listening", "host", host, "port", port)
+	slog.Info(fmt.Sprintf("Open http://%s:%s in the browser", host, port))
+
+	select {
+	case err = <-serverErr:
+		slog.Error("server stopped", "err", err)
+		os.Exit(1)
+
+	case <-ctx.Done():
+	}
+
+	gracefulShutdown(checker, server, stopManager, managerDone, &dbpool)
+}
+
+// idlePolicyFromConfig reads the idle player policy from session.idle.warn-after and session.idle.remove-after
+// (in tasks; 0 disables the action), falling back to session.DefaultIdlePolicy.
+func idlePolicyFromConfig() session.IdlePolicy {
+	viper.SetDefault("session.idle.warn-after", session.DefaultIdlePolicy.WarnAfter)
+	viper.SetDefault("session.idle.remove-after", session.DefaultIdlePolicy.RemoveAfter)
+
+	return session.IdlePolicy{
+		WarnAfter:   viper.GetInt("session.idle.warn-after"),
+		RemoveAfter: viper.GetInt("session.idle.remove-after"),
+	}
+}
+
+// gracefulShutdown stops the server.
+//
+// First the server reports draining on /readyz for server.drain-period, so the orchestrator stops routing traffic to it.
+// Then it stops accepting connections, waits for in-flight requests, stops the session manager
+// and disposes of the db pool.
+func gracefulShutdown(
+	checker *health.Checker,
+	server *http.Server,
+	stopManager context.CancelFunc,
+	managerDone <-chan struct{},
+	dbpool shutdown.Disposable,
+) {
+	drainPeriod := viper.GetDuration("server.drain-period")
+	if drainPeriod == 0 {
+		drainPeriod = DefaultDrainPeriod
+	}
+
+	slog.Info("shutting down: draining", "drain_period", drainPeriod)
+	checker.SetDraining()
+	time.Sleep(drainPeriod)
+
+	ctx, cancel := context.WithTimeout(context.Background(), ShutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		slog.Error("could not shut down the server gracefully", "err", err)
+	}
+
+	stopManager()
+	<-managerDone
+	dbpool.Dispose()
+	slog.Info("shutdown complete")
 }
@@ -0,0 +1,90 @@
+// Package metrics defines the Prometheus metrics exported by the server.
+//
+// The metrics are registered in the default registry and served by Handler.
+// Gauges describing live state (sessions, players, the db pool) are collected on scrape
+// by the collectors provided by session.Manager and db.DBPool.
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "partybuddy"
+
+var (
+	// WSMessagesReceived counts WebSocket messages received from clients by message kind.
+	WSMessagesReceived = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "ws",
+		Name:      "messages_received_total",
+		Help:      "WebSocket messages received from clients.",
+	}, []string{"kind"})
+
+	// WSMessagesSent counts WebSocket messages sent to clients by message kind.
+	WSMessagesSent = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "ws",
+		Name:      "messages_sent_total",
+		Help:      "WebSocket messages sent to clients.",
+	}, []string{"kind"})
+
+	// WSErrors counts error messages sent to clients by error kind.
+	WSErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "ws",
+		Name:      "errors_total",
+		Help:      "Protocol errors sent to clients.",
+	}, []string{"code"})
+
+	// UpdaterHandlingSeconds observes how long a session updater takes to handle a message.
+	UpdaterHandlingSeconds = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "session",
+		Name:      "updater_handling_seconds",
+		Help:      "Time taken by session updaters to handle a message.",
+		Buckets:   []float64{.00005, .0001, .00025, .0005, .001, .0025, .005, .01, .025, .05, .1},
+	}, []string{"msg"})
+
+	// StorageLockHeldSeconds observes how long the session storage mutex is held.
+	StorageLockHeldSeconds = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "session",
+		Name:      "storage_lock_held_seconds",
+		Help:      "Time the session storage mutex is held for.",
+		Buckets:   []float64{.00001, .000025, .00005, .0001, .00025, .0005, .001, .0025, .005, .01, .025, .05, .1},
+	})
+
+	// ImgUploadedBytes counts image bytes stored by upload requests.
+	ImgUploadedBytes = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "img",
+		Name:      "uploaded_bytes_total",
+		Help:      "Image bytes stored by uploads.",
+	})
+
+	// ImgDownloadedBytes counts image bytes served by the server (presigned redirects are not included).
+	ImgDownloadedBytes = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "img",
+		Name:      "downloaded_bytes_total",
+		Help:      "Image bytes served to clients.",
+	})
+
+	// HTTPRequestSeconds observes HTTP request durations by route template, method and status code.
+	HTTPRequestSeconds = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "HTTP request durations.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "method", "status"})
+)
+
+// Handler serves the metrics in the Prometheus exposition format.
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
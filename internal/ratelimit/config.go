@@ -0,0 +1,211 @@
+package ratelimit
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+	"golang.org/x/time/rate"
+)
+
+// An Action is a rate-limited kind of request.
+type Action string
+
+const (
+	SessionCreate Action = "session-create"
+	SessionJoin   Action = "session-join"
+	ImgUpload     Action = "img-upload"
+	GameImport    Action = "game-import"
+	GameClone     Action = "game-clone"
+	WSMessage     Action = "ws-message"
+
+	// WSChat limits the chat messages and the reactions on top of WSMessage.
+	WSChat Action = "ws-chat"
+
+	// DeviceRegister is only limited per IP since the client is not authenticated yet.
+	DeviceRegister Action = "device-register"
+)
+
+// Actions lists all rate-limited actions.
+var Actions = []Action{SessionCreate, SessionJoin, ImgUpload, GameImport, GameClone, WSMessage, WSChat, DeviceRegister}
+
+// defaultLimits are used unless overridden by the config.
+// The per-IP limits are more lenient since several clients may share an address (e.g. behind a NAT).
+var defaultLimits = map[Action]struct{ perClient, perIP Limit }{
+	SessionCreate: {
+		perClient: Limit{Rate: rate.Every(10 * time.Second), Burst: 3},
+		perIP:     Limit{Rate: rate.Every(2 * time.Second), Burst: 10},
+	},
+	SessionJoin: {
+		perClient: Limit{Rate: rate.Every(2 * time.Second), Burst: 5},
+		perIP:     Limit{Rate: 2, Burst: 30},
+	},
+	ImgUpload: {
+		perClient: Limit{Rate: 2, Burst: 20},
+		perIP:     Limit{Rate: 10, Burst: 100},
+	},
+	GameImport: {
+		perClient: Limit{Rate: rate.Every(10 * time.Second), Burst: 3},
+		perIP:     Limit{Rate: rate.Every(2 * time.Second), Burst: 10},
+	},
+	GameClone: {
+		perClient: Limit{Rate: rate.Every(10 * time.Second), Burst: 3},
+		perIP:     Limit{Rate: rate.Every(2 * time.Second), Burst: 10},
+	},
+	WSMessage: {
+		perClient: Limit{Rate: 10, Burst: 30},
+		perIP:     Limit{Rate: 50, Burst: 200},
+	},
+	WSChat: {
+		perClient: Limit{Rate: 1, Burst: 5},
+		perIP:     Limit{Rate: 5, Burst: 30},
+	},
+	DeviceRegister: {
+		perIP: Limit{Rate: rate.Every(10 * time.Second), Burst: 10},
+	},
+}
+
+var ErrTrustedProxyInvalid = errors.New("trusted-proxy-invalid")
+
+// A Limiter holds a Policy for every Action.
+type Limiter struct {
+	policies map[Action]*Policy
+
+	// trustForwardedFor makes ClientIP take the address from the X-Forwarded-For header
+	trustForwardedFor bool
+
+	// trustedProxies are the addresses of the proxies in front of the server besides the one it's connected to
+	trustedProxies []netip.Prefix
+}
+
+func New(policies map[Action]*Policy, trustForwardedFor bool, trustedProxies []netip.Prefix) *Limiter {
+	return &Limiter{policies: policies, trustForwardedFor: trustForwardedFor, trustedProxies: trustedProxies}
+}
+
+// ClientIP returns the address the request came from.
+//
+// If the limiter trusts X-Forwarded-For (which is only safe behind a proxy that sets it),
+// the rightmost address in the header that isn't one of the trusted proxies is used.
+// The proxies append to the header, so the addresses to the left of it may be forged by the client.
+func (l *Limiter) ClientIP(r *http.Request) string {
+	if l.trustForwardedFor {
+		if ip, ok := l.forwardedFor(r.Header.Values("X-Forwarded-For")); ok {
+			return ip
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// forwardedFor returns the rightmost address in the X-Forwarded-For headers that isn't a trusted proxy.
+func (l *Limiter) forwardedFor(headers []string) (string, bool) {
+	var addrs []string
+	for _, header := range headers {
+		addrs = append(addrs, strings.Split(header, ",")...)
+	}
+
+	for i := len(addrs) - 1; i >= 0; i-- {
+		addr := strings.TrimSpace(addrs[i])
+		if addr == "" {
+			continue
+		}
+		if !l.trustedProxy(addr) || i == 0 {
+			return addr, true
+		}
+	}
+	return "", false
+}
+
+func (l *Limiter) trustedProxy(addr string) bool {
+	ip, err := netip.ParseAddr(addr)
+	if err != nil {
+		return false
+	}
+	for _, prefix := range l.trustedProxies {
+		if prefix.Contains(ip.Unmap()) {
+			return true
+		}
+	}
+	return false
+}
+
+// Policy returns the policy for the action.
+// If the action has no policy, it's not limited.
+func (l *Limiter) Policy(action Action) *Policy {
+	if p, ok := l.policies[action]; ok {
+		return p
+	}
+	return NewPolicy(Limit{}, Limit{})
+}
+
+// ConfigKey returns the config key for the rate (events per second) or the burst of an action's limit.
+// The scope is either "client" or "ip", the param is either "rate" or "burst".
+func ConfigKey(action Action, scope string, param string) string {
+	return fmt.Sprintf("ratelimit.%s.%s.%s", action, scope, param)
+}
+
+// NewFromConfig creates a Limiter with the limits set in the config.
+//
+// The limits are set by ratelimit.<action>.<client|ip>.<rate|burst>.
+// A zero rate disables the limit.
+// If ratelimit.trust-forwarded-for is set, client addresses are taken from the X-Forwarded-For header,
+// skipping the proxies listed in ratelimit.trusted-proxies (addresses or CIDR prefixes).
+func NewFromConfig() (*Limiter, error) {
+	policies := make(map[Action]*Policy)
+	for _, action := range Actions {
+		defaults := defaultLimits[action]
+		policies[action] = NewPolicy(
+			limitFromConfig(action, "client", defaults.perClient),
+			limitFromConfig(action, "ip", defaults.perIP),
+		)
+	}
+
+	var trustedProxies []netip.Prefix
+	for _, proxy := range viper.GetStringSlice("ratelimit.trusted-proxies") {
+		prefix, err := parseProxy(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q", ErrTrustedProxyInvalid, proxy)
+		}
+		trustedProxies = append(trustedProxies, prefix)
+	}
+
+	return New(policies, viper.GetBool("ratelimit.trust-forwarded-for"), trustedProxies), nil
+}
+
+// parseProxy parses either an address or a CIDR prefix.
+func parseProxy(proxy string) (netip.Prefix, error) {
+	if strings.Contains(proxy, "/") {
+		prefix, err := netip.ParsePrefix(proxy)
+		return prefix.Masked(), err
+	}
+	addr, err := netip.ParseAddr(proxy)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	return netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()), nil
+}
+
+func limitFromConfig(action Action, scope string, def Limit) Limit {
+	rateKey, burstKey := ConfigKey(action, scope, "rate"), ConfigKey(action, scope, "burst")
+	viper.SetDefault(rateKey, float64(def.Rate))
+	viper.SetDefault(burstKey, def.Burst)
+
+	limit := Limit{
+		Rate:  rate.Limit(viper.GetFloat64(rateKey)),
+		Burst: viper.GetInt(burstKey),
+	}
+	if limit.Burst < 1 {
+		limit.Burst = 1
+	}
+
+	return limit
+}
@@ -0,0 +1,122 @@
+// Package ratelimit implements token-bucket rate limits keyed by client and by IP address.
+package ratelimit
+
+import (
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// idleTTL is how long a bucket may go unused before it is forgotten.
+// A forgotten bucket is recreated full, so it must be long enough for any bucket to refill.
+const idleTTL = 10 * time.Minute
+
+// A Limit describes a token bucket: Rate tokens are added per second, up to Burst.
+// A zero Rate disables the limit.
+type Limit struct {
+	Rate  rate.Limit
+	Burst int
+}
+
+func (l Limit) enabled() bool {
+	return l.Rate > 0
+}
+
+type bucket struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// buckets holds a token bucket per key.
+type buckets struct {
+	limit Limit
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+func newBuckets(limit Limit) *buckets {
+	return &buckets{
+		limit:     limit,
+		buckets:   make(map[string]*bucket),
+		lastSweep: time.Now(),
+	}
+}
+
+// reserve takes a token from the key's bucket.
+// If there is none, returns false and the time until one becomes available.
+// Otherwise, returns a function that puts the token back.
+func (b *buckets) reserve(key string, now time.Time) (ok bool, retryAfter time.Duration, cancel func()) {
+	if !b.limit.enabled() {
+		return true, 0, func() {}
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if now.Sub(b.lastSweep) > idleTTL {
+		for k, v := range b.buckets {
+			if now.Sub(v.lastSeen) > idleTTL {
+				delete(b.buckets, k)
+			}
+		}
+		b.lastSweep = now
+	}
+
+	entry := b.buckets[key]
+	if entry == nil {
+		entry = &bucket{limiter: rate.NewLimiter(b.limit.Rate, b.limit.Burst)}
+		b.buckets[key] = entry
+	}
+	entry.lastSeen = now
+
+	r := entry.limiter.ReserveN(now, 1)
+	if !r.OK() {
+		return false, idleTTL, nil
+	}
+	if delay := r.DelayFrom(now); delay > 0 {
+		r.CancelAt(now)
+		return false, delay, nil
+	}
+
+	return true, 0, func() { r.CancelAt(now) }
+}
+
+// A Policy limits an action both per client and per IP address.
+type Policy struct {
+	perClient *buckets
+	perIP     *buckets
+}
+
+func NewPolicy(perClient Limit, perIP Limit) *Policy {
+	return &Policy{
+		perClient: newBuckets(perClient),
+		perIP:     newBuckets(perIP),
+	}
+}
+
+// Allow reports whether the client may perform the action from the ip.
+// If not, retryAfter tells when it's worth trying again.
+//
+// An empty clientID or ip skips the corresponding limit.
+// A rejected request doesn't count towards either limit.
+func (p *Policy) Allow(clientID string, ip string) (ok bool, retryAfter time.Duration) {
+	now := time.Now()
+
+	cancelIP := func() {}
+	if ip != "" {
+		if ok, retryAfter, cancelIP = p.perIP.reserve(ip, now); !ok {
+			return
+		}
+	}
+	if clientID != "" {
+		if ok, retryAfter, _ = p.perClient.reserve(clientID, now); !ok {
+			cancelIP()
+			return
+		}
+	}
+
+	return true, 0
+}
@@ -0,0 +1,108 @@
+package ratelimit
+
+import (
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+)
+
+func TestPolicyBurst(t *testing.T) {
+	p := NewPolicy(Limit{Rate: 1, Burst: 2}, Limit{})
+
+	for i := 0; i < 2; i++ {
+		if ok, _ := p.Allow("client", "127.0.0.1"); !ok {
+			t.Fatalf("request %d was rejected within the burst", i)
+		}
+	}
+
+	ok, retryAfter := p.Allow("client", "127.0.0.1")
+	if ok {
+		t.Fatal("request exceeding the burst was allowed")
+	}
+	if retryAfter <= 0 || retryAfter > time.Second {
+		t.Errorf("unexpected retry-after: %v", retryAfter)
+	}
+
+	if ok, _ = p.Allow("another-client", "127.0.0.1"); !ok {
+		t.Error("the limit is shared among clients")
+	}
+}
+
+func TestPolicyPerIP(t *testing.T) {
+	p := NewPolicy(Limit{}, Limit{Rate: 1, Burst: 1})
+
+	if ok, _ := p.Allow("a", "10.0.0.1"); !ok {
+		t.Fatal("the first request was rejected")
+	}
+	if ok, _ := p.Allow("b", "10.0.0.1"); ok {
+		t.Error("the ip limit is not shared among clients")
+	}
+	if ok, _ := p.Allow("a", "10.0.0.2"); !ok {
+		t.Error("the limit is shared among addresses")
+	}
+}
+
+func TestPolicyRejectionDoesNotConsume(t *testing.T) {
+	p := NewPolicy(Limit{Rate: 1, Burst: 1}, Limit{Rate: 1, Burst: 2})
+
+	if ok, _ := p.Allow("a", "10.0.0.1"); !ok {
+		t.Fatal("the first request was rejected")
+	}
+	if ok, _ := p.Allow("a", "10.0.0.1"); ok {
+		t.Fatal("the client limit was not applied")
+	}
+	if ok, _ := p.Allow("b", "10.0.0.1"); !ok {
+		t.Error("a request rejected by the client limit consumed an ip token")
+	}
+}
+
+func TestPolicyDisabled(t *testing.T) {
+	p := NewPolicy(Limit{}, Limit{})
+
+	for i := 0; i < 100; i++ {
+		if ok, _ := p.Allow("client", "127.0.0.1"); !ok {
+			t.Fatal("a disabled policy rejected a request")
+		}
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	r := httptest.NewRequest("GET", "/", nil)
+	r.RemoteAddr = "192.0.2.1:1234"
+	r.Header.Set("X-Forwarded-For", "203.0.113.7, 198.51.100.1")
+
+	if ip := New(nil, false, nil).ClientIP(r); ip != "192.0.2.1" {
+		t.Errorf("untrusted: got %q", ip)
+	}
+	if ip := New(nil, true, nil).ClientIP(r); ip != "198.51.100.1" {
+		t.Errorf("trusted: got %q", ip)
+	}
+
+	proxies := []netip.Prefix{netip.MustParsePrefix("198.51.100.0/24")}
+	if ip := New(nil, true, proxies).ClientIP(r); ip != "203.0.113.7" {
+		t.Errorf("behind a trusted proxy: got %q", ip)
+	}
+}
+
+func TestClientIPSpoofed(t *testing.T) {
+	l := New(nil, true, []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")})
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("X-Forwarded-For", "203.0.113.7, 10.0.0.2")
+	want := l.ClientIP(r)
+
+	for _, spoofed := range []string{"1.2.3.4", "10.0.0.3", "garbage"} {
+		r.Header.Set("X-Forwarded-For", spoofed+", 203.0.113.7, 10.0.0.2")
+		if ip := l.ClientIP(r); ip != want {
+			t.Errorf("spoofed %q: got %q, want %q", spoofed, ip, want)
+		}
+	}
+
+	// a proxy may add another header instead of appending to the existing one
+	r.Header.Set("X-Forwarded-For", "1.2.3.4")
+	r.Header.Add("X-Forwarded-For", "203.0.113.7")
+	if ip := l.ClientIP(r); ip != "203.0.113.7" {
+		t.Errorf("several headers: got %q", ip)
+	}
+}
@@ -6,7 +6,9 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"github.com/google/uuid"
 	"party-buddy/internal/validate"
+	"time"
 
 	"github.com/cohesivestack/valgo"
 )
@@ -23,6 +25,11 @@ var (
 	ErrOnlyOwnerAllowed    ErrorKind = "only-owned-allowed"
 	ErrAuthRequired        ErrorKind = "auth-required"
 	ErrNotEnoughPrivileges ErrorKind = "not-enough-privileges"
+	ErrSignatureInvalid    ErrorKind = "signature-invalid"
+	ErrSignatureExpired    ErrorKind = "signature-expired"
+	ErrTokenInvalid        ErrorKind = "token-invalid"
+	ErrTokenExpired        ErrorKind = "token-expired"
+	ErrUserIDTaken         ErrorKind = "user-id-taken"
 )
 
 // ImageErrorKind codes
@@ -51,6 +58,10 @@ var (
 // GameErrorKind codes
 var (
 	ErrGameInvalid ErrorKind = "game-invalid"
+
+	ErrBundleInvalid  ErrorKind = "bundle-invalid"
+	ErrBundleTooLarge ErrorKind = "bundle-too-large"
+	ErrCSVTooLarge    ErrorKind = "csv-too-large"
 )
 
 // GeneralErrorKind codes
@@ -59,11 +70,16 @@ var (
 	ErrMethodNotAllowed ErrorKind = "method-not-allowed"
 	ErrInternal         ErrorKind = "internal"
 	ErrMalformedRequest ErrorKind = "malformed-request"
+	ErrRateLimited      ErrorKind = "rate-limited"
 )
 
 var (
 	ErrInvalidUpgrade ErrorKind = "invalid-upgrade"
 	ErrUpgradeFailed  ErrorKind = "upgrade-failed"
+
+	ErrProtocolUnsupported ErrorKind = "protocol-unsupported"
+
+	ErrConnectionClosed ErrorKind = "connection-closed"
 )
 
 type Error struct {
@@ -91,6 +107,7 @@ func (e Error) String() string {
 //    All fields should be exported.
 // 2. Set a JSON tag for each field.
 // 3. Use [json.Marshal] (or its encoder) to convert a structure to JSON.
+// 4. Describe it in internal/apispec/openapi.yaml and add it to the contract tests.
 //
 // How to add a new request (input, client-to-server) body struct:
 // 1. Create a new (exported) type of the desired structure.
@@ -101,6 +118,7 @@ func (e Error) String() string {
 //    Make sure to check each required field for nil ([validate.FieldValue] may come in handy for this):
 //    otherwise you won't know if this field was present in a request.
 // 4. Use [Parse] to deserialize JSON into a target structure.
+// 5. Describe it in internal/apispec/openapi.yaml and add it to the contract tests.
 
 // Parse parses JSON-encoded data into target and runs validation.
 // Returns a formatted [Error] on parsing failure.
@@ -142,6 +160,9 @@ type ImgRequest int8
 type SessionCreateResponse struct {
 	InviteCode  string           `json:"invite-code"`
 	ImgRequests []ImgReqResponse `json:"img-requests"`
+
+	// TaskSeed can be passed to a new session to play the tasks in the same order.
+	TaskSeed int64 `json:"task-seed"`
 }
 
 type ImgReqResponse struct {
@@ -149,6 +170,117 @@ type ImgReqResponse struct {
 	ImgURI     string     `json:"img-uri"`
 }
 
+type Lobby struct {
+	SessionID uuid.UUID `json:"session-id"`
+
+	// GameID is null for the games sent along with the session request.
+	GameID      *uuid.UUID `json:"game-id"`
+	GameName    string     `json:"game-name"`
+	PlayerCount uint8      `json:"player-count"`
+	MaxPlayers  uint8      `json:"max-players"`
+	CreatedAt   time.Time  `json:"created-at"`
+}
+
+type GameImportResponse struct {
+	GameID uuid.UUID `json:"game-id"`
+}
+
+type GameCloneResponse struct {
+	GameID uuid.UUID `json:"game-id"`
+}
+
+type CSVImportResponse struct {
+	Rows []CSVRowReport `json:"rows"`
+
+	// ValidCount is the number of the rows that have passed the validation.
+	ValidCount int `json:"valid-count"`
+
+	// GameID is the game assembled of the valid rows: null if none has been requested, or in a dry run.
+	GameID *uuid.UUID `json:"game-id"`
+}
+
+type CSVRowReport struct {
+	// Line is the line of the CSV file the row starts at.
+	Line  int  `json:"line"`
+	Valid bool `json:"valid"`
+
+	// Error describes the first problem found in an invalid row.
+	Error *string `json:"error"`
+}
+
+type QuickJoinResponse struct {
+	SessionID uuid.UUID `json:"session-id"`
+
+	// Created is true if no suitable lobby existed and a new one was created for the client.
+	Created bool `json:"created"`
+}
+
+type TokenResponse struct {
+	UserID               uuid.UUID `json:"user-id"`
+	AccessToken          string    `json:"access-token"`
+	AccessTokenExpiresAt time.Time `json:"access-token-expires-at"`
+	RefreshToken         string    `json:"refresh-token"`
+}
+
+type AdminSessionSummary struct {
+	SessionID   uuid.UUID `json:"session-id"`
+	State       string    `json:"state"`
+	GameName    string    `json:"game-name"`
+	PlayerCount uint8     `json:"player-count"`
+	MaxPlayers  uint8     `json:"max-players"`
+	CreatedAt   time.Time `json:"created-at"`
+
+	// AgeSecs is the number of seconds since the session was created.
+	AgeSecs int64 `json:"age-secs"`
+}
+
+type AdminPlayer struct {
+	PlayerID uint32    `json:"player-id"`
+	ClientID uuid.UUID `json:"client-id"`
+	Nickname string    `json:"nickname"`
+	Score    uint32    `json:"score"`
+}
+
+type AdminAnswer struct {
+	PlayerID uint32 `json:"player-id"`
+
+	// Type is one of "photo", "text", "checked-text", "choice"
+	Type string `json:"type"`
+
+	// Value is an image uri for photo answers, an option index for choice answers and the text otherwise
+	Value any `json:"value"`
+}
+
+type AdminSessionDetails struct {
+	AdminSessionSummary
+
+	Owner      *uuid.UUID    `json:"owner"`
+	InviteCode *string       `json:"invite-code"`
+	TaskIdx    *int          `json:"task-idx"`
+	Deadline   time.Time     `json:"deadline"`
+	Players    []AdminPlayer `json:"players"`
+	Answers    []AdminAnswer `json:"answers"`
+	Banned     []uuid.UUID   `json:"banned"`
+	TaskSeed   int64         `json:"task-seed"`
+}
+
+const (
+	HealthOK       = "ok"
+	HealthFail     = "fail"
+	HealthDraining = "draining"
+)
+
+type HealthCheck struct {
+	// Status is either HealthOK or HealthFail
+	Status string `json:"status"`
+}
+
+type HealthResponse struct {
+	// Status is HealthOK, HealthFail or HealthDraining
+	Status string                 `json:"status"`
+	Checks map[string]HealthCheck `json:"checks,omitempty"`
+}
+
 type ErrorFromConverters struct {
 	ApiError   Error
 	StatusCode int
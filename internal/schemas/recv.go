@@ -19,26 +19,82 @@ const (
 	Private GameType = "private"
 )
 
+type LateJoinScore string
+
+var validLateJoinScores = []LateJoinScore{LateJoinZero, LateJoinMinimum}
+
+const (
+	LateJoinZero    LateJoinScore = "zero"
+	LateJoinMinimum LateJoinScore = "minimum"
+)
+
 type BaseCreateSessionRequest struct {
 	PlayerCount  *int8     `json:"player-count"`
 	RequireReady *bool     `json:"require-ready"`
 	GameType     *GameType `json:"game-type"`
+
+	// The session timings in seconds. The defaults are used for those not set.
+	NoOwnerTimeout     *uint16 `json:"no-owner-timeout,omitempty"`
+	GameStartedTimeout *uint16 `json:"game-started-timeout,omitempty"`
+	TaskEndTimeout     *uint16 `json:"task-end-timeout,omitempty"`
+
+	// LateJoin lets players join after the game has started, starting with the LateJoinScore ("zero" by default).
+	LateJoin      *bool          `json:"late-join,omitempty"`
+	LateJoinScore *LateJoinScore `json:"late-join-score,omitempty"`
+
+	// The task selection: the tasks can be played in a random order (ShuffleTasks),
+	// or TaskCount of them can be drawn at random, keeping the proportions of the task kinds if StratifyTasks is set.
+	// TaskSeed reproduces the selection of an earlier session.
+	ShuffleTasks  *bool  `json:"shuffle-tasks,omitempty"`
+	TaskCount     *int   `json:"task-count,omitempty"`
+	StratifyTasks *bool  `json:"stratify-tasks,omitempty"`
+	TaskSeed      *int64 `json:"task-seed,omitempty"`
 }
 
 func (r *BaseCreateSessionRequest) Validate(ctx context.Context) *valgo.Validation {
 	f, _ := validate.FromContext(ctx)
 
-	return f.
+	v := f.
 		Is(valgo.Int8P(r.PlayerCount, "player-count", "player-count").Not().Nil().
 			Between(configuration.PlayerMin, configuration.PlayerMax)).
 		Is(valgo.StringP(r.GameType, "game-type", "game-type").Not().Nil().
 			InSlice(validGameTypes, "game-type")).
 		Is(valgo.BoolP(r.RequireReady, "require-ready", "require-ready").Not().Nil())
+
+	if r.NoOwnerTimeout != nil {
+		v = v.Is(valgo.Uint16P(r.NoOwnerTimeout, "no-owner-timeout", "no-owner-timeout").
+			Between(configuration.MinNoOwnerTimeout, configuration.MaxNoOwnerTimeout))
+	}
+	if r.GameStartedTimeout != nil {
+		v = v.Is(valgo.Uint16P(r.GameStartedTimeout, "game-started-timeout", "game-started-timeout").
+			Between(configuration.MinGameStartedTimeout, configuration.MaxGameStartedTimeout))
+	}
+	if r.TaskEndTimeout != nil {
+		v = v.Is(valgo.Uint16P(r.TaskEndTimeout, "task-end-timeout", "task-end-timeout").
+			Between(configuration.MinTaskEndTimeout, configuration.MaxTaskEndTimeout))
+	}
+	if r.TaskCount != nil {
+		v = v.Is(valgo.IntP(r.TaskCount, "task-count", "task-count").
+			Between(configuration.MinTaskCount, configuration.MaxTaskCount))
+	}
+	if r.TaskSeed != nil {
+		v = v.Is(valgo.Int64P(r.TaskSeed, "task-seed", "task-seed").
+			Between(0, configuration.MaxTaskSeed))
+	}
+	if r.LateJoinScore != nil {
+		v = v.Is(valgo.StringP(r.LateJoinScore, "late-join-score", "late-join-score").
+			InSlice(validLateJoinScores, "late-join-score"))
+	}
+
+	return v
 }
 
 type PublicCreateSessionRequest struct {
 	BaseCreateSessionRequest
 	GameID *uuid.UUID `json:"game-id"`
+
+	// Listed makes the session visible in the lobby browser.
+	Listed *bool `json:"listed,omitempty"`
 }
 
 func (r *PublicCreateSessionRequest) Validate(ctx context.Context) *valgo.Validation {
@@ -200,3 +256,124 @@ func (t *BaseTaskWithImgRequest) Validate(ctx context.Context) *valgo.Validation
 		return v
 	}
 }
+
+type RegisterDeviceRequest struct {
+	// LegacyID is the id the client used before registering.
+	// It's only honored while legacy ids are allowed.
+	LegacyID *uuid.UUID `json:"legacy-id,omitempty"`
+}
+
+func (r *RegisterDeviceRequest) Validate(ctx context.Context) *valgo.Validation {
+	f, _ := validate.FromContext(ctx)
+
+	return f.New()
+}
+
+type RefreshTokenRequest struct {
+	RefreshToken *string `json:"refresh-token"`
+}
+
+func (r *RefreshTokenRequest) Validate(ctx context.Context) *valgo.Validation {
+	f, _ := validate.FromContext(ctx)
+
+	return f.Is(valgo.StringP(r.RefreshToken, "refresh-token", "refresh-token").Not().Nil().Not().Blank())
+}
+
+type AdminCloseSessionRequest struct {
+	Reason *string `json:"reason"`
+}
+
+func (r *AdminCloseSessionRequest) Validate(ctx context.Context) *valgo.Validation {
+	f, _ := validate.FromContext(ctx)
+
+	return f.Is(valgo.StringP(r.Reason, "reason", "reason").Not().Nil().Not().Blank().
+		MatchingTo(configuration.BaseTextReg).Passing(util.MaxLengthPChecker(configuration.MaxDescriptionLength)))
+}
+
+// GameBundleManifest describes a game exported with its tasks.
+// It's stored as manifest.json in the bundle, next to the image files.
+type GameBundleManifest struct {
+	Version *uint16       `json:"version"`
+	Game    *FullGameInfo `json:"game"`
+
+	// Images maps the img-requests of the game and its tasks to the image files in the bundle.
+	Images *[]GameBundleImage `json:"images"`
+}
+
+type GameBundleImage struct {
+	ImgRequest *api.ImgRequest `json:"img-request"`
+	File       *string         `json:"file"`
+}
+
+func (m *GameBundleManifest) Validate(ctx context.Context) *valgo.Validation {
+	f, _ := validate.FromContext(ctx)
+
+	v := f.Is(valgo.Uint16P(m.Version, "version", "version").Not().Nil().
+		EqualTo(configuration.GameBundleVersion)).
+		Is(validate.FieldValue(m.Game, "game", "game").Set()).
+		Is(validate.FieldValue(m.Images, "images", "images").Set())
+
+	if m.Images != nil {
+		seen := make(map[api.ImgRequest]bool)
+		for _, img := range *m.Images {
+			v = v.Is(valgo.Int8P(img.ImgRequest, "img-request", "img-request").Not().Nil().
+				GreaterOrEqualTo(0).
+				Passing(func(r *api.ImgRequest) bool { return r == nil || !seen[*r] }, "img-requests must be unique")).
+				Is(valgo.StringP(img.File, "file", "file").Not().Nil().Not().Blank())
+			if img.ImgRequest != nil {
+				seen[*img.ImgRequest] = true
+			}
+		}
+	}
+
+	if m.Game == nil {
+		return v
+	}
+	return v.Merge(m.Game.Validate(ctx))
+}
+
+type CloneTasks string
+
+var validCloneTasks = []CloneTasks{CloneShared, CloneCopied}
+
+const (
+	CloneShared CloneTasks = "shared"
+	CloneCopied CloneTasks = "copied"
+)
+
+type CloneGameRequest struct {
+	// Tasks chooses whether the clone refers to the tasks of the original game ("shared" by default)
+	// or gets its own copies of them ("copied").
+	Tasks *CloneTasks `json:"tasks,omitempty"`
+}
+
+func (r *CloneGameRequest) Validate(ctx context.Context) *valgo.Validation {
+	f, _ := validate.FromContext(ctx)
+
+	v := f.New()
+	if r.Tasks != nil {
+		v = v.Is(valgo.StringP(r.Tasks, "tasks", "tasks").InSlice(validCloneTasks, "tasks"))
+	}
+	return v
+}
+
+type QuickJoinRequest struct {
+	// GameID restricts the search to the lobbies playing the game.
+	// If no such lobby exists, a new one is created for it.
+	GameID *uuid.UUID `json:"game-id,omitempty"`
+
+	// PlayerCount and RequireReady are only used when a new lobby is created.
+	PlayerCount  *int8 `json:"player-count,omitempty"`
+	RequireReady *bool `json:"require-ready,omitempty"`
+}
+
+func (r *QuickJoinRequest) Validate(ctx context.Context) *valgo.Validation {
+	f, _ := validate.FromContext(ctx)
+
+	v := f.New()
+	if r.PlayerCount != nil {
+		v = v.Is(valgo.Int8P(r.PlayerCount, "player-count", "player-count").
+			Between(configuration.PlayerMin, configuration.PlayerMax))
+	}
+	return v
+}
@@ -76,5 +76,8 @@ type IDGameInfo struct {
 
 	ID uuid.UUID `json:"id"`
 
+	// ForkedFrom is the game this one has been cloned from, if any.
+	ForkedFrom *uuid.UUID `json:"forked-from"`
+
 	Tasks []BaseTaskWithImgAndID `json:"tasks"`
 }
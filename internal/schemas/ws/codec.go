@@ -0,0 +1,116 @@
+package ws
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+
+	"github.com/fxamacker/cbor/v2"
+)
+
+// An Encoding is a wire format of the protocol messages.
+type Encoding string
+
+const (
+	EncodingJSON Encoding = "json"
+
+	// EncodingCBOR is the binary encoding (RFC 8949).
+	// The field names are the same as in JSON, UUIDs are encoded as 16-byte strings.
+	EncodingCBOR Encoding = "cbor"
+)
+
+// A Codec encodes and decodes protocol messages.
+type Codec interface {
+	Encoding() Encoding
+
+	// Binary returns true if the messages must be sent in binary frames.
+	Binary() bool
+
+	Marshal(v any) ([]byte, error)
+
+	// Unmarshal decodes the data into v.
+	// If strict is true, fields not present in v are rejected.
+	Unmarshal(data []byte, v any, strict bool) error
+}
+
+var (
+	JSONCodec Codec = jsonCodec{}
+	CBORCodec Codec = newCBORCodec()
+)
+
+// CodecFor returns the codec for the encoding.
+func CodecFor(encoding Encoding) Codec {
+	if encoding == EncodingCBOR {
+		return CBORCodec
+	}
+	return JSONCodec
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) Encoding() Encoding {
+	return EncodingJSON
+}
+
+func (jsonCodec) Binary() bool {
+	return false
+}
+
+func (jsonCodec) Marshal(v any) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonCodec) Unmarshal(data []byte, v any, strict bool) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	if strict {
+		decoder.DisallowUnknownFields()
+	}
+	return decoder.Decode(v)
+}
+
+type cborCodec struct {
+	enc    cbor.EncMode
+	dec    cbor.DecMode
+	strict cbor.DecMode
+}
+
+func newCBORCodec() cborCodec {
+	// times are encoded like in JSON
+	enc, err := cbor.EncOptions{Time: cbor.TimeRFC3339Nano}.EncMode()
+	if err != nil {
+		panic(err)
+	}
+
+	decOpts := cbor.DecOptions{DefaultMapType: reflect.TypeOf(map[string]any{})}
+	dec, err := decOpts.DecMode()
+	if err != nil {
+		panic(err)
+	}
+
+	decOpts.ExtraReturnErrors = cbor.ExtraDecErrorUnknownField
+	strict, err := decOpts.DecMode()
+	if err != nil {
+		panic(err)
+	}
+
+	return cborCodec{enc: enc, dec: dec, strict: strict}
+}
+
+func (cborCodec) Encoding() Encoding {
+	return EncodingCBOR
+}
+
+func (cborCodec) Binary() bool {
+	return true
+}
+
+func (c cborCodec) Marshal(v any) ([]byte, error) {
+	return c.enc.Marshal(v)
+}
+
+func (c cborCodec) Unmarshal(data []byte, v any, strict bool) error {
+	if strict {
+		return c.strict.Unmarshal(data, v)
+	}
+	return c.dec.Unmarshal(data, v)
+}
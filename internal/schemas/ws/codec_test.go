@@ -0,0 +1,252 @@
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"party-buddy/internal/schemas"
+	"party-buddy/internal/validate"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var codecs = []Codec{JSONCodec, CBORCodec}
+
+func testBase(kind MessageKind, id MessageID) BaseMessage {
+	t := Time(time.UnixMilli(1701517977438))
+	return BaseMessage{MsgID: &id, Kind: &kind, Time: &t}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func TestRespMessagesRoundTrip(t *testing.T) {
+	seqBase := testBase(MsgKindWaiting, 7)
+	seqBase.SetSeq(42)
+
+	messages := []RespMessage{
+		&MessageError{
+			BaseMessage: testBase(MsgKindError, 1),
+			Error:       Error{RefID: ptr(MessageID(3)), Code: ErrRateLimited, Message: "too many messages"},
+		},
+		&MessageJoined{
+			BaseMessage: testBase(MsgKindJoined, 2),
+			RefID:       ptr(MessageID(1)),
+			PlayerID:    5,
+			Sid:         uuid.MustParse("6ba7b810-9dad-11d1-80b4-00c04fd430c8"),
+			InviteCode:  ptr("ABC123"),
+			Game: schemas.GameDetails{
+				BaseGameInfo: schemas.BaseGameInfo{
+					Name:        "Quiz",
+					Description: "A quiz",
+					ImgURI:      "http://localhost/img",
+					DateChanged: time.Date(2023, 12, 1, 10, 0, 0, 500, time.UTC),
+				},
+				Tasks: []schemas.BaseTaskWithImg{{
+					BaseTask: schemas.BaseTask{
+						Name:         "Task",
+						Description:  "Pick one",
+						Duration:     schemas.PollDuration{Kind: schemas.Fixed, Secs: 30},
+						Type:         schemas.Choice,
+						PollDuration: schemas.PollDuration{Kind: schemas.Dynamic, Secs: 10},
+					},
+					ImgURI: "http://localhost/task-img",
+				}},
+			},
+			MaxPlayers: 20,
+		},
+		&MessageGameStatus{
+			BaseMessage: testBase(MsgKindGameStatus, 3),
+			Players:     []Player{{PlayerID: 1, Nickname: "alice"}, {PlayerID: 2, Nickname: "bob"}},
+		},
+		&MessageTaskStart{
+			BaseMessage: testBase(MsgKindTaskStart, 4),
+			TaskIdx:     1,
+			Deadline:    Time(time.UnixMilli(1701517987438)),
+			Options:     &[]string{"a", "b", "c", "d"},
+		},
+		&MessageTaskStart{
+			BaseMessage: testBase(MsgKindTaskStart, 5),
+			Deadline:    Time(time.UnixMilli(1701517987438)),
+			ImgURI:      ptr("http://localhost/photo"),
+		},
+		&MessageTaskEnd{
+			BaseMessage: testBase(MsgKindTaskEnd, 6),
+			TaskIdx:     2,
+			Deadline:    Time(time.UnixMilli(1701517997438)),
+			Scoreboard:  []TaskPlayerScore{{PlayerID: 1, TaskPoints: 2, TotalPoints: 6}},
+		},
+		&MessageWaiting{
+			BaseMessage: seqBase,
+			Ready:       []uint32{1, 2},
+		},
+		&MessageGameStart{
+			BaseMessage: testBase(MsgKindGameStart, 8),
+			Deadline:    Time(time.UnixMilli(1701518007438)),
+		},
+		&MessageGameEnd{
+			BaseMessage: testBase(MsgKindGameEnd, 9),
+			Scoreboard:  []GamePlayerScore{{PlayerID: 2, TotalPoints: 10}, {PlayerID: 1, TotalPoints: 6}},
+		},
+		&MessageChatMessage{
+			BaseMessage: testBase(MsgKindChatMessage, 10),
+			ChatEntry:   ChatEntry{PlayerID: 1, Text: "hi", SentAt: Time(time.UnixMilli(1701518007438))},
+		},
+		&MessageReaction{BaseMessage: testBase(MsgKindReaction, 11), PlayerID: 2, Reaction: "👏"},
+		&MessageChatMuted{BaseMessage: testBase(MsgKindChatMuted, 12), Muted: true},
+		&MessageOwnerChanged{BaseMessage: testBase(MsgKindOwnerChanged, 14), PlayerID: 3},
+		&MessageDeadlineChanged{
+			BaseMessage: testBase(MsgKindDeadlineChanged, 15),
+			Deadline:    Time(time.UnixMilli(1701518007438)),
+			Paused:      true,
+			RemainingMs: ptr(uint64(12500)),
+		},
+		&MessageChatHistory{
+			BaseMessage: testBase(MsgKindChatHistory, 13),
+			Messages:    []ChatEntry{{PlayerID: 1, Text: "hi", SentAt: Time(time.UnixMilli(1701518007438))}},
+		},
+	}
+
+	for _, codec := range codecs {
+		for _, msg := range messages {
+			data, err := codec.Marshal(msg)
+			if err != nil {
+				t.Errorf("%s: could not encode %T: %v", codec.Encoding(), msg, err)
+				continue
+			}
+
+			decoded := reflect.New(reflect.TypeOf(msg).Elem()).Interface()
+			if err = codec.Unmarshal(data, decoded, true); err != nil {
+				t.Errorf("%s: could not decode %T: %v", codec.Encoding(), msg, err)
+				continue
+			}
+
+			if !reflect.DeepEqual(msg, decoded) {
+				t.Errorf("%s: %T does not survive a round trip:\n got %+v\nwant %+v", codec.Encoding(), msg, decoded, msg)
+			}
+		}
+	}
+}
+
+// The answers in `task-end` are interfaces, so they cannot be decoded into the message struct.
+// Instead, their shape is compared with the JSON one.
+func TestTaskEndAnswersShape(t *testing.T) {
+	msg := &MessageTaskEnd{
+		BaseMessage: testBase(MsgKindTaskEnd, 1),
+		Answers: []Answer{
+			&CheckedWordAnswer{Value: "word", PlayerCount: 3, Correct: true},
+			&PhotoAnswer{Value: "http://localhost/photo", Votes: 2},
+			&WordAnswer{Value: "text", Votes: 1},
+			&TaskOptionAnswer{Value: "option", PlayerCount: 4, Correct: false},
+		},
+	}
+
+	var want map[string]any
+	data, _ := JSONCodec.Marshal(msg)
+	if err := JSONCodec.Unmarshal(data, &want, false); err != nil {
+		t.Fatalf("could not decode JSON: %v", err)
+	}
+
+	var decoded any
+	data, err := CBORCodec.Marshal(msg)
+	if err != nil {
+		t.Fatalf("could not encode CBOR: %v", err)
+	}
+	if err = CBORCodec.Unmarshal(data, &decoded, false); err != nil {
+		t.Fatalf("could not decode CBOR: %v", err)
+	}
+
+	// normalize the numbers
+	var got map[string]any
+	data, _ = json.Marshal(decoded)
+	_ = json.Unmarshal(data, &got)
+
+	if !reflect.DeepEqual(want["answers"], got["answers"]) {
+		t.Errorf("the answers differ:\n got %v\nwant %v", got, want)
+	}
+}
+
+func TestRecvMessagesRoundTrip(t *testing.T) {
+	ctx := validate.NewContext(context.Background(), validate.NewValidationFactory())
+
+	messages := []RecvMessage{
+		&MessageJoin{BaseMessage: testBase(MsgKindJoin, 1), Nickname: ptr("alice")},
+		&MessageJoin{BaseMessage: testBase(MsgKindJoin, 2), Nickname: ptr("bob"), LastSeq: ptr(uint64(17))},
+		&MessageReady{BaseMessage: testBase(MsgKindReady, 3), Ready: ptr(true)},
+		&MessageKick{BaseMessage: testBase(MsgKindKick, 4)},
+		&MessageLeave{BaseMessage: testBase(MsgKindLeave, 5)},
+		&MessageTaskAnswer{BaseMessage: testBase(MsgKindTaskAnswer, 6), TaskIdx: ptr(0), Ready: ptr(false)},
+		&MessageTaskAnswer{
+			BaseMessage: testBase(MsgKindTaskAnswer, 7),
+			TaskIdx:     ptr(1),
+			Ready:       ptr(true),
+			Answer:      &RecvAnswer{Type: ptr(Option), Option: ptr(uint8(2))},
+		},
+		&MessageTaskAnswer{
+			BaseMessage: testBase(MsgKindTaskAnswer, 8),
+			TaskIdx:     ptr(2),
+			Ready:       ptr(true),
+			Answer:      &RecvAnswer{Type: ptr(CheckedText), Text: ptr("ANSWER")},
+		},
+		&MessageTaskAnswer{
+			BaseMessage: testBase(MsgKindTaskAnswer, 9),
+			TaskIdx:     ptr(3),
+			Ready:       ptr(false),
+			Answer:      &RecvAnswer{Type: ptr(Text), Text: ptr("some text")},
+		},
+		&MessagePollChoose{BaseMessage: testBase(MsgKindPollChoose, 10)},
+		&MessageChat{BaseMessage: testBase(MsgKindChat, 11), Text: ptr("good luck, have fun")},
+		&MessageReact{BaseMessage: testBase(MsgKindReact, 12), Reaction: ptr("🔥")},
+		&MessageMuteChat{BaseMessage: testBase(MsgKindMuteChat, 13), Muted: ptr(true)},
+		&MessageTransferOwner{BaseMessage: testBase(MsgKindTransferOwner, 14), PlayerID: ptr(uint32(3))},
+		&MessagePause{BaseMessage: testBase(MsgKindPause, 15)},
+		&MessageResume{BaseMessage: testBase(MsgKindResume, 16)},
+		&MessageSkipTask{BaseMessage: testBase(MsgKindSkipTask, 17)},
+		&MessageAddTime{BaseMessage: testBase(MsgKindAddTime, 18), Seconds: ptr(uint16(30))},
+	}
+
+	for _, codec := range codecs {
+		for _, msg := range messages {
+			data, err := codec.Marshal(msg)
+			if err != nil {
+				t.Errorf("%s: could not encode %T: %v", codec.Encoding(), msg, err)
+				continue
+			}
+
+			parsed, err := ParseMessage(ctx, codec, data)
+			if err != nil {
+				t.Errorf("%s: could not parse %T: %v", codec.Encoding(), msg, err)
+				continue
+			}
+
+			if !reflect.DeepEqual(msg, parsed) {
+				t.Errorf("%s: %T does not survive a round trip:\n got %+v\nwant %+v", codec.Encoding(), msg, parsed, msg)
+			}
+		}
+	}
+}
+
+func TestParseMessageRejectsUnknownFields(t *testing.T) {
+	ctx := validate.NewContext(context.Background(), validate.NewValidationFactory())
+
+	for _, codec := range codecs {
+		data, err := codec.Marshal(map[string]any{
+			"msg-id":   1,
+			"kind":     MsgKindReady,
+			"time":     1701517977438,
+			"ready":    true,
+			"unwanted": 1,
+		})
+		if err != nil {
+			t.Fatalf("%s: could not encode: %v", codec.Encoding(), err)
+		}
+
+		_, err = ParseMessage(ctx, codec, data)
+		if dto := ParseErrorToMessageError(err).(*Error); dto.Code != ErrMalformedMsg {
+			t.Errorf("%s: expected %s, got %v", codec.Encoding(), ErrMalformedMsg, err)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package ws
+
+import "fmt"
+
+// A ProtocolVersion is a version of the session protocol.
+type ProtocolVersion uint8
+
+const (
+	// ProtocolV1 is the original protocol.
+	// It's also assumed for clients that don't request a subprotocol.
+	ProtocolV1 ProtocolVersion = 1
+
+	// ProtocolV2 adds sequence numbers to server messages (the `seq` field)
+	// and the replay of missed messages on rejoin (the `last-seq` field of `join`).
+	ProtocolV2 ProtocolVersion = 2
+
+	// ProtocolV3 adds the chat, the reactions and the lobby ownership transfer.
+	ProtocolV3 ProtocolVersion = 3
+)
+
+// sinceVersion maps the message kinds added after ProtocolV1 to the version that introduced them.
+var sinceVersion = map[MessageKind]ProtocolVersion{
+	MsgKindChat:        ProtocolV3,
+	MsgKindReact:       ProtocolV3,
+	MsgKindMuteChat:    ProtocolV3,
+	MsgKindChatMessage: ProtocolV3,
+	MsgKindReaction:    ProtocolV3,
+	MsgKindChatMuted:   ProtocolV3,
+	MsgKindChatHistory: ProtocolV3,
+
+	MsgKindTransferOwner: ProtocolV3,
+	MsgKindOwnerChanged:  ProtocolV3,
+
+	MsgKindPause:           ProtocolV3,
+	MsgKindResume:          ProtocolV3,
+	MsgKindSkipTask:        ProtocolV3,
+	MsgKindAddTime:         ProtocolV3,
+	MsgKindDeadlineChanged: ProtocolV3,
+}
+
+// Supports returns true if the message kind is a part of the protocol version.
+func (v ProtocolVersion) Supports(kind MessageKind) bool {
+	since, ok := sinceVersion[kind]
+	return !ok || v >= since
+}
+
+// A Protocol is negotiated with the client via the WebSocket subprotocol (the Sec-WebSocket-Protocol header).
+// It defines the protocol version and the message encoding.
+type Protocol struct {
+	Version  ProtocolVersion
+	Encoding Encoding
+}
+
+// SupportedProtocols lists the supported protocols, most preferred first.
+var SupportedProtocols = []Protocol{
+	{Version: ProtocolV3, Encoding: EncodingCBOR},
+	{Version: ProtocolV3, Encoding: EncodingJSON},
+	{Version: ProtocolV2, Encoding: EncodingCBOR},
+	{Version: ProtocolV2, Encoding: EncodingJSON},
+	{Version: ProtocolV1, Encoding: EncodingCBOR},
+	{Version: ProtocolV1, Encoding: EncodingJSON},
+}
+
+const subprotocolPrefix = "partybuddy.v"
+
+// Subprotocol returns the name of the WebSocket subprotocol for the protocol,
+// e.g. `partybuddy.v1` for JSON or `partybuddy.v2+cbor` for CBOR.
+func (p Protocol) Subprotocol() string {
+	name := fmt.Sprintf("%s%d", subprotocolPrefix, p.Version)
+	if p.Encoding != EncodingJSON {
+		name += "+" + string(p.Encoding)
+	}
+	return name
+}
+
+func (p Protocol) String() string {
+	return p.Subprotocol()
+}
+
+// Codec returns the codec for the protocol's messages.
+func (p Protocol) Codec() Codec {
+	return CodecFor(p.Encoding)
+}
+
+// TextProtocols lists the supported protocols with text encodings, most preferred first.
+// These are the only ones available over transports that cannot carry binary messages.
+func TextProtocols() []Protocol {
+	var protocols []Protocol
+	for _, p := range SupportedProtocols {
+		if !p.Codec().Binary() {
+			protocols = append(protocols, p)
+		}
+	}
+	return protocols
+}
+
+// NegotiateProtocol picks the most preferred supported protocol among the subprotocols offered by the client.
+//
+// If the client offered none, ProtocolV1 over JSON is assumed.
+// If none of the offered subprotocols is supported, ok is false.
+func NegotiateProtocol(offered []string) (p Protocol, ok bool) {
+	return negotiate(offered, SupportedProtocols)
+}
+
+// NegotiateTextProtocol is like NegotiateProtocol but only considers TextProtocols
+func NegotiateTextProtocol(offered []string) (p Protocol, ok bool) {
+	return negotiate(offered, TextProtocols())
+}
+
+func negotiate(offered []string, supported []Protocol) (p Protocol, ok bool) {
+	if len(offered) == 0 {
+		return Protocol{Version: ProtocolV1, Encoding: EncodingJSON}, true
+	}
+
+	for _, p = range supported {
+		for _, name := range offered {
+			if name == p.Subprotocol() {
+				return p, true
+			}
+		}
+	}
+
+	return Protocol{}, false
+}
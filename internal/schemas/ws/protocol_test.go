@@ -0,0 +1,56 @@
+package ws
+
+import "testing"
+
+func TestNegotiateProtocol(t *testing.T) {
+	v1JSON := Protocol{Version: ProtocolV1, Encoding: EncodingJSON}
+	v2JSON := Protocol{Version: ProtocolV2, Encoding: EncodingJSON}
+	v2CBOR := Protocol{Version: ProtocolV2, Encoding: EncodingCBOR}
+	v3JSON := Protocol{Version: ProtocolV3, Encoding: EncodingJSON}
+
+	tests := []struct {
+		offered []string
+		want    Protocol
+		ok      bool
+	}{
+		{offered: nil, want: v1JSON, ok: true},
+		{offered: []string{"partybuddy.v1"}, want: v1JSON, ok: true},
+		{offered: []string{"partybuddy.v1", "partybuddy.v2"}, want: v2JSON, ok: true},
+		{offered: []string{"chat", "partybuddy.v2"}, want: v2JSON, ok: true},
+		{offered: []string{"partybuddy.v2", "partybuddy.v2+cbor"}, want: v2CBOR, ok: true},
+		{offered: []string{"partybuddy.v3", "partybuddy.v2+cbor"}, want: v3JSON, ok: true},
+		{offered: []string{"partybuddy.v9"}, ok: false},
+	}
+
+	for _, tt := range tests {
+		got, ok := NegotiateProtocol(tt.offered)
+		if ok != tt.ok || (ok && got != tt.want) {
+			t.Errorf("NegotiateProtocol(%v) = %v, %v; want %v, %v", tt.offered, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestNegotiateTextProtocol(t *testing.T) {
+	offered := []string{"partybuddy.v1", "partybuddy.v2+cbor", "partybuddy.v2"}
+	want := Protocol{Version: ProtocolV2, Encoding: EncodingJSON}
+	if got, ok := NegotiateTextProtocol(offered); !ok || got != want {
+		t.Errorf("NegotiateTextProtocol(%v) = %v, %v; want %v, true", offered, got, ok, want)
+	}
+
+	offered = []string{"partybuddy.v2+cbor"}
+	if got, ok := NegotiateTextProtocol(offered); ok {
+		t.Errorf("NegotiateTextProtocol(%v) = %v, true; want a failure", offered, got)
+	}
+}
+
+func TestProtocolVersionSupports(t *testing.T) {
+	if !ProtocolV1.Supports(MsgKindJoin) {
+		t.Error("v1 should support the original messages")
+	}
+	if ProtocolV2.Supports(MsgKindChat) || ProtocolV2.Supports(MsgKindChatHistory) {
+		t.Error("v2 should not support the chat")
+	}
+	if !ProtocolV3.Supports(MsgKindChat) || !ProtocolV3.Supports(MsgKindChatHistory) {
+		t.Error("v3 should support the chat")
+	}
+}
@@ -1,7 +1,6 @@
 package ws
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
@@ -15,6 +14,7 @@ import (
 	"time"
 
 	"github.com/cohesivestack/valgo"
+	"github.com/fxamacker/cbor/v2"
 )
 
 // See internal/api/api_schema.go for information on serialization/deserialization.
@@ -38,8 +38,58 @@ var (
 	MsgKindGameEnd    MessageKind = "game-end"
 	MsgKindGameStart  MessageKind = "game-start"
 	MsgKindWaiting    MessageKind = "waiting"
+
+	MsgKindChat        MessageKind = "chat"
+	MsgKindReact       MessageKind = "react"
+	MsgKindMuteChat    MessageKind = "mute-chat"
+	MsgKindChatMessage MessageKind = "chat-message"
+	MsgKindReaction    MessageKind = "reaction"
+	MsgKindChatMuted   MessageKind = "chat-muted"
+	MsgKindChatHistory MessageKind = "chat-history"
+
+	MsgKindTransferOwner MessageKind = "transfer-owner"
+	MsgKindOwnerChanged  MessageKind = "owner-changed"
+
+	MsgKindPause           MessageKind = "pause"
+	MsgKindResume          MessageKind = "resume"
+	MsgKindSkipTask        MessageKind = "skip-task"
+	MsgKindAddTime         MessageKind = "add-time"
+	MsgKindDeadlineChanged MessageKind = "deadline-changed"
 )
 
+// MessageKinds lists every message kind of the protocol
+var MessageKinds = []MessageKind{
+	MsgKindError,
+	MsgKindJoin,
+	MsgKindJoined,
+	MsgKindGameStatus,
+	MsgKindReady,
+	MsgKindKick,
+	MsgKindLeave,
+	MsgKindTaskStart,
+	MsgKindTaskAnswer,
+	MsgKindPollStart,
+	MsgKindPollChoose,
+	MsgKindTaskEnd,
+	MsgKindGameEnd,
+	MsgKindGameStart,
+	MsgKindWaiting,
+	MsgKindChat,
+	MsgKindReact,
+	MsgKindMuteChat,
+	MsgKindChatMessage,
+	MsgKindReaction,
+	MsgKindChatMuted,
+	MsgKindChatHistory,
+	MsgKindTransferOwner,
+	MsgKindOwnerChanged,
+	MsgKindPause,
+	MsgKindResume,
+	MsgKindSkipTask,
+	MsgKindAddTime,
+	MsgKindDeadlineChanged,
+}
+
 func (m MessageKind) MarshalText() ([]byte, error) {
 	return []byte(m), nil
 }
@@ -71,10 +121,28 @@ func (t *Time) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
+func (t Time) MarshalCBOR() ([]byte, error) {
+	return cbor.Marshal(time.Time(t).UnixMilli())
+}
+
+func (t *Time) UnmarshalCBOR(data []byte) error {
+	var val int64
+	err := cbor.Unmarshal(data, &val)
+	if err != nil {
+		return err
+	}
+	*t = Time(time.UnixMilli(val))
+	return nil
+}
+
 type BaseMessage struct {
 	MsgID *MessageID   `json:"msg-id"`
 	Kind  *MessageKind `json:"kind"`
 	Time  *Time        `json:"time"`
+
+	// Seq is the session-scoped sequence number of a server message.
+	// It's only set for the messages the server can replay after a reconnect (see MessageJoin.LastSeq).
+	Seq *uint64 `json:"seq,omitempty"`
 }
 
 func (m *BaseMessage) GetKind() MessageKind {
@@ -89,6 +157,10 @@ func (m *BaseMessage) SetMsgID(id MessageID) {
 	m.MsgID = &id
 }
 
+func (m *BaseMessage) SetSeq(seq uint64) {
+	m.Seq = &seq
+}
+
 func (m *BaseMessage) Validate(ctx context.Context) *valgo.Validation {
 	f, _ := validate.FromContext(ctx)
 
@@ -120,6 +192,7 @@ var (
 	ErrMalformedMsg   ErrorKind = "malformed-msg"
 	ErrProtoViolation ErrorKind = "proto-violation"
 	ErrReconnected    ErrorKind = "reconnected"
+	ErrRateLimited    ErrorKind = "rate-limited"
 )
 
 // JoinErrorKind codes
@@ -135,10 +208,18 @@ var (
 	ErrOpOnly ErrorKind = "op-only"
 )
 
+// ChatErrorKind codes
+var (
+	ErrChatMuted ErrorKind = "chat-muted"
+)
+
 // GameErrorKind codes
 var (
-	ErrInactivity    ErrorKind = "inactivity"
-	ErrSessionClosed ErrorKind = "session-closed"
+	ErrInactivity        ErrorKind = "inactivity"
+	ErrInactivityWarning ErrorKind = "inactivity-warning"
+	ErrJoinedMidTask     ErrorKind = "joined-mid-task"
+	ErrSessionClosed     ErrorKind = "session-closed"
+	ErrKicked            ErrorKind = "kicked"
 )
 
 type Error struct {
@@ -172,6 +253,11 @@ type MessageJoin struct {
 	BaseMessage
 
 	Nickname *string `json:"nickname"`
+
+	// LastSeq is the sequence number of the last message the client received before reconnecting.
+	// If provided, the server replays the messages the client has missed instead of sending a state snapshot.
+	// Only honored since ProtocolV2.
+	LastSeq *uint64 `json:"last-seq,omitempty"`
 }
 
 var nicknameRegex *regexp.Regexp = regexp.MustCompile("^[a-zA-Zа-яА-Я._ 0-9]{1,20}$")
@@ -232,11 +318,45 @@ type RecvAnswer struct {
 	Text   *string
 }
 
+// recvAnswerWire is the wire representation of a RecvAnswer.
+// The type of the value depends on the answer type.
+type recvAnswerWire struct {
+	Type  *RecvAnswerType `json:"type"`
+	Value any             `json:"value,omitempty"`
+}
+
+func (a RecvAnswer) toWire() recvAnswerWire {
+	w := recvAnswerWire{Type: a.Type}
+	switch {
+	case a.Text != nil:
+		w.Value = *a.Text
+	case a.Option != nil:
+		w.Value = *a.Option
+	}
+	return w
+}
+
+func (a RecvAnswer) MarshalJSON() ([]byte, error) {
+	return json.Marshal(a.toWire())
+}
+
+func (a RecvAnswer) MarshalCBOR() ([]byte, error) {
+	return cbor.Marshal(a.toWire())
+}
+
 func (a *RecvAnswer) UnmarshalJSON(data []byte) error {
+	return a.unmarshal(data, json.Unmarshal)
+}
+
+func (a *RecvAnswer) UnmarshalCBOR(data []byte) error {
+	return a.unmarshal(data, cbor.Unmarshal)
+}
+
+func (a *RecvAnswer) unmarshal(data []byte, unmarshal func(data []byte, v any) error) error {
 	var base struct {
 		Type *RecvAnswerType `json:"type"`
 	}
-	if err := json.Unmarshal(data, &base); err != nil {
+	if err := unmarshal(data, &base); err != nil {
 		return err
 	}
 
@@ -251,7 +371,7 @@ func (a *RecvAnswer) UnmarshalJSON(data []byte) error {
 		var answer struct {
 			Value *string `json:"value"`
 		}
-		if err := json.Unmarshal(data, &answer); err != nil {
+		if err := unmarshal(data, &answer); err != nil {
 			return err
 		}
 		a.Text = answer.Value
@@ -260,7 +380,7 @@ func (a *RecvAnswer) UnmarshalJSON(data []byte) error {
 		var answer struct {
 			Value *uint8 `json:"value"`
 		}
-		if err := json.Unmarshal(data, &answer); err != nil {
+		if err := unmarshal(data, &answer); err != nil {
 			return err
 		}
 		a.Option = answer.Value
@@ -323,12 +443,112 @@ func (m *MessagePollChoose) Validate(ctx context.Context) *valgo.Validation {
 	return f.New()
 }
 
-func (*MessageJoin) isRecvMessage()       {}
-func (*MessageReady) isRecvMessage()      {}
-func (*MessageKick) isRecvMessage()       {}
-func (*MessageLeave) isRecvMessage()      {}
-func (*MessageTaskAnswer) isRecvMessage() {}
-func (*MessagePollChoose) isRecvMessage() {}
+type MessageChat struct {
+	BaseMessage
+
+	Text *string `json:"text"`
+}
+
+func (m *MessageChat) Validate(ctx context.Context) *valgo.Validation {
+	return m.BaseMessage.Validate(ctx).
+		Is(valgo.StringP(m.Text, "text", "text").Not().Nil().Not().Blank().
+			MatchingTo(configuration.BaseTextReg).Passing(util.MaxLengthPChecker(configuration.MaxChatMessageLength))).
+		Is(valgo.StringP(m.Kind, "kind", "kind").EqualTo(MsgKindChat))
+}
+
+// Reactions lists the reactions a player can send.
+var Reactions = []string{"👍", "👎", "😂", "😮", "😢", "🔥", "👏", "❤️"}
+
+type MessageReact struct {
+	BaseMessage
+
+	Reaction *string `json:"reaction"`
+}
+
+func (m *MessageReact) Validate(ctx context.Context) *valgo.Validation {
+	return m.BaseMessage.Validate(ctx).
+		Is(valgo.StringP(m.Reaction, "reaction", "reaction").Not().Nil().InSlice(Reactions)).
+		Is(valgo.StringP(m.Kind, "kind", "kind").EqualTo(MsgKindReact))
+}
+
+type MessageMuteChat struct {
+	BaseMessage
+
+	Muted *bool `json:"muted"`
+}
+
+func (m *MessageMuteChat) Validate(ctx context.Context) *valgo.Validation {
+	return m.BaseMessage.Validate(ctx).
+		Is(validate.FieldValue(m.Muted, "muted", "muted").Set()).
+		Is(valgo.StringP(m.Kind, "kind", "kind").EqualTo(MsgKindMuteChat))
+}
+
+type MessageTransferOwner struct {
+	BaseMessage
+
+	PlayerID *uint32 `json:"player-id"`
+}
+
+func (m *MessageTransferOwner) Validate(ctx context.Context) *valgo.Validation {
+	return m.BaseMessage.Validate(ctx).
+		Is(validate.FieldValue(m.PlayerID, "player-id", "player-id").Set()).
+		Is(valgo.StringP(m.Kind, "kind", "kind").EqualTo(MsgKindTransferOwner))
+}
+
+type MessagePause struct {
+	BaseMessage
+}
+
+func (m *MessagePause) Validate(ctx context.Context) *valgo.Validation {
+	return m.BaseMessage.Validate(ctx).
+		Is(valgo.StringP(m.Kind, "kind", "kind").EqualTo(MsgKindPause))
+}
+
+type MessageResume struct {
+	BaseMessage
+}
+
+func (m *MessageResume) Validate(ctx context.Context) *valgo.Validation {
+	return m.BaseMessage.Validate(ctx).
+		Is(valgo.StringP(m.Kind, "kind", "kind").EqualTo(MsgKindResume))
+}
+
+type MessageSkipTask struct {
+	BaseMessage
+}
+
+func (m *MessageSkipTask) Validate(ctx context.Context) *valgo.Validation {
+	return m.BaseMessage.Validate(ctx).
+		Is(valgo.StringP(m.Kind, "kind", "kind").EqualTo(MsgKindSkipTask))
+}
+
+type MessageAddTime struct {
+	BaseMessage
+
+	Seconds *uint16 `json:"seconds"`
+}
+
+func (m *MessageAddTime) Validate(ctx context.Context) *valgo.Validation {
+	return m.BaseMessage.Validate(ctx).
+		Is(valgo.Uint16P(m.Seconds, "seconds", "seconds").Not().Nil().
+			Between(configuration.MinAddedSeconds, configuration.MaxAddedSeconds)).
+		Is(valgo.StringP(m.Kind, "kind", "kind").EqualTo(MsgKindAddTime))
+}
+
+func (*MessageJoin) isRecvMessage()          {}
+func (*MessageReady) isRecvMessage()         {}
+func (*MessageKick) isRecvMessage()          {}
+func (*MessageLeave) isRecvMessage()         {}
+func (*MessageTaskAnswer) isRecvMessage()    {}
+func (*MessagePollChoose) isRecvMessage()    {}
+func (*MessageChat) isRecvMessage()          {}
+func (*MessageReact) isRecvMessage()         {}
+func (*MessageMuteChat) isRecvMessage()      {}
+func (*MessageTransferOwner) isRecvMessage() {}
+func (*MessagePause) isRecvMessage()         {}
+func (*MessageResume) isRecvMessage()        {}
+func (*MessageSkipTask) isRecvMessage()      {}
+func (*MessageAddTime) isRecvMessage()       {}
 
 type UnknownMessageError struct {
 	refID MessageID
@@ -380,17 +600,17 @@ func (e *ValidationError) Unwrap() error {
 	return e.cause
 }
 
-// ParseMessage decodes and validates a protocol message.
+// ParseMessage decodes and validates a protocol message encoded with the codec.
 //
 // If the supplied data is invalid, returns one of the following errors:
 // - [DecodeError] if some an error has occurred during decoding
 // - [UnknownMessageError] if the message data specifies an unknown message kind
 // - [ValidationError] if validation fails
 // - or possibly some other error type.
-func ParseMessage(ctx context.Context, data []byte) (RecvMessage, error) {
+func ParseMessage(ctx context.Context, codec Codec, data []byte) (RecvMessage, error) {
 	var base BaseMessage
-	if err := json.Unmarshal(data, &base); err != nil {
-		return nil, err
+	if err := codec.Unmarshal(data, &base, false); err != nil {
+		return nil, &DecodeError{cause: err}
 	}
 	if val := base.Validate(ctx); !val.Valid() {
 		return nil, &ValidationError{
@@ -399,9 +619,6 @@ func ParseMessage(ctx context.Context, data []byte) (RecvMessage, error) {
 		}
 	}
 
-	decoder := json.NewDecoder(bytes.NewReader(data))
-	decoder.DisallowUnknownFields()
-
 	var msg RecvMessage
 
 	switch *base.Kind {
@@ -417,11 +634,27 @@ func ParseMessage(ctx context.Context, data []byte) (RecvMessage, error) {
 		msg = &MessageTaskAnswer{}
 	case MsgKindPollChoose:
 		msg = &MessagePollChoose{}
+	case MsgKindChat:
+		msg = &MessageChat{}
+	case MsgKindReact:
+		msg = &MessageReact{}
+	case MsgKindMuteChat:
+		msg = &MessageMuteChat{}
+	case MsgKindTransferOwner:
+		msg = &MessageTransferOwner{}
+	case MsgKindPause:
+		msg = &MessagePause{}
+	case MsgKindResume:
+		msg = &MessageResume{}
+	case MsgKindSkipTask:
+		msg = &MessageSkipTask{}
+	case MsgKindAddTime:
+		msg = &MessageAddTime{}
 	default:
 		return nil, &UnknownMessageError{kind: *base.Kind}
 	}
 
-	if err := decoder.Decode(msg); err != nil {
+	if err := codec.Unmarshal(data, msg, true); err != nil {
 		return nil, &DecodeError{cause: err}
 	}
 
@@ -455,12 +688,21 @@ func ParseErrorToMessageError(err error) error {
 		}
 	}
 
+	var cborTypeError *cbor.UnmarshalTypeError
+	if errors.As(err, &cborTypeError) {
+		return &Error{
+			RefID:   nil,
+			Code:    ErrMalformedMsg,
+			Message: fmt.Sprintf("in field `%s`: %s is not allowed here", cborTypeError.StructFieldName, cborTypeError.CBORType),
+		}
+	}
+
 	var decodeError *DecodeError
 	if errors.As(err, &decodeError) {
 		return &Error{
 			RefID:   nil,
 			Code:    ErrMalformedMsg,
-			Message: fmt.Sprintf("message is not valid JSON: %s", decodeError),
+			Message: fmt.Sprintf("message could not be decoded: %s", decodeError),
 		}
 	}
 
@@ -625,3 +867,67 @@ type MessageGameEnd struct {
 }
 
 func (*MessageGameEnd) isRespMessage() {}
+
+type ChatEntry struct {
+	PlayerID uint32 `json:"player-id"`
+	Text     string `json:"text"`
+	SentAt   Time   `json:"sent-at"`
+}
+
+type MessageChatMessage struct {
+	BaseMessage
+	ChatEntry
+}
+
+func (*MessageChatMessage) isRespMessage() {}
+
+type MessageReaction struct {
+	BaseMessage
+
+	PlayerID uint32 `json:"player-id"`
+	Reaction string `json:"reaction"`
+}
+
+func (*MessageReaction) isRespMessage() {}
+
+type MessageChatMuted struct {
+	BaseMessage
+
+	Muted bool `json:"muted"`
+}
+
+func (*MessageChatMuted) isRespMessage() {}
+
+// MessageChatHistory is sent right after MessageJoined.
+type MessageChatHistory struct {
+	BaseMessage
+
+	Muted    bool        `json:"muted"`
+	Messages []ChatEntry `json:"messages"`
+}
+
+func (*MessageChatHistory) isRespMessage() {}
+
+// MessageOwnerChanged announces the owner of the lobby.
+// It's also sent to a joining player after MessageWaiting.
+type MessageOwnerChanged struct {
+	BaseMessage
+
+	PlayerID uint32 `json:"player-id"`
+}
+
+func (*MessageOwnerChanged) isRespMessage() {}
+
+// MessageDeadlineChanged announces the new deadline after the owner has paused, resumed or extended the game.
+// It's also sent to a joining player after the state message if the game is paused.
+type MessageDeadlineChanged struct {
+	BaseMessage
+
+	Deadline Time `json:"deadline"`
+	Paused   bool `json:"paused"`
+
+	// RemainingMs is the time left until the deadline in milliseconds. Only set while the game is paused.
+	RemainingMs *uint64 `json:"remaining-ms,omitempty"`
+}
+
+func (*MessageDeadlineChanged) isRespMessage() {}
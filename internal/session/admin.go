@@ -0,0 +1,152 @@
+package session
+
+import (
+	"context"
+	"time"
+
+	"golang.org/x/exp/maps"
+)
+
+// # Administration
+//
+// The methods here are meant for server administrators and bypass the usual in-game privilege checks.
+
+// A SessionSummary is a short description of a live session.
+type SessionSummary struct {
+	ID          SessionID
+	State       string
+	GameName    string
+	PlayerCount int
+	PlayersMax  int
+	CreatedAt   time.Time
+}
+
+// A SessionDetails is a snapshot of a live session's full state.
+type SessionDetails struct {
+	SessionSummary
+
+	Owner ClientID
+
+	// InviteCode is only valid while the session awaits players.
+	InviteCode *InviteCode
+
+	// TaskIdx is only valid during a task, a poll or right after a task ends.
+	TaskIdx    *int
+	Deadline   time.Time
+	Players    []Player
+	Scoreboard Scoreboard
+
+	// Answers holds the current answers while a task is underway.
+	Answers map[PlayerID]TaskAnswer
+	Banned  []ClientID
+
+	// TaskSeed reproduces the order of the tasks and the ChoiceTask options.
+	TaskSeed int64
+}
+
+func (s *UnsafeStorage) sessionSummary(session *session) SessionSummary {
+	return SessionSummary{
+		ID:          session.id,
+		State:       StateName(session.state),
+		GameName:    session.game.Name,
+		PlayerCount: len(session.players),
+		PlayersMax:  session.playersMax,
+		CreatedAt:   session.createdAt,
+	}
+}
+
+// SessionSummaries returns a summary for every live session.
+func (s *UnsafeStorage) SessionSummaries() []SessionSummary {
+	summaries := make([]SessionSummary, 0, len(s.sessions))
+	for _, session := range s.sessions {
+		summaries = append(summaries, s.sessionSummary(session))
+	}
+	return summaries
+}
+
+// SessionDetails returns a snapshot of a session's state.
+func (s *UnsafeStorage) SessionDetails(sid SessionID) (details SessionDetails, err error) {
+	session := s.sessions[sid]
+	if session == nil {
+		return details, ErrNoSession
+	}
+
+	details = SessionDetails{
+		SessionSummary: s.sessionSummary(session),
+		Deadline:       session.state.Deadline(),
+		Players:        s.Players(sid),
+		Scoreboard:     session.scoreboard.Clone(),
+		Banned:         maps.Keys(session.bannedClients),
+		Owner:          session.owner,
+		TaskSeed:       session.taskSeed,
+	}
+
+	switch state := session.state.(type) {
+	case *AwaitingPlayersState:
+		code := state.inviteCode
+		details.InviteCode = &code
+
+	case *TaskStartedState:
+		taskIdx := state.taskIdx
+		details.TaskIdx = &taskIdx
+		details.Answers = maps.Clone(state.answers)
+
+	case *PollStartedState:
+		taskIdx := state.taskIdx
+		details.TaskIdx = &taskIdx
+
+	case *TaskEndedState:
+		taskIdx := state.taskIdx
+		details.TaskIdx = &taskIdx
+	}
+
+	return
+}
+
+// CloseSession forcibly closes a session.
+// The players are sent a ClosedByAdminError carrying the reason.
+func (m *Manager) CloseSession(ctx context.Context, sid SessionID, reason string) (err error) {
+	m.storage.Atomically(func(s *UnsafeStorage) {
+		if !s.SessionExists(sid) {
+			err = ErrNoSession
+		}
+	})
+
+	if err != nil {
+		return
+	}
+
+	m.sendToUpdater(sid, &updateMsgCloseSession{
+		ctx:    ctx,
+		reason: reason,
+	})
+
+	return
+}
+
+// KickPlayer removes a player from a session.
+// If ban is true, the player's client will not be able to join the session again.
+func (m *Manager) KickPlayer(ctx context.Context, sid SessionID, playerID PlayerID, ban bool) (err error) {
+	m.storage.Atomically(func(s *UnsafeStorage) {
+		if !s.SessionExists(sid) {
+			err = ErrNoSession
+			return
+		}
+		if !s.PlayerExists(sid, playerID) {
+			err = ErrNoPlayer
+			return
+		}
+	})
+
+	if err != nil {
+		return
+	}
+
+	m.sendToUpdater(sid, &updateMsgKickPlayer{
+		ctx:      ctx,
+		playerID: playerID,
+		ban:      ban,
+	})
+
+	return
+}
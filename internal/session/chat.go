@@ -0,0 +1,174 @@
+package session
+
+import (
+	"context"
+	"time"
+)
+
+// # Chat
+//
+// Chat messages and reactions are not a part of the game: they aren't kept for replay,
+// and only the last ChatHistorySize chat messages are remembered to be shown to the players who join later.
+
+// ChatHistorySize is the number of recent chat messages sent to a joining player.
+const ChatHistorySize = 20
+
+type ChatMessage struct {
+	PlayerID PlayerID
+	Text     string
+	SentAt   time.Time
+}
+
+// appendChatMessage adds the message to the chat history, evicting the oldest one if it's full.
+func (s *UnsafeStorage) appendChatMessage(sid SessionID, msg ChatMessage) {
+	session := s.sessions[sid]
+	if session == nil {
+		return
+	}
+
+	if len(session.chatHistory) >= ChatHistorySize {
+		session.chatHistory = append(session.chatHistory[:0], session.chatHistory[1:]...)
+	}
+	session.chatHistory = append(session.chatHistory, msg)
+}
+
+// ChatHistory returns a copy of the recent chat messages, oldest first.
+func (s *UnsafeStorage) ChatHistory(sid SessionID) []ChatMessage {
+	if session := s.sessions[sid]; session != nil {
+		return append([]ChatMessage(nil), session.chatHistory...)
+	}
+	return nil
+}
+
+// ChatMuted returns true if the owner has muted the chat.
+func (s *UnsafeStorage) ChatMuted(sid SessionID) bool {
+	if session := s.sessions[sid]; session != nil {
+		return session.chatMuted
+	}
+	return false
+}
+
+func (s *UnsafeStorage) setChatMuted(sid SessionID, muted bool) {
+	if session := s.sessions[sid]; session != nil {
+		session.chatMuted = muted
+	}
+}
+
+// SendChatMessage sends a chat message to every player in the session.
+func (m *Manager) SendChatMessage(ctx context.Context, sid SessionID, playerID PlayerID, text string) (err error) {
+	m.storage.Atomically(func(s *UnsafeStorage) {
+		if !s.SessionExists(sid) {
+			err = ErrNoSession
+			return
+		}
+		if !s.PlayerExists(sid, playerID) {
+			err = ErrNoPlayer
+			return
+		}
+		if s.ChatMuted(sid) {
+			err = ErrChatMuted
+			return
+		}
+	})
+
+	if err != nil {
+		return
+	}
+
+	m.sendToUpdater(sid, &updateMsgChat{
+		ctx:      ctx,
+		playerID: playerID,
+		text:     text,
+	})
+
+	return
+}
+
+// SendReaction sends a reaction to every player in the session.
+func (m *Manager) SendReaction(ctx context.Context, sid SessionID, playerID PlayerID, reaction string) (err error) {
+	m.storage.Atomically(func(s *UnsafeStorage) {
+		if !s.SessionExists(sid) {
+			err = ErrNoSession
+			return
+		}
+		if !s.PlayerExists(sid, playerID) {
+			err = ErrNoPlayer
+			return
+		}
+	})
+
+	if err != nil {
+		return
+	}
+
+	m.sendToUpdater(sid, &updateMsgReaction{
+		ctx:      ctx,
+		playerID: playerID,
+		reaction: reaction,
+	})
+
+	return
+}
+
+// MuteChat mutes or unmutes the session chat. Only the owner may do this.
+func (m *Manager) MuteChat(ctx context.Context, sid SessionID, playerID PlayerID, muted bool) (err error) {
+	m.storage.Atomically(func(s *UnsafeStorage) {
+		if !s.SessionExists(sid) {
+			err = ErrNoSession
+			return
+		}
+		if !s.PlayerExists(sid, playerID) {
+			err = ErrNoPlayer
+			return
+		}
+		if !s.isOwner(sid, playerID) {
+			err = ErrOwnerOnly
+			return
+		}
+	})
+
+	if err != nil {
+		return
+	}
+
+	m.sendToUpdater(sid, &updateMsgMuteChat{
+		ctx:   ctx,
+		muted: muted,
+	})
+
+	return
+}
+
+func (u *sessionUpdater) chatMessage(msgCtx context.Context, s *UnsafeStorage, playerID PlayerID, text string) {
+	player, err := s.PlayerByID(u.sid, playerID)
+	if err != nil {
+		return
+	}
+	if s.ChatMuted(u.sid) {
+		// the chat was muted after the message had been accepted
+		u.m.sendToPlayer(player.tx, u.m.makeMsgError(msgCtx, ErrChatMuted))
+		return
+	}
+
+	msg := ChatMessage{PlayerID: playerID, Text: text, SentAt: time.Now()}
+	s.appendChatMessage(u.sid, msg)
+	u.m.sendToAllPlayersUnlogged(s, u.sid, u.m.makeMsgChat(msgCtx, msg))
+}
+
+func (u *sessionUpdater) reaction(msgCtx context.Context, s *UnsafeStorage, playerID PlayerID, reaction string) {
+	if !s.PlayerExists(u.sid, playerID) {
+		return
+	}
+
+	u.m.sendToAllPlayersUnlogged(s, u.sid, u.m.makeMsgReaction(msgCtx, playerID, reaction))
+}
+
+func (u *sessionUpdater) muteChat(msgCtx context.Context, s *UnsafeStorage, muted bool) {
+	if s.ChatMuted(u.sid) == muted {
+		return
+	}
+
+	u.log.Info("chat muted by the owner", "muted", muted)
+	s.setChatMuted(u.sid, muted)
+	u.m.sendToAllPlayersUnlogged(s, u.sid, u.m.makeMsgChatMuted(msgCtx, muted))
+}
@@ -0,0 +1,22 @@
+package session
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestChatHistoryBounded(t *testing.T) {
+	s, sid, playerID := newTestStorageWithPlayer(t)
+
+	for i := 0; i < ChatHistorySize+5; i++ {
+		s.appendChatMessage(sid, ChatMessage{PlayerID: playerID, Text: fmt.Sprint(i)})
+	}
+
+	history := s.ChatHistory(sid)
+	if len(history) != ChatHistorySize {
+		t.Fatalf("expected %d messages, got %d", ChatHistorySize, len(history))
+	}
+	if history[0].Text != "5" || history[len(history)-1].Text != fmt.Sprint(ChatHistorySize+4) {
+		t.Errorf("expected the most recent messages oldest first, got %q..%q", history[0].Text, history[len(history)-1].Text)
+	}
+}
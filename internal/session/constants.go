@@ -2,10 +2,11 @@ package session
 
 import "time"
 
-var (
-	NoOwnerTimeout     = 5 * time.Minute
-	GameStartedTimeout = 5 * time.Second
-	TaskEndTimeout     = 10 * time.Second
+// The timings used unless the owner chooses otherwise. See Timings.
+const (
+	DefaultNoOwnerTimeout     = 5 * time.Minute
+	DefaultGameStartedTimeout = 5 * time.Second
+	DefaultTaskEndTimeout     = 10 * time.Second
 )
 
 // How many points players gain for correctly answering questions.
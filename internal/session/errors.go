@@ -1,6 +1,9 @@
 package session
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+)
 
 // NOTE: if you're adding an error here, don't forget to fix the switch in ws/converters/error
 
@@ -8,8 +11,27 @@ var (
 	ErrNoOwnerTimeout = errors.New("timed out waiting for the owner to join")
 	ErrReconnected    = errors.New("client joined the session from another connection")
 	ErrOwnerLeft      = errors.New("owner left the session")
+	ErrKicked         = errors.New("player was kicked from the session")
+	ErrInactivity     = errors.New("player was removed from the session for inactivity")
 )
 
+// ErrInactivityWarning is sent to a player who is about to be removed for inactivity.
+// Unlike other errors, it does not end the player's participation.
+var ErrInactivityWarning = errors.New("player will be removed from the session for inactivity")
+
+// ErrJoinedMidTask is sent to a player who tries to answer the task that was underway when they joined.
+// Like ErrInactivityWarning, it does not end the player's participation.
+var ErrJoinedMidTask = errors.New("player joined after the task had started")
+
+// A ClosedByAdminError is sent to players when an administrator closes the session.
+type ClosedByAdminError struct {
+	Reason string
+}
+
+func (e *ClosedByAdminError) Error() string {
+	return fmt.Sprintf("session closed by an administrator: %s", e.Reason)
+}
+
 var (
 	ErrNoSession      = errors.New("no such session")
 	ErrGameInProgress = errors.New("game has already started")
@@ -23,6 +45,14 @@ var (
 	ErrNoPlayer = errors.New("no player with such id")
 )
 
+var (
+	ErrOwnerOnly      = errors.New("only the owner may do this")
+	ErrChatMuted      = errors.New("chat is muted by the owner")
+	ErrNoTargetPlayer = errors.New("no player with such id to perform the action on")
+	ErrTargetLegacy   = errors.New("the player's client does not support the action")
+	ErrLegacyPlayers  = errors.New("some players' clients do not support the action")
+)
+
 var (
 	ErrTaskNotStartedYet          = errors.New("task hasn't been started yet")
 	ErrTypesTaskAndAnswerMismatch = errors.New("answer type cannot be used with this task")
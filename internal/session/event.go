@@ -12,17 +12,32 @@ type TxChan chan<- ServerTx
 // Once the message is sent, its fields must not be updated.
 type ServerTx interface {
 	Context() context.Context
+
+	// Seq returns the session-scoped sequence number of the message.
+	// Only the messages kept for replay after a reconnect have one; for others it's 0.
+	Seq() uint64
+
+	setSeq(seq uint64)
 	isServerTx()
 }
 
 type baseTx struct {
 	Ctx context.Context
+	seq uint64
 }
 
 func (m *baseTx) Context() context.Context {
 	return m.Ctx
 }
 
+func (m *baseTx) Seq() uint64 {
+	return m.seq
+}
+
+func (m *baseTx) setSeq(seq uint64) {
+	m.seq = seq
+}
+
 type MsgError struct {
 	baseTx
 
@@ -39,6 +54,10 @@ type MsgJoined struct {
 	InviteCode *InviteCode
 	Game       *Game
 	MaxPlayers int
+
+	// State is the session state at the moment the player joined.
+	// Its fields must not be accessed: only its type is meaningful.
+	State State
 }
 
 func (*MsgJoined) isServerTx() {}
@@ -60,7 +79,8 @@ type MsgTaskStart struct {
 	// Options must be only for ChoiceTask otherwise must be nil
 	Options *[]string
 
-	// ImgID must be only for PhotoTask otherwise must be nil
+	// ImgID must be only for PhotoTask otherwise must be nil.
+	// It's nil for the players who have joined during the PhotoTask, too.
 	ImgID *ImageID
 }
 
@@ -103,3 +123,59 @@ type MsgGameEnd struct {
 }
 
 func (*MsgGameEnd) isServerTx() {}
+
+type MsgChat struct {
+	baseTx
+
+	ChatMessage
+}
+
+func (*MsgChat) isServerTx() {}
+
+type MsgReaction struct {
+	baseTx
+
+	PlayerID PlayerID
+	Reaction string
+}
+
+func (*MsgReaction) isServerTx() {}
+
+type MsgChatMuted struct {
+	baseTx
+
+	Muted bool
+}
+
+func (*MsgChatMuted) isServerTx() {}
+
+// MsgChatHistory is sent to a joining player.
+type MsgChatHistory struct {
+	baseTx
+
+	Muted    bool
+	Messages []ChatMessage
+}
+
+func (*MsgChatHistory) isServerTx() {}
+
+// MsgOwnerChanged announces the owner of the lobby.
+type MsgOwnerChanged struct {
+	baseTx
+
+	PlayerID PlayerID
+}
+
+func (*MsgOwnerChanged) isServerTx() {}
+
+// MsgDeadlineChanged announces the new deadline after the owner has paused, resumed or extended the game.
+// While the game is paused, Remaining is the time left until the deadline.
+type MsgDeadlineChanged struct {
+	baseTx
+
+	Deadline  time.Time
+	Paused    bool
+	Remaining time.Duration
+}
+
+func (*MsgDeadlineChanged) isServerTx() {}
@@ -0,0 +1,216 @@
+package session
+
+import (
+	"context"
+	"time"
+)
+
+// # Host controls
+//
+// The owner can pause and resume the game, skip the current task or poll, and give the players more time.
+// Every change of the deadline is announced to all players with MsgDeadlineChanged.
+// A state change (e.g. when everyone is ready before the deadline) resumes a paused game.
+//
+// The legacy players (see Player) don't know about MsgDeadlineChanged and would go on counting down to the old deadline.
+// So the game can't be paused nor given more time while any of them is present,
+// and a legacy player joining a paused game resumes it.
+
+// Paused returns true if the owner has paused the game.
+func (s *UnsafeStorage) Paused(sid SessionID) bool {
+	if session := s.sessions[sid]; session != nil {
+		return session.paused
+	}
+	return false
+}
+
+// Remaining returns the time left until the deadline of a paused game.
+func (s *UnsafeStorage) Remaining(sid SessionID) time.Duration {
+	if session := s.sessions[sid]; session != nil {
+		return session.remaining
+	}
+	return 0
+}
+
+func (s *UnsafeStorage) setPaused(sid SessionID, remaining time.Duration) {
+	if session := s.sessions[sid]; session != nil {
+		session.paused = true
+		session.remaining = remaining
+	}
+}
+
+func (s *UnsafeStorage) clearPause(sid SessionID) {
+	if session := s.sessions[sid]; session != nil {
+		session.paused = false
+		session.remaining = 0
+	}
+}
+
+// hostControllable returns true if the host controls apply to the session state: that is, the game is underway.
+func hostControllable(state State) bool {
+	switch state.(type) {
+	case *GameStartedState, *TaskStartedState, *PollStartedState, *TaskEndedState:
+		return true
+	default:
+		return false
+	}
+}
+
+// checkHostControl checks that the player may use the host controls.
+// The session state is checked by the updater: the requests that come too early or too late are ignored.
+func (s *UnsafeStorage) checkHostControl(sid SessionID, playerID PlayerID) error {
+	if !s.SessionExists(sid) {
+		return ErrNoSession
+	}
+	if !s.PlayerExists(sid, playerID) {
+		return ErrNoPlayer
+	}
+	if !s.isOwner(sid, playerID) {
+		return ErrOwnerOnly
+	}
+	return nil
+}
+
+// PauseGame pauses or resumes the game. Only the owner may do this.
+func (m *Manager) PauseGame(ctx context.Context, sid SessionID, playerID PlayerID, paused bool) (err error) {
+	m.storage.Atomically(func(s *UnsafeStorage) {
+		if err = s.checkHostControl(sid, playerID); err == nil && paused && s.legacyPlayersPresent(sid) {
+			err = ErrLegacyPlayers
+		}
+	})
+
+	if err != nil {
+		return
+	}
+
+	m.sendToUpdater(sid, &updateMsgPause{
+		ctx:      ctx,
+		playerID: playerID,
+		paused:   paused,
+	})
+
+	return
+}
+
+// SkipTask ends the current task or poll right away. Only the owner may do this.
+func (m *Manager) SkipTask(ctx context.Context, sid SessionID, playerID PlayerID) (err error) {
+	m.storage.Atomically(func(s *UnsafeStorage) {
+		err = s.checkHostControl(sid, playerID)
+	})
+
+	if err != nil {
+		return
+	}
+
+	m.sendToUpdater(sid, &updateMsgSkipTask{
+		ctx:      ctx,
+		playerID: playerID,
+	})
+
+	return
+}
+
+// AddTime moves the current deadline d later. Only the owner may do this.
+func (m *Manager) AddTime(ctx context.Context, sid SessionID, playerID PlayerID, d time.Duration) (err error) {
+	m.storage.Atomically(func(s *UnsafeStorage) {
+		if err = s.checkHostControl(sid, playerID); err == nil && s.legacyPlayersPresent(sid) {
+			err = ErrLegacyPlayers
+		}
+	})
+
+	if err != nil {
+		return
+	}
+
+	m.sendToUpdater(sid, &updateMsgAddTime{
+		ctx:      ctx,
+		playerID: playerID,
+		d:        d,
+	})
+
+	return
+}
+
+func (u *sessionUpdater) makeMsgDeadlineChanged(msgCtx context.Context, s *UnsafeStorage) ServerTx {
+	return u.m.makeMsgDeadlineChanged(
+		msgCtx,
+		s.sessionState(u.sid).Deadline(),
+		s.Paused(u.sid),
+		s.Remaining(u.sid),
+	)
+}
+
+// hostControlAllowed re-checks the request: the owner might have changed since it was accepted.
+func (u *sessionUpdater) hostControlAllowed(s *UnsafeStorage, playerID PlayerID) bool {
+	return s.isOwner(u.sid, playerID) && hostControllable(s.sessionState(u.sid))
+}
+
+func (u *sessionUpdater) pause(msgCtx context.Context, s *UnsafeStorage, playerID PlayerID, paused bool) {
+	if !u.hostControlAllowed(s, playerID) || s.Paused(u.sid) == paused {
+		return
+	}
+
+	if !paused {
+		u.log.Info("the game is resumed by the owner")
+		u.resume(msgCtx, s)
+		return
+	}
+
+	// a legacy player might have joined since the request was accepted
+	if s.legacyPlayersPresent(u.sid) {
+		return
+	}
+
+	state := s.sessionState(u.sid)
+	remaining := max(time.Until(state.Deadline()), 0)
+	u.log.Info("the game is paused by the owner", "remaining", remaining)
+	u.deadline.Stop()
+	s.setPaused(u.sid, remaining)
+
+	u.m.sendToAllPlayers(s, u.sid, u.makeMsgDeadlineChanged(msgCtx, s))
+}
+
+// resume resumes a paused game with the time that was left when it was paused.
+func (u *sessionUpdater) resume(msgCtx context.Context, s *UnsafeStorage) {
+	remaining := s.Remaining(u.sid)
+	u.log.Info("resuming the game", "remaining", remaining)
+	s.sessionState(u.sid).setDeadline(time.Now().Add(remaining))
+	s.clearPause(u.sid)
+	u.deadline.Reset(remaining)
+
+	u.m.sendToAllPlayers(s, u.sid, u.makeMsgDeadlineChanged(msgCtx, s))
+}
+
+func (u *sessionUpdater) skipTask(ctx context.Context, msgCtx context.Context, s *UnsafeStorage, playerID PlayerID) {
+	if !u.hostControlAllowed(s, playerID) {
+		return
+	}
+
+	switch state := s.sessionState(u.sid).(type) {
+	case *TaskStartedState:
+		u.log.Info("the task is skipped by the owner", "task_idx", state.taskIdx)
+		u.finishTask(ctx, msgCtx, s, state, true)
+
+	case *PollStartedState:
+		u.log.Info("the poll is skipped by the owner", "task_idx", state.taskIdx)
+		u.changeStateTo(ctx, msgCtx, s, u.makePollTaskEndedState(s, state))
+	}
+}
+
+func (u *sessionUpdater) addTime(msgCtx context.Context, s *UnsafeStorage, playerID PlayerID, d time.Duration) {
+	if !u.hostControlAllowed(s, playerID) || s.legacyPlayersPresent(u.sid) {
+		return
+	}
+
+	u.log.Info("the owner has added time", "added", d)
+	state := s.sessionState(u.sid)
+	state.setDeadline(state.Deadline().Add(d))
+
+	if s.Paused(u.sid) {
+		s.setPaused(u.sid, s.Remaining(u.sid)+d)
+	} else {
+		u.deadline.Stop()
+		u.deadline.Reset(time.Until(state.Deadline()))
+	}
+
+	u.m.sendToAllPlayers(s, u.sid, u.makeMsgDeadlineChanged(msgCtx, s))
+}
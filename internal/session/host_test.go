@@ -0,0 +1,145 @@
+package session
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func TestPauseAndAddTime(t *testing.T) {
+	s, sid, playerID := newTestStorageWithPlayer(t)
+	s.setOwner(sid, ClientID{1})
+
+	state := &TaskEndedState{deadline: time.Now().Add(10 * time.Second)}
+	s.setSessionState(sid, state)
+
+	u := &sessionUpdater{
+		m:        &Manager{},
+		sid:      sid,
+		log:      slog.Default(),
+		deadline: time.NewTimer(time.Hour),
+	}
+	defer u.deadline.Stop()
+	ctx := context.Background()
+
+	u.pause(ctx, &s, playerID, true)
+	if !s.Paused(sid) {
+		t.Fatal("the game should be paused")
+	}
+	if remaining := s.Remaining(sid); remaining <= 9*time.Second || remaining > 10*time.Second {
+		t.Errorf("expected about 10s remaining, got %v", remaining)
+	}
+
+	u.addTime(ctx, &s, playerID, 5*time.Second)
+	if remaining := s.Remaining(sid); remaining <= 14*time.Second || remaining > 15*time.Second {
+		t.Errorf("expected about 15s remaining after adding time, got %v", remaining)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	u.pause(ctx, &s, playerID, false)
+	if s.Paused(sid) {
+		t.Fatal("the game should be resumed")
+	}
+	if left := time.Until(state.Deadline()); left <= 14*time.Second || left > 15*time.Second {
+		t.Errorf("expected the deadline in about 15s after resuming, got %v", left)
+	}
+
+	other, err := s.addPlayer(sid, ClientID{2}, "other", nil)
+	if err != nil {
+		t.Fatalf("could not add a player: %v", err)
+	}
+	u.pause(ctx, &s, other.ID, true)
+	if s.Paused(sid) {
+		t.Error("only the owner may pause the game")
+	}
+}
+
+func TestSkipTaskNotIdle(t *testing.T) {
+	s, sid, owner := newTestStorageWithPlayer(t)
+	s.setOwner(sid, ClientID{1})
+	s.sessions[sid].game.Tasks = []Task{
+		CheckedTextTask{BaseTask: BaseTask{Name: "text", TaskDuration: time.Minute}, Answer: "A"},
+	}
+	idle, err := s.addPlayer(sid, ClientID{2}, "idle", nil)
+	if err != nil {
+		t.Fatalf("could not add a player: %v", err)
+	}
+
+	u := &sessionUpdater{
+		m:        &Manager{idle: IdlePolicy{RemoveAfter: 1}},
+		sid:      sid,
+		log:      slog.Default(),
+		deadline: time.NewTimer(time.Hour),
+	}
+	defer u.deadline.Stop()
+	ctx := context.Background()
+
+	startTask := func() *TaskStartedState {
+		state := &TaskStartedState{
+			deadline:   time.Now().Add(time.Minute),
+			answers:    make(map[PlayerID]TaskAnswer),
+			ready:      make(map[PlayerID]struct{}),
+			active:     map[PlayerID]struct{}{owner: {}},
+			latecomers: make(map[PlayerID]struct{}),
+		}
+		s.setSessionState(sid, state)
+		return state
+	}
+
+	for i := 0; i < 3; i++ {
+		startTask()
+		u.skipTask(ctx, ctx, &s, owner)
+	}
+	if !s.PlayerExists(sid, idle.ID) {
+		t.Fatal("a player was removed as idle after the owner skipped the tasks")
+	}
+
+	u.finishTask(ctx, ctx, &s, startTask(), false)
+	if s.PlayerExists(sid, idle.ID) {
+		t.Error("an idle player was not removed after the task ended")
+	}
+}
+
+func TestHostControlsWithLegacyPlayers(t *testing.T) {
+	s, sid, owner := newTestStorageWithPlayer(t)
+	s.setOwner(sid, ClientID{1})
+
+	state := &GameStartedState{deadline: time.Now().Add(10 * time.Second)}
+	s.setSessionState(sid, state)
+
+	u := &sessionUpdater{
+		m:        &Manager{},
+		sid:      sid,
+		log:      slog.Default(),
+		deadline: time.NewTimer(time.Hour),
+	}
+	defer u.deadline.Stop()
+	ctx := context.Background()
+
+	u.pause(ctx, &s, owner, true)
+	if !s.Paused(sid) {
+		t.Fatal("the game should be paused")
+	}
+
+	legacy, err := s.addPlayer(sid, ClientID{2}, "legacy", nil)
+	if err != nil {
+		t.Fatalf("could not add a player: %v", err)
+	}
+	s.setPlayerLegacy(sid, legacy.ID, true)
+	u.playerAdded(ctx, ctx, &s, legacy.ID, false, nil)
+	if s.Paused(sid) {
+		t.Fatal("a legacy player joining a paused game should resume it")
+	}
+
+	u.pause(ctx, &s, owner, true)
+	if s.Paused(sid) {
+		t.Error("the game was paused with a legacy player present")
+	}
+
+	deadline := state.Deadline()
+	u.addTime(ctx, &s, owner, 5*time.Second)
+	if !state.Deadline().Equal(deadline) {
+		t.Error("time was added with a legacy player present")
+	}
+}
@@ -0,0 +1,59 @@
+package session
+
+import (
+	"context"
+
+	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
+)
+
+// An IdlePolicy tells what happens to players who send nothing during several tasks in a row.
+//
+// A player is warned after WarnAfter idle tasks and removed from the session after RemoveAfter idle tasks.
+// A zero value disables the corresponding action.
+type IdlePolicy struct {
+	WarnAfter   int
+	RemoveAfter int
+}
+
+var DefaultIdlePolicy = IdlePolicy{
+	WarnAfter:   2,
+	RemoveAfter: 3,
+}
+
+// handleIdlePlayers warns or removes the idle players according to the manager's IdlePolicy.
+// The idle map holds the number of consecutive idle tasks for each idle player.
+func (u *sessionUpdater) handleIdlePlayers(
+	ctx context.Context,
+	msgCtx context.Context,
+	s *UnsafeStorage,
+	idle map[PlayerID]int,
+) {
+	policy := u.m.idle
+
+	playerIDs := maps.Keys(idle)
+	slices.Sort(playerIDs)
+
+	for _, playerID := range playerIDs {
+		if !s.SessionExists(u.sid) {
+			// removing the last player closes the session
+			return
+		}
+
+		player, err := s.PlayerByID(u.sid, playerID)
+		if err != nil {
+			continue
+		}
+
+		switch count := idle[playerID]; {
+		case policy.RemoveAfter > 0 && count >= policy.RemoveAfter:
+			u.log.Info("removing an idle player", "player_id", playerID, "idle_tasks", count)
+			u.m.sendToPlayer(player.tx, u.m.makeMsgError(msgCtx, ErrInactivity))
+			u.removePlayer(ctx, msgCtx, s, playerID)
+
+		case policy.WarnAfter > 0 && count == policy.WarnAfter:
+			u.log.Info("warning an idle player", "player_id", playerID, "idle_tasks", count)
+			u.m.sendToPlayer(player.tx, u.m.makeMsgError(msgCtx, ErrInactivityWarning))
+		}
+	}
+}
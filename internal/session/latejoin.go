@@ -0,0 +1,73 @@
+package session
+
+import "context"
+
+// # Late join
+//
+// If the owner allows it, players can join a game already in progress.
+// They get the same snapshot of the session state as a reconnecting player.
+// A player joining while a task is underway sits it out and plays from the next task on.
+
+// A LateJoinScore tells what score the late joiners start with.
+type LateJoinScore int
+
+const (
+	// LateJoinZeroScore makes late joiners start from scratch.
+	LateJoinZeroScore LateJoinScore = iota
+
+	// LateJoinMinScore gives late joiners the lowest score among the players.
+	LateJoinMinScore
+)
+
+// LateJoinAllowed returns true if players may join the session after the game has started.
+func (s *UnsafeStorage) LateJoinAllowed(sid SessionID) bool {
+	if session := s.sessions[sid]; session != nil {
+		return session.lateJoin
+	}
+	return false
+}
+
+// setLateJoinScore sets the initial score of a player who has joined a game in progress.
+func (s *UnsafeStorage) setLateJoinScore(sid SessionID, playerID PlayerID) {
+	session := s.sessions[sid]
+	if session == nil || session.lateJoinScore != LateJoinMinScore {
+		return
+	}
+
+	var minScore Score
+	found := false
+	for id, score := range session.scoreboard {
+		if id == playerID {
+			continue
+		}
+		if !found || score < minScore {
+			minScore, found = score, true
+		}
+	}
+
+	if _, ok := session.scoreboard[playerID]; ok {
+		session.scoreboard[playerID] = minScore
+	}
+}
+
+// isLatecomer returns true if the player has joined after the task had started.
+func (s *TaskStartedState) isLatecomer(playerID PlayerID) bool {
+	_, ok := s.latecomers[playerID]
+	return ok
+}
+
+// lateJoined lets a player who has joined a game in progress sit out the current task.
+func (u *sessionUpdater) lateJoined(s *UnsafeStorage, player Player) {
+	u.log.Info("the player has joined a game in progress", "player_id", player.ID, "client_id", player.ClientID)
+
+	if state, ok := s.sessionState(u.sid).(*TaskStartedState); ok {
+		state.latecomers[player.ID] = struct{}{}
+		// they can't answer, which doesn't make them idle
+		state.active[player.ID] = struct{}{}
+	}
+}
+
+// rejectLatecomerAnswer tells a latecomer that the current task is not theirs to answer.
+func (u *sessionUpdater) rejectLatecomerAnswer(msgCtx context.Context, player Player) {
+	u.m.sendToPlayer(player.tx, u.m.makeMsgError(msgCtx, ErrJoinedMidTask))
+}
@@ -0,0 +1,95 @@
+package session
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func TestLateJoinScore(t *testing.T) {
+	for _, tc := range []struct {
+		policy LateJoinScore
+		want   Score
+	}{
+		{LateJoinZeroScore, 0},
+		{LateJoinMinScore, 3},
+	} {
+		s := NewUnsafeStorage()
+		opts := SessionOptions{LateJoin: true, LateJoinScore: tc.policy}
+		sid, _, _, err := s.newSession(&Game{}, ClientID{}, false, 8, time.Now(), opts)
+		if err != nil {
+			t.Fatalf("could not create a session: %v", err)
+		}
+
+		for i, score := range []Score{5, 3} {
+			player, err := s.addPlayer(sid, ClientID{byte(i + 1)}, "player", nil)
+			if err != nil {
+				t.Fatalf("could not add a player: %v", err)
+			}
+			s.incrementScores(sid, map[PlayerID]Score{player.ID: score})
+		}
+
+		latecomer, err := s.addPlayer(sid, ClientID{9}, "latecomer", nil)
+		if err != nil {
+			t.Fatalf("could not add a player: %v", err)
+		}
+		s.setLateJoinScore(sid, latecomer.ID)
+
+		if got := s.SessionScoreboard(sid)[latecomer.ID]; got != tc.want {
+			t.Errorf("policy %v: expected the latecomer to start with %v, got %v", tc.policy, tc.want, got)
+		}
+	}
+}
+
+func TestLateJoinDuringPhotoTask(t *testing.T) {
+	s := NewUnsafeStorage()
+	game := &Game{Tasks: []Task{PhotoTask{BaseTask: BaseTask{Name: "photo", TaskDuration: time.Minute}}}}
+	opts := SessionOptions{LateJoin: true}
+	sid, _, _, err := s.newSession(game, ClientID{1}, false, 8, time.Now(), opts)
+	if err != nil {
+		t.Fatalf("could not create a session: %v", err)
+	}
+
+	owner, err := s.addPlayer(sid, ClientID{1}, "owner", nil)
+	if err != nil {
+		t.Fatalf("could not add a player: %v", err)
+	}
+	s.setSessionState(sid, &TaskStartedState{
+		deadline:   time.Now().Add(time.Minute),
+		answers:    map[PlayerID]TaskAnswer{owner.ID: PhotoTaskAnswer(ImageID{})},
+		ready:      make(map[PlayerID]struct{}),
+		active:     make(map[PlayerID]struct{}),
+		latecomers: make(map[PlayerID]struct{}),
+	})
+
+	u := &sessionUpdater{
+		m:        &Manager{},
+		sid:      sid,
+		log:      slog.Default(),
+		deadline: time.NewTimer(time.Hour),
+	}
+	defer u.deadline.Stop()
+	ctx := context.Background()
+
+	rx := make(chan ServerTx, 16)
+	latecomer, err := s.addPlayer(sid, ClientID{2}, "latecomer", rx)
+	if err != nil {
+		t.Fatalf("could not add a player: %v", err)
+	}
+	u.playerAdded(ctx, ctx, &s, latecomer.ID, false, nil)
+	close(rx)
+
+	var taskStart *MsgTaskStart
+	for msg := range rx {
+		if msg, ok := msg.(*MsgTaskStart); ok {
+			taskStart = msg
+		}
+	}
+	if taskStart == nil {
+		t.Fatal("the latecomer did not get the task")
+	}
+	if taskStart.ImgID != nil {
+		t.Errorf("the latecomer got an image to upload: %v", *taskStart.ImgID)
+	}
+}
@@ -0,0 +1,77 @@
+package session
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/exp/slices"
+)
+
+// # Lobby browser
+//
+// The sessions their owners have chosen to list can be found and joined without an invite code.
+
+// A Lobby is a listed session that is awaiting players and has a free slot.
+type Lobby struct {
+	ID          SessionID
+	GameID      uuid.NullUUID
+	GameName    string
+	PlayerCount int
+	PlayersMax  int
+	CreatedAt   time.Time
+}
+
+// joinable reports whether the client can join the session from the lobby browser.
+func (s *UnsafeStorage) joinable(session *session, clientID ClientID) bool {
+	if !session.listed {
+		return false
+	}
+	if _, ok := session.state.(*AwaitingPlayersState); !ok {
+		return false
+	}
+	if _, banned := session.bannedClients[clientID]; banned {
+		return false
+	}
+	return len(session.players) < session.playersMax
+}
+
+// Lobbies returns the lobbies the client can join.
+// The most populated lobbies come first; among equally populated ones the oldest come first.
+func (s *UnsafeStorage) Lobbies(clientID ClientID) []Lobby {
+	lobbies := make([]Lobby, 0)
+	for _, session := range s.sessions {
+		if !s.joinable(session, clientID) {
+			continue
+		}
+
+		lobbies = append(lobbies, Lobby{
+			ID:          session.id,
+			GameID:      session.game.ID,
+			GameName:    session.game.Name,
+			PlayerCount: len(session.players),
+			PlayersMax:  session.playersMax,
+			CreatedAt:   session.createdAt,
+		})
+	}
+
+	slices.SortFunc(lobbies, func(a, b Lobby) int {
+		if a.PlayerCount != b.PlayerCount {
+			return b.PlayerCount - a.PlayerCount
+		}
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+
+	return lobbies
+}
+
+// PickLobby chooses the lobby the client should be sent to by quick-join.
+// If gameID is valid, only the lobbies playing that game are considered.
+// Filling up the existing lobbies is preferred, so the most populated lobby is picked.
+func (s *UnsafeStorage) PickLobby(clientID ClientID, gameID uuid.NullUUID) (lobby Lobby, ok bool) {
+	for _, lobby = range s.Lobbies(clientID) {
+		if !gameID.Valid || lobby.GameID == gameID {
+			return lobby, true
+		}
+	}
+	return Lobby{}, false
+}
@@ -0,0 +1,55 @@
+package session
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func newTestLobby(t *testing.T, s *UnsafeStorage, gameID uuid.NullUUID, listed bool, playersMax, players int) SessionID {
+	t.Helper()
+
+	sid, _, _, err := s.newSession(&Game{ID: gameID}, ClientID{}, false, playersMax, time.Now(), SessionOptions{Listed: listed})
+	if err != nil {
+		t.Fatalf("could not create a session: %v", err)
+	}
+	for i := 0; i < players; i++ {
+		if _, err = s.addPlayer(sid, ClientID{byte(i + 1)}, "player", nil); err != nil {
+			t.Fatalf("could not add a player: %v", err)
+		}
+	}
+
+	return sid
+}
+
+func TestLobbies(t *testing.T) {
+	s := NewUnsafeStorage()
+	game := uuid.NullUUID{UUID: uuid.New(), Valid: true}
+	other := uuid.NullUUID{UUID: uuid.New(), Valid: true}
+
+	newTestLobby(t, &s, game, false, 8, 1)
+	newTestLobby(t, &s, game, true, 2, 2)
+	emptier := newTestLobby(t, &s, game, true, 8, 1)
+	fuller := newTestLobby(t, &s, other, true, 8, 3)
+	banned := newTestLobby(t, &s, game, true, 8, 2)
+	s.banClient(banned, ClientID{42})
+
+	lobbies := s.Lobbies(ClientID{42})
+	if len(lobbies) != 2 {
+		t.Fatalf("expected 2 lobbies, got %d", len(lobbies))
+	}
+	if lobbies[0].ID != fuller || lobbies[1].ID != emptier {
+		t.Errorf("the lobbies are not sorted by the player count")
+	}
+
+	if lobby, ok := s.PickLobby(ClientID{42}, game); !ok || lobby.ID != emptier {
+		t.Errorf("expected the lobby of the requested game to be picked, got %v (ok=%v)", lobby.ID, ok)
+	}
+	if lobby, ok := s.PickLobby(ClientID{42}, uuid.NullUUID{}); !ok || lobby.ID != fuller {
+		t.Errorf("expected the most populated lobby to be picked, got %v (ok=%v)", lobby.ID, ok)
+	}
+	if _, ok := s.PickLobby(ClientID{42}, uuid.NullUUID{UUID: uuid.New(), Valid: true}); ok {
+		t.Error("a lobby of another game was picked")
+	}
+}
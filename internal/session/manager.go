@@ -2,10 +2,12 @@ package session
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github.com/google/uuid"
-	"log"
+	"log/slog"
 	"party-buddy/internal/db"
+	"sync/atomic"
 	"time"
 
 	"github.com/jackc/pgx/v5"
@@ -16,15 +18,20 @@ type Manager struct {
 	db      *db.DBPool
 	storage SyncStorage
 	runChan chan runMsg
-	log     *log.Logger
+	log     *slog.Logger
+	idle    IdlePolicy
+
+	// running is true while Run is running
+	running atomic.Bool
 }
 
-func NewManager(db *db.DBPool, logger *log.Logger) *Manager {
+func NewManager(db *db.DBPool, logger *slog.Logger, idle IdlePolicy) *Manager {
 	return &Manager{
 		db:      db,
 		storage: NewSyncStorage(),
 		runChan: make(chan runMsg),
 		log:     logger,
+		idle:    idle,
 	}
 }
 
@@ -54,11 +61,17 @@ type runMsg interface {
 type runMsgSpawn struct {
 	sid SessionID
 	rx  <-chan updateMsg
+
+	// deadline is the deadline of the session's initial state
+	deadline time.Time
 }
 
 func (*runMsgSpawn) isRunMsg() {}
 
 func (m *Manager) Run(ctx context.Context) error {
+	m.running.Store(true)
+	defer m.running.Store(false)
+
 	group, ctx := errgroup.WithContext(ctx)
 
 outer:
@@ -70,18 +83,7 @@ outer:
 		case msg := <-m.runChan:
 			switch msg := msg.(type) {
 			case *runMsgSpawn:
-				logger := log.New(
-					m.log.Writer(),
-					fmt.Sprintf("sessionUpdater(sid %s): ", msg.sid),
-					m.log.Flags(),
-				)
-				updater := sessionUpdater{
-					m:        m,
-					sid:      msg.sid,
-					rx:       msg.rx,
-					log:      logger,
-					deadline: time.NewTimer(NoOwnerTimeout),
-				}
+				updater := m.newUpdater(msg)
 				group.Go(func() error {
 					return updater.run(ctx)
 				})
@@ -92,6 +94,30 @@ outer:
 	return group.Wait()
 }
 
+// newUpdater creates the updater for a new session.
+// Its timer fires at the deadline of the session's initial state.
+func (m *Manager) newUpdater(msg *runMsgSpawn) *sessionUpdater {
+	return &sessionUpdater{
+		m:        m,
+		sid:      msg.sid,
+		rx:       msg.rx,
+		log:      m.log.With("component", "sessionUpdater", "sid", msg.sid),
+		deadline: time.NewTimer(time.Until(msg.deadline)),
+
+		reconnect: newStoppedTimer(),
+	}
+}
+
+var errNotRunning = errors.New("the session manager is not running")
+
+// Check returns an error unless Run is running.
+func (m *Manager) Check(ctx context.Context) error {
+	if !m.running.Load() {
+		return errNotRunning
+	}
+	return nil
+}
+
 // sendToUpdater sends a message to a session updater goroutine.
 //
 // DANGER: you MUST NOT call this method while holding the storage's mutex,
@@ -148,13 +174,14 @@ func (m *Manager) NewSession(
 	owner ClientID,
 	requireReady bool,
 	playersMax int,
+	opts SessionOptions,
 ) (sid SessionID, code InviteCode, err error) {
 	var updateChan chan updateMsg
+	deadline := time.Now().Add(opts.Timings.withDefaults().NoOwnerTimeout)
 
 	m.storage.Atomically(func(s *UnsafeStorage) {
-		deadline := time.Now().Add(NoOwnerTimeout)
 		sid, code, updateChan, err = s.newSession(
-			game, owner, requireReady, playersMax, deadline,
+			game, owner, requireReady, playersMax, deadline, opts,
 		)
 		if err != nil {
 			return
@@ -169,6 +196,8 @@ func (m *Manager) NewSession(
 			return
 		}
 
+		// all the tasks' images are registered, even those not selected,
+		// so that the images the owner uploads for them are cleaned up with the session
 		for _, task := range game.Tasks {
 			if err = m.registerImage(ctx, tx, sid, task.GetImageID()); err != nil {
 				return
@@ -181,19 +210,25 @@ func (m *Manager) NewSession(
 	}
 
 	m.runChan <- &runMsgSpawn{
-		sid: sid,
-		rx:  updateChan,
+		sid:      sid,
+		rx:       updateChan,
+		deadline: deadline,
 	}
 
 	return
 }
 
+// JoinSession adds the client to the session, or reconnects them if they're already a player.
+//
+// A legacy client speaks a protocol predating the lobby ownership (see Player).
 func (m *Manager) JoinSession(
 	ctx context.Context,
 	sid SessionID,
 	clientID ClientID,
 	nickname string,
 	tx TxChan,
+	lastSeq *uint64,
+	legacy bool,
 ) (player Player, err error) {
 	var reconnected bool
 
@@ -206,9 +241,11 @@ func (m *Manager) JoinSession(
 			reconnected = true
 			m.sendToPlayer(player.tx, m.makeMsgError(ctx, ErrReconnected))
 			m.closePlayerTx(s, sid, player.ID)
+			s.setPlayerTx(sid, player.ID, tx)
+			s.setPlayerLegacy(sid, player.ID, legacy)
 			return
 		}
-		if !s.AwaitingPlayers(sid) {
+		if !s.AwaitingPlayers(sid) && !s.LateJoinAllowed(sid) {
 			err = ErrGameInProgress
 			return
 		}
@@ -231,6 +268,10 @@ func (m *Manager) JoinSession(
 			err = fmt.Errorf("%w: could not add player to the session: %w", ErrInternal, err)
 			return
 		}
+		s.setPlayerLegacy(sid, player.ID, legacy)
+		if !s.AwaitingPlayers(sid) {
+			s.setLateJoinScore(sid, player.ID)
+		}
 	})
 
 	if err == nil {
@@ -238,6 +279,7 @@ func (m *Manager) JoinSession(
 			ctx:         ctx,
 			playerID:    player.ID,
 			reconnected: reconnected,
+			lastSeq:     lastSeq,
 		})
 	}
 
@@ -245,10 +287,27 @@ func (m *Manager) JoinSession(
 }
 
 // RemovePlayer removes a player from a session.
-func (m *Manager) RemovePlayer(ctx context.Context, sid SessionID, playerID PlayerID) {
+//
+// The tx is the channel the player was joined with.
+// If the player has since reconnected with another channel, they are not removed.
+func (m *Manager) RemovePlayer(ctx context.Context, sid SessionID, playerID PlayerID, tx TxChan) {
 	m.sendToUpdater(sid, &updateMsgRemovePlayer{
 		ctx:      ctx,
 		playerID: playerID,
+		tx:       tx,
+	})
+}
+
+// DisconnectPlayer handles a player whose connection has dropped.
+//
+// The player is kept in the session for the ReconnectGracePeriod, so that they can rejoin
+// without losing their score and have the missed messages replayed.
+// The tx is the channel the player was joined with, as in RemovePlayer.
+func (m *Manager) DisconnectPlayer(ctx context.Context, sid SessionID, playerID PlayerID, tx TxChan) {
+	m.sendToUpdater(sid, &updateMsgDisconnectPlayer{
+		ctx:      ctx,
+		playerID: playerID,
+		tx:       tx,
 	})
 }
 
@@ -342,13 +401,13 @@ func (m *Manager) closeSession(
 	tx pgx.Tx,
 	sid SessionID,
 ) {
-	m.log.Printf("closing session %s", sid)
+	m.log.Info("closing session", "sid", sid)
 	s.ForEachPlayer(sid, func(p Player) {
 		m.closePlayerTx(s, sid, p.ID)
 	})
 
 	if err := db.RemoveSessionImageRefs(ctx, tx, sid.UUID()); err != nil {
-		m.log.Printf("while closing session %s: could not remove session image references: %s", sid, err)
+		m.log.Error("while closing session: could not remove session image references", "sid", sid, "err", err)
 	}
 
 	s.closeUpdater(sid)
@@ -363,7 +422,25 @@ func (m *Manager) sendToPlayer(tx TxChan, message ServerTx) {
 	}
 }
 
+// sendLogged sends a message to the player and keeps it for replay.
+func (m *Manager) sendLogged(s *UnsafeStorage, sid SessionID, player Player, message ServerTx) {
+	s.stampSeq(sid, message)
+	s.logForReplay(sid, player.ID, message)
+	m.sendToPlayer(player.tx, message)
+}
+
+// sendToAllPlayers sends a message to every player and keeps it for replay.
+// All players see the message under the same sequence number.
 func (m *Manager) sendToAllPlayers(s *UnsafeStorage, sid SessionID, message ServerTx) {
+	s.stampSeq(sid, message)
+	s.ForEachPlayer(sid, func(p Player) {
+		s.logForReplay(sid, p.ID, message)
+		m.sendToPlayer(p.tx, message)
+	})
+}
+
+// sendToAllPlayersUnlogged sends a message to every player without keeping it for replay.
+func (m *Manager) sendToAllPlayersUnlogged(s *UnsafeStorage, sid SessionID, message ServerTx) {
 	for _, tx := range s.PlayerTxs(sid) {
 		m.sendToPlayer(tx, message)
 	}
@@ -0,0 +1,36 @@
+package session
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func TestNewSessionDeadline(t *testing.T) {
+	m := NewManager(nil, slog.Default(), DefaultIdlePolicy)
+	timeout := 50 * time.Millisecond
+	opts := SessionOptions{Timings: Timings{NoOwnerTimeout: timeout}}
+
+	go func() {
+		if _, _, err := m.NewSession(context.Background(), nil, &Game{}, ClientID{1}, false, 8, opts); err != nil {
+			t.Errorf("could not create a session: %v", err)
+		}
+	}()
+
+	var spawn *runMsgSpawn
+	select {
+	case msg := <-m.runChan:
+		spawn = msg.(*runMsgSpawn)
+	case <-time.After(time.Second):
+		t.Fatal("the session's updater was not spawned")
+	}
+
+	u := m.newUpdater(spawn)
+	defer u.deadline.Stop()
+	select {
+	case <-u.deadline.C:
+	case <-time.After(time.Second):
+		t.Fatalf("the updater's timer did not fire after the session's no-owner timeout of %v", timeout)
+	}
+}
@@ -21,8 +21,10 @@ func (m *Manager) makeMsgJoined(
 	inviteCode *InviteCode,
 	game *Game,
 	maxPlayers int,
+	state State,
 ) ServerTx {
 	return &MsgJoined{
+		State:      state,
 		baseTx:     baseTx{Ctx: ctx},
 		PlayerID:   playerID,
 		SessionID:  sid,
@@ -56,8 +58,11 @@ func (m *Manager) makeMsgTaskStart(
 		msg.Options = &t.Options
 		return msg
 	case PhotoTask:
-		i := ImageID(answer.(PhotoTaskAnswer))
-		msg.ImgID = &i
+		// the latecomers have no image to upload
+		if answer, ok := answer.(PhotoTaskAnswer); ok {
+			i := ImageID(answer)
+			msg.ImgID = &i
+		}
 		return msg
 	default:
 		return msg
@@ -115,3 +120,49 @@ func (m *Manager) makeMsgWaiting(ctx context.Context, playersReady map[PlayerID]
 		PlayersReady: maps.Clone(playersReady),
 	}
 }
+
+func (m *Manager) makeMsgChat(ctx context.Context, msg ChatMessage) ServerTx {
+	return &MsgChat{
+		baseTx:      baseTx{Ctx: ctx},
+		ChatMessage: msg,
+	}
+}
+
+func (m *Manager) makeMsgReaction(ctx context.Context, playerID PlayerID, reaction string) ServerTx {
+	return &MsgReaction{
+		baseTx:   baseTx{Ctx: ctx},
+		PlayerID: playerID,
+		Reaction: reaction,
+	}
+}
+
+func (m *Manager) makeMsgChatMuted(ctx context.Context, muted bool) ServerTx {
+	return &MsgChatMuted{
+		baseTx: baseTx{Ctx: ctx},
+		Muted:  muted,
+	}
+}
+
+func (m *Manager) makeMsgChatHistory(ctx context.Context, muted bool, messages []ChatMessage) ServerTx {
+	return &MsgChatHistory{
+		baseTx:   baseTx{Ctx: ctx},
+		Muted:    muted,
+		Messages: messages,
+	}
+}
+
+func (m *Manager) makeMsgOwnerChanged(ctx context.Context, playerID PlayerID) ServerTx {
+	return &MsgOwnerChanged{
+		baseTx:   baseTx{Ctx: ctx},
+		PlayerID: playerID,
+	}
+}
+
+func (m *Manager) makeMsgDeadlineChanged(ctx context.Context, deadline time.Time, paused bool, remaining time.Duration) ServerTx {
+	return &MsgDeadlineChanged{
+		baseTx:    baseTx{Ctx: ctx},
+		Deadline:  deadline,
+		Paused:    paused,
+		Remaining: remaining,
+	}
+}
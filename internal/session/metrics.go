@@ -0,0 +1,60 @@
+package session
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	sessionsDesc = prometheus.NewDesc(
+		"partybuddy_sessions",
+		"Live sessions by state.",
+		[]string{"state"}, nil,
+	)
+	playersConnectedDesc = prometheus.NewDesc(
+		"partybuddy_players_connected",
+		"Players with an open connection to a live session.",
+		nil, nil,
+	)
+)
+
+// managerCollector collects live session statistics from the storage on scrape.
+type managerCollector struct {
+	m *Manager
+}
+
+// Collector returns a Prometheus collector reporting the live sessions and connected players.
+func (m *Manager) Collector() prometheus.Collector {
+	return managerCollector{m: m}
+}
+
+func (c managerCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- sessionsDesc
+	ch <- playersConnectedDesc
+}
+
+func (c managerCollector) Collect(ch chan<- prometheus.Metric) {
+	sessions := map[string]int{
+		StateName(&AwaitingPlayersState{}): 0,
+		StateName(&GameStartedState{}):     0,
+		StateName(&TaskStartedState{}):     0,
+		StateName(&PollStartedState{}):     0,
+		StateName(&TaskEndedState{}):       0,
+	}
+	connected := 0
+
+	c.m.storage.Atomically(func(s *UnsafeStorage) {
+		for _, session := range s.sessions {
+			sessions[StateName(session.state)]++
+			for _, player := range session.players {
+				if player.tx != nil {
+					connected++
+				}
+			}
+		}
+	})
+
+	for state, count := range sessions {
+		ch <- prometheus.MustNewConstMetric(sessionsDesc, prometheus.GaugeValue, float64(count), state)
+	}
+	ch <- prometheus.MustNewConstMetric(playersConnectedDesc, prometheus.GaugeValue, float64(connected))
+}
@@ -0,0 +1,176 @@
+package session
+
+import (
+	"context"
+	"time"
+)
+
+// # Session ownership
+//
+// The owner of a session is never absent while anyone else is there:
+// when they leave, the ownership passes to the longest-present player.
+// A player joining a lobby the owner has left empty becomes the owner.
+// The creator keeps the ownership until they join: if they don't in the NoOwnerTimeout, the lobby is closed.
+//
+// The legacy players (see Player) are never told about the ownership changes,
+// so a lobby with any of them is closed when the owner leaves, as it used to be.
+// Neither can the ownership be handed over to them.
+
+func (s *UnsafeStorage) setOwner(sid SessionID, clientID ClientID) {
+	if session := s.sessions[sid]; session != nil {
+		session.owner = clientID
+	}
+}
+
+// ownerHasJoined returns true if the owner has been one of the players of the session.
+func (s *UnsafeStorage) ownerHasJoined(sid SessionID) bool {
+	session := s.sessions[sid]
+	return session != nil && session.ownerJoined
+}
+
+func (s *UnsafeStorage) setOwnerJoined(sid SessionID) {
+	if session := s.sessions[sid]; session != nil {
+		session.ownerJoined = true
+	}
+}
+
+// isOwnerClient returns true if the client owns the session.
+func (s *UnsafeStorage) isOwnerClient(sid SessionID, clientID ClientID) bool {
+	session := s.sessions[sid]
+	return session != nil && session.owner == clientID
+}
+
+// isOwner returns true if the player owns the session.
+func (s *UnsafeStorage) isOwner(sid SessionID, playerID PlayerID) bool {
+	player, err := s.PlayerByID(sid, playerID)
+	return err == nil && s.isOwnerClient(sid, player.ClientID)
+}
+
+// ownerPlayer returns the owner if they're one of the players.
+func (s *UnsafeStorage) ownerPlayer(sid SessionID) (owner Player, ok bool) {
+	session := s.sessions[sid]
+	if session == nil {
+		return owner, false
+	}
+	owner, err := s.PlayerByClientID(sid, session.owner)
+	return owner, err == nil
+}
+
+// ownerPresent returns true if the owner of the session is one of its players.
+func (s *UnsafeStorage) ownerPresent(sid SessionID) bool {
+	_, ok := s.ownerPlayer(sid)
+	return ok
+}
+
+// legacyPlayersPresent returns true if any of the players is a legacy one.
+func (s *UnsafeStorage) legacyPlayersPresent(sid SessionID) (present bool) {
+	s.ForEachPlayer(sid, func(p Player) {
+		present = present || p.legacy
+	})
+	return
+}
+
+// longestPresentPlayer returns the player who joined the session the earliest among those still present.
+// The player ids are given out in order and kept on reconnection, so it's simply the one with the smallest id.
+func (s *UnsafeStorage) longestPresentPlayer(sid SessionID) (player Player, ok bool) {
+	s.ForEachPlayer(sid, func(p Player) {
+		if !ok || p.ID < player.ID {
+			player, ok = p, true
+		}
+	})
+	return
+}
+
+// TransferOwnership hands the ownership of a lobby over to another player.
+// Only the owner may do this.
+func (m *Manager) TransferOwnership(ctx context.Context, sid SessionID, playerID PlayerID, to PlayerID) (err error) {
+	m.storage.Atomically(func(s *UnsafeStorage) {
+		if !s.SessionExists(sid) {
+			err = ErrNoSession
+			return
+		}
+		if !s.PlayerExists(sid, playerID) {
+			err = ErrNoPlayer
+			return
+		}
+		if !s.isOwner(sid, playerID) {
+			err = ErrOwnerOnly
+			return
+		}
+		target, targetErr := s.PlayerByID(sid, to)
+		if targetErr != nil {
+			err = ErrNoTargetPlayer
+			return
+		}
+		if target.legacy {
+			err = ErrTargetLegacy
+			return
+		}
+	})
+
+	if err != nil {
+		return
+	}
+
+	m.sendToUpdater(sid, &updateMsgTransferOwner{
+		ctx:      ctx,
+		playerID: playerID,
+		to:       to,
+	})
+
+	return
+}
+
+// passOwnership makes the player the owner of the session and announces it.
+func (u *sessionUpdater) passOwnership(msgCtx context.Context, s *UnsafeStorage, player Player) {
+	if s.isOwnerClient(u.sid, player.ClientID) {
+		return
+	}
+
+	u.log.Info("the ownership has passed to another player", "player_id", player.ID, "client_id", player.ClientID)
+	s.setOwner(u.sid, player.ClientID)
+	u.m.sendToAllPlayers(s, u.sid, u.m.makeMsgOwnerChanged(msgCtx, player.ID))
+}
+
+// transferOwner hands the ownership over on the owner's request.
+func (u *sessionUpdater) transferOwner(
+	ctx context.Context,
+	msgCtx context.Context,
+	s *UnsafeStorage,
+	playerID PlayerID,
+	to PlayerID,
+) {
+	// the owner might have changed since the request was accepted
+	if !s.isOwner(u.sid, playerID) {
+		return
+	}
+
+	player, err := s.PlayerByID(u.sid, to)
+	if err != nil {
+		return
+	}
+
+	u.passOwnership(msgCtx, s, player)
+
+	// the new owner might be ready already
+	if state, ok := s.sessionState(u.sid).(*AwaitingPlayersState); ok && u.shouldStartGame(s) {
+		u.changeStateTo(ctx, msgCtx, s, u.makeGameStartedState(s, state))
+	}
+}
+
+// ownerLeft passes the ownership to the longest-present player after the owner has left.
+// If the lobby is now empty, it's closed unless someone joins in the session's NoOwnerTimeout.
+func (u *sessionUpdater) ownerLeft(msgCtx context.Context, s *UnsafeStorage) {
+	player, ok := s.longestPresentPlayer(u.sid)
+	if ok {
+		u.passOwnership(msgCtx, s, player)
+		return
+	}
+
+	if state, ok := s.sessionState(u.sid).(*AwaitingPlayersState); ok {
+		timeout := s.SessionTimings(u.sid).NoOwnerTimeout
+		u.log.Info("the lobby is empty, waiting for someone to join", "timeout", timeout)
+		state.deadline = time.Now().Add(timeout)
+		u.deadline.Reset(timeout)
+	}
+}
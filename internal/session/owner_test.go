@@ -0,0 +1,114 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func TestLongestPresentPlayer(t *testing.T) {
+	s, sid, first := newTestStorageWithPlayer(t)
+
+	second, err := s.addPlayer(sid, ClientID{2}, "second", nil)
+	if err != nil {
+		t.Fatalf("could not add a player: %v", err)
+	}
+	if _, err = s.addPlayer(sid, ClientID{3}, "third", nil); err != nil {
+		t.Fatalf("could not add a player: %v", err)
+	}
+
+	if player, ok := s.longestPresentPlayer(sid); !ok || player.ID != first {
+		t.Errorf("expected player %v, got %v (ok=%v)", first, player.ID, ok)
+	}
+
+	s.removePlayer(sid, ClientID{1})
+	if player, ok := s.longestPresentPlayer(sid); !ok || player.ID != second.ID {
+		t.Errorf("expected player %v after the first one has left, got %v (ok=%v)", second.ID, player.ID, ok)
+	}
+
+	s.removePlayer(sid, ClientID{2})
+	s.removePlayer(sid, ClientID{3})
+	if _, ok := s.longestPresentPlayer(sid); ok {
+		t.Error("an empty lobby has no players")
+	}
+}
+
+func TestOwnershipWhileAwaitingCreator(t *testing.T) {
+	s := NewUnsafeStorage()
+	creator := ClientID{1}
+	sid, _, _, err := s.newSession(&Game{}, creator, false, 8, time.Now().Add(time.Hour), SessionOptions{})
+	if err != nil {
+		t.Fatalf("could not create a session: %v", err)
+	}
+
+	u := &sessionUpdater{
+		m:        &Manager{},
+		sid:      sid,
+		log:      slog.Default(),
+		deadline: time.NewTimer(time.Hour),
+	}
+	defer u.deadline.Stop()
+	ctx := context.Background()
+
+	join := func(clientID ClientID) Player {
+		player, err := s.addPlayer(sid, clientID, "player", nil)
+		if err != nil {
+			t.Fatalf("could not add a player: %v", err)
+		}
+		u.playerAdded(ctx, ctx, &s, player.ID, false, nil)
+		return player
+	}
+
+	stranger := join(ClientID{2})
+	if !s.isOwnerClient(sid, creator) {
+		t.Fatal("a player joining before the creator has taken the ownership over")
+	}
+
+	join(creator)
+	s.removePlayer(sid, creator)
+	u.ownerLeft(ctx, &s)
+	if !s.isOwner(sid, stranger.ID) {
+		t.Fatal("the ownership has not passed to the remaining player")
+	}
+
+	s.removePlayer(sid, stranger.ClientID)
+	u.ownerLeft(ctx, &s)
+	newcomer := join(ClientID{3})
+	if !s.isOwner(sid, newcomer.ID) {
+		t.Error("a player joining a lobby the owner has left empty has not become the owner")
+	}
+}
+
+func TestOwnershipWithLegacyPlayers(t *testing.T) {
+	m := NewManager(nil, slog.Default(), DefaultIdlePolicy)
+
+	var sid SessionID
+	var owner, legacy Player
+	m.storage.Atomically(func(s *UnsafeStorage) {
+		var err error
+		if sid, _, _, err = s.newSession(&Game{}, ClientID{1}, false, 8, time.Now(), SessionOptions{}); err != nil {
+			t.Fatalf("could not create a session: %v", err)
+		}
+		if owner, err = s.addPlayer(sid, ClientID{1}, "owner", nil); err != nil {
+			t.Fatalf("could not add a player: %v", err)
+		}
+		if s.legacyPlayersPresent(sid) {
+			t.Error("a lobby without legacy players reports them")
+		}
+
+		if legacy, err = s.addPlayer(sid, ClientID{2}, "legacy", nil); err != nil {
+			t.Fatalf("could not add a player: %v", err)
+		}
+		s.setPlayerLegacy(sid, legacy.ID, true)
+		if !s.legacyPlayersPresent(sid) {
+			t.Error("a lobby with a legacy player doesn't report it")
+		}
+	})
+
+	err := m.TransferOwnership(context.Background(), sid, owner.ID, legacy.ID)
+	if !errors.Is(err, ErrTargetLegacy) {
+		t.Errorf("expected ErrTargetLegacy when handing the ownership to a legacy player, got %v", err)
+	}
+}
@@ -0,0 +1,187 @@
+package session
+
+import (
+	"context"
+	"time"
+
+	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
+)
+
+// ReplayLogSize is the number of messages kept for each player to be replayed after a reconnect.
+var ReplayLogSize = 64
+
+// ReconnectGracePeriod is how long a player whose connection has dropped is kept in the session.
+// A zero value removes them right away.
+var ReconnectGracePeriod = 30 * time.Second
+
+type replayEntry struct {
+	seq uint64
+	msg ServerTx
+}
+
+// A replayLog is a bounded log of the messages sent to a player.
+// Once the log is full, the oldest messages are dropped.
+type replayLog struct {
+	entries []replayEntry
+
+	// evicted is the sequence number of the latest message dropped from the log (0 if none).
+	evicted uint64
+}
+
+func (l *replayLog) append(msg ServerTx) {
+	if len(l.entries) >= ReplayLogSize {
+		drop := len(l.entries) - ReplayLogSize + 1
+		l.evicted = l.entries[drop-1].seq
+		l.entries = append(l.entries[:0], l.entries[drop:]...)
+	}
+
+	l.entries = append(l.entries, replayEntry{seq: msg.Seq(), msg: msg})
+}
+
+// since returns the messages with sequence numbers greater than seq.
+// If some of them have already been dropped, ok is false.
+func (l *replayLog) since(seq uint64) (msgs []ServerTx, ok bool) {
+	if seq < l.evicted {
+		return nil, false
+	}
+
+	for _, entry := range l.entries {
+		if entry.seq > seq {
+			msgs = append(msgs, entry.msg)
+		}
+	}
+
+	return msgs, true
+}
+
+// stampSeq assigns the next session-scoped sequence number to the message.
+func (s *UnsafeStorage) stampSeq(sid SessionID, msg ServerTx) {
+	if session := s.sessions[sid]; session != nil {
+		session.lastSeq++
+		msg.setSeq(session.lastSeq)
+	}
+}
+
+// logForReplay records a message sent to the player.
+// The message must already have a sequence number.
+func (s *UnsafeStorage) logForReplay(sid SessionID, playerID PlayerID, msg ServerTx) {
+	session := s.sessions[sid]
+	if session == nil {
+		return
+	}
+
+	log := session.replayLogs[playerID]
+	if log == nil {
+		log = &replayLog{}
+		session.replayLogs[playerID] = log
+	}
+	log.append(msg)
+}
+
+// missedMessages returns the messages sent to the player after the one with the sequence number lastSeq.
+// If the player's log no longer holds all of them, ok is false.
+func (s *UnsafeStorage) missedMessages(sid SessionID, playerID PlayerID, lastSeq uint64) (msgs []ServerTx, ok bool) {
+	session := s.sessions[sid]
+	if session == nil || lastSeq > session.lastSeq {
+		return nil, false
+	}
+
+	log := session.replayLogs[playerID]
+	if log == nil {
+		return nil, lastSeq == 0
+	}
+
+	return log.since(lastSeq)
+}
+
+// markDisconnected records that the player's connection has dropped.
+// Unless they reconnect, they are to be removed at the given time.
+func (s *UnsafeStorage) markDisconnected(sid SessionID, playerID PlayerID, until time.Time) {
+	if session := s.sessions[sid]; session != nil {
+		session.disconnected[playerID] = until
+	}
+}
+
+// expiredDisconnects returns the disconnected players whose grace period has ended by now, in the order of their ids.
+func (s *UnsafeStorage) expiredDisconnects(sid SessionID, now time.Time) []PlayerID {
+	session := s.sessions[sid]
+	if session == nil {
+		return nil
+	}
+
+	var expired []PlayerID
+	for playerID, until := range session.disconnected {
+		if !until.After(now) {
+			expired = append(expired, playerID)
+		}
+	}
+	slices.Sort(expired)
+
+	return expired
+}
+
+// nextDisconnectExpiry returns the time the earliest grace period of the disconnected players ends at.
+func (s *UnsafeStorage) nextDisconnectExpiry(sid SessionID) (time.Time, bool) {
+	session := s.sessions[sid]
+	if session == nil || len(session.disconnected) == 0 {
+		return time.Time{}, false
+	}
+
+	return slices.MinFunc(maps.Values(session.disconnected), func(a, b time.Time) int {
+		return a.Compare(b)
+	}), true
+}
+
+func newStoppedTimer() *time.Timer {
+	timer := time.NewTimer(time.Hour)
+	timer.Stop()
+	return timer
+}
+
+// disconnectPlayer handles a dropped connection.
+// The player stays in the session for the ReconnectGracePeriod; meanwhile the messages sent to them are kept for replay.
+func (u *sessionUpdater) disconnectPlayer(
+	ctx context.Context,
+	msgCtx context.Context,
+	s *UnsafeStorage,
+	playerID PlayerID,
+) {
+	if ReconnectGracePeriod <= 0 {
+		u.removePlayer(ctx, msgCtx, s, playerID)
+		return
+	}
+
+	if !s.PlayerExists(u.sid, playerID) {
+		u.log.Warn("received disconnectPlayer for unknown player", "player_id", playerID)
+		return
+	}
+
+	u.log.Info("the player has disconnected", "player_id", playerID, "grace_period", ReconnectGracePeriod)
+	u.m.closePlayerTx(s, u.sid, playerID)
+	s.markDisconnected(u.sid, playerID, time.Now().Add(ReconnectGracePeriod))
+	u.armReconnectTimer(s)
+}
+
+// reconnectExpired removes the disconnected players who have not reconnected in time.
+func (u *sessionUpdater) reconnectExpired(ctx context.Context, s *UnsafeStorage) {
+	for _, playerID := range s.expiredDisconnects(u.sid, time.Now()) {
+		if !s.SessionExists(u.sid) {
+			// removing the last player closes the session
+			return
+		}
+
+		u.log.Info("the player has not reconnected in time, removing", "player_id", playerID)
+		u.removePlayer(ctx, ctx, s, playerID)
+	}
+
+	u.armReconnectTimer(s)
+}
+
+// armReconnectTimer sets the reconnect timer to fire when the earliest grace period ends.
+func (u *sessionUpdater) armReconnectTimer(s *UnsafeStorage) {
+	u.reconnect.Stop()
+	if expiry, ok := s.nextDisconnectExpiry(u.sid); ok {
+		u.reconnect.Reset(max(time.Until(expiry), 0))
+	}
+}
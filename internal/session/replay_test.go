@@ -0,0 +1,171 @@
+package session
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+
+	"golang.org/x/exp/slices"
+)
+
+func newTestStorageWithPlayer(t *testing.T) (UnsafeStorage, SessionID, PlayerID) {
+	t.Helper()
+
+	s := NewUnsafeStorage()
+	sid, _, _, err := s.newSession(&Game{}, ClientID{}, false, 8, time.Now(), SessionOptions{})
+	if err != nil {
+		t.Fatalf("could not create a session: %v", err)
+	}
+	player, err := s.addPlayer(sid, ClientID{1}, "player", nil)
+	if err != nil {
+		t.Fatalf("could not add a player: %v", err)
+	}
+
+	return s, sid, player.ID
+}
+
+func logMessages(s *UnsafeStorage, sid SessionID, playerID PlayerID, n int) {
+	for i := 0; i < n; i++ {
+		msg := &MsgGameStatus{baseTx: baseTx{Ctx: context.Background()}}
+		s.stampSeq(sid, msg)
+		s.logForReplay(sid, playerID, msg)
+	}
+}
+
+func TestMissedMessages(t *testing.T) {
+	s, sid, playerID := newTestStorageWithPlayer(t)
+	logMessages(&s, sid, playerID, 5)
+
+	missed, ok := s.missedMessages(sid, playerID, 2)
+	if !ok {
+		t.Fatal("the log should cover the missed messages")
+	}
+	if len(missed) != 3 {
+		t.Fatalf("expected 3 missed messages, got %d", len(missed))
+	}
+	for i, msg := range missed {
+		if want := uint64(3 + i); msg.Seq() != want {
+			t.Errorf("message %d: expected seq %d, got %d", i, want, msg.Seq())
+		}
+	}
+
+	if missed, ok = s.missedMessages(sid, playerID, 5); !ok || len(missed) != 0 {
+		t.Errorf("nothing was missed, got %d messages (ok=%v)", len(missed), ok)
+	}
+
+	if _, ok = s.missedMessages(sid, playerID, 6); ok {
+		t.Error("a sequence number from the future was accepted")
+	}
+}
+
+func TestMissedMessagesEvicted(t *testing.T) {
+	s, sid, playerID := newTestStorageWithPlayer(t)
+	logMessages(&s, sid, playerID, ReplayLogSize+10)
+
+	if _, ok := s.missedMessages(sid, playerID, 5); ok {
+		t.Error("the log no longer holds the missed messages")
+	}
+
+	missed, ok := s.missedMessages(sid, playerID, 10)
+	if !ok {
+		t.Fatal("the log should cover the messages after the last evicted one")
+	}
+	if len(missed) != ReplayLogSize {
+		t.Errorf("expected %d missed messages, got %d", ReplayLogSize, len(missed))
+	}
+}
+
+func TestReconnectAfterDisconnect(t *testing.T) {
+	s, sid, playerID := newTestStorageWithPlayer(t)
+	if _, err := s.addPlayer(sid, ClientID{2}, "other", nil); err != nil {
+		t.Fatalf("could not add a player: %v", err)
+	}
+	s.setSessionState(sid, &TaskEndedState{deadline: time.Now().Add(time.Hour)})
+	updates := make(chan updateMsg, 1)
+	s.updaters[sid] = updates
+
+	m := &Manager{storage: SyncStorage{inner: s}}
+	st := &m.storage.inner
+	u := &sessionUpdater{
+		m:         m,
+		sid:       sid,
+		log:       slog.Default(),
+		deadline:  time.NewTimer(time.Hour),
+		reconnect: newStoppedTimer(),
+	}
+	defer u.deadline.Stop()
+	defer u.reconnect.Stop()
+	ctx := context.Background()
+
+	rx := make(chan ServerTx, 16)
+	st.setPlayerTx(sid, playerID, rx)
+	m.sendToAllPlayers(st, sid, &MsgGameStatus{baseTx: baseTx{Ctx: ctx}})
+	lastSeq := (<-rx).Seq()
+
+	u.disconnectPlayer(ctx, ctx, st, playerID)
+	if _, ok := <-rx; ok {
+		t.Fatal("the dropped connection's channel should be closed")
+	}
+	if !st.PlayerExists(sid, playerID) {
+		t.Fatal("the disconnected player should be kept in the session")
+	}
+
+	// the player misses these
+	for i := 0; i < 2; i++ {
+		m.sendToAllPlayers(st, sid, &MsgGameStatus{baseTx: baseTx{Ctx: ctx}})
+	}
+
+	rx = make(chan ServerTx, 16)
+	player, err := m.JoinSession(ctx, sid, ClientID{1}, "player", rx, &lastSeq, false)
+	if err != nil {
+		t.Fatalf("could not rejoin: %v", err)
+	}
+	if player.ID != playerID {
+		t.Fatalf("expected to rejoin as player %v, got %v", playerID, player.ID)
+	}
+	added := (<-updates).(*updateMsgPlayerAdded)
+	u.playerAdded(ctx, added.ctx, st, added.playerID, added.reconnected, added.lastSeq)
+	close(rx)
+
+	var replayed []uint64
+	for msg := range rx {
+		if msg.Seq() != 0 {
+			replayed = append(replayed, msg.Seq())
+		}
+	}
+	if want := []uint64{lastSeq + 1, lastSeq + 2}; !slices.Equal(replayed, want) {
+		t.Errorf("expected the missed messages %v to be replayed, got %v", want, replayed)
+	}
+}
+
+func TestDisconnectGracePeriodExpired(t *testing.T) {
+	s, sid, playerID := newTestStorageWithPlayer(t)
+	if _, err := s.addPlayer(sid, ClientID{2}, "other", nil); err != nil {
+		t.Fatalf("could not add a player: %v", err)
+	}
+	s.setSessionState(sid, &TaskEndedState{deadline: time.Now().Add(time.Hour)})
+
+	u := &sessionUpdater{
+		m:         &Manager{},
+		sid:       sid,
+		log:       slog.Default(),
+		deadline:  time.NewTimer(time.Hour),
+		reconnect: newStoppedTimer(),
+	}
+	defer u.deadline.Stop()
+	defer u.reconnect.Stop()
+	ctx := context.Background()
+
+	u.disconnectPlayer(ctx, ctx, &s, playerID)
+	u.reconnectExpired(ctx, &s)
+	if !s.PlayerExists(sid, playerID) {
+		t.Fatal("the player was removed before the grace period ended")
+	}
+
+	s.markDisconnected(sid, playerID, time.Now())
+	u.reconnectExpired(ctx, &s)
+	if s.PlayerExists(sid, playerID) {
+		t.Error("the player should be removed once the grace period has ended")
+	}
+}
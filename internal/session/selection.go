@@ -0,0 +1,170 @@
+package session
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	mathrand "math/rand"
+	"party-buddy/internal/configuration"
+
+	"golang.org/x/exp/slices"
+)
+
+// # Task selection
+//
+// The order of the tasks and of the ChoiceTask options is decided by a pseudo-random generator
+// seeded when the session is created. The seed is recorded so that the same order can be reproduced.
+
+// A TaskSelection tells which of the game's tasks are played and in what order.
+type TaskSelection struct {
+	// Shuffle plays the tasks in a random order instead of the game's one.
+	Shuffle bool
+
+	// Count is the number of tasks drawn at random from the game.
+	// Zero (or a number not less than the number of tasks) means all of them.
+	Count int
+
+	// Stratify keeps the proportions of the task kinds among the drawn tasks.
+	Stratify bool
+
+	// Seed is the seed of the random generator. If nil, a random one is generated.
+	Seed *int64
+}
+
+// NewTaskSeed generates a random seed for the task selection.
+func NewTaskSeed() int64 {
+	seed, err := rand.Int(rand.Reader, big.NewInt(configuration.MaxTaskSeed+1))
+	if err != nil {
+		panic(fmt.Sprintf("could not generate a random task seed: %s", err))
+	}
+	return seed.Int64()
+}
+
+// SessionTaskSeed returns the seed used to select the session's tasks and shuffle the ChoiceTask options.
+func (s *UnsafeStorage) SessionTaskSeed(sid SessionID) int64 {
+	if session := s.sessions[sid]; session != nil {
+		return session.taskSeed
+	}
+	return 0
+}
+
+// arrangeTasks selects the tasks to be played and shuffles the options of the ChoiceTasks.
+// It returns the seed it has used. The tasks slice is not modified.
+func arrangeTasks(tasks []Task, sel TaskSelection) (selected []Task, seed int64) {
+	seed = NewTaskSeed()
+	if sel.Seed != nil {
+		seed = *sel.Seed
+	}
+	rng := mathrand.New(mathrand.NewSource(seed))
+
+	selected = selectTasks(rng, tasks, sel)
+	shuffleOptions(rng, selected)
+
+	return selected, seed
+}
+
+// taskKind returns the kind of the task used to stratify the draw.
+func taskKind(task Task) string {
+	return fmt.Sprintf("%T", task)
+}
+
+// selectTasks returns the tasks to be played. The tasks slice is not modified.
+func selectTasks(rng *mathrand.Rand, tasks []Task, sel TaskSelection) []Task {
+	indices := make([]int, len(tasks))
+	for i := range indices {
+		indices[i] = i
+	}
+
+	if sel.Count > 0 && sel.Count < len(tasks) {
+		if sel.Stratify {
+			indices = drawStratified(rng, tasks, sel.Count)
+		} else {
+			rng.Shuffle(len(indices), func(i, j int) {
+				indices[i], indices[j] = indices[j], indices[i]
+			})
+			indices = indices[:sel.Count]
+		}
+
+		// unless shuffled, the drawn tasks are played in the game's order
+		slices.Sort(indices)
+	}
+
+	if sel.Shuffle {
+		rng.Shuffle(len(indices), func(i, j int) {
+			indices[i], indices[j] = indices[j], indices[i]
+		})
+	}
+
+	selected := make([]Task, 0, len(indices))
+	for _, idx := range indices {
+		selected = append(selected, tasks[idx])
+	}
+
+	return selected
+}
+
+// drawStratified draws count task indices so that each task kind gets a share proportional to its size in the game.
+// The shares are rounded by the largest remainder method.
+func drawStratified(rng *mathrand.Rand, tasks []Task, count int) []int {
+	var kinds []string
+	strata := make(map[string][]int)
+	for i, task := range tasks {
+		kind := taskKind(task)
+		if _, ok := strata[kind]; !ok {
+			kinds = append(kinds, kind)
+		}
+		strata[kind] = append(strata[kind], i)
+	}
+
+	shares := make(map[string]int, len(kinds))
+	remainders := make(map[string]int, len(kinds))
+	allotted := 0
+	for _, kind := range kinds {
+		n := len(strata[kind]) * count
+		shares[kind] = n / len(tasks)
+		remainders[kind] = n % len(tasks)
+		allotted += shares[kind]
+	}
+
+	byRemainder := slices.Clone(kinds)
+	slices.SortStableFunc(byRemainder, func(a, b string) int {
+		return remainders[b] - remainders[a]
+	})
+	for _, kind := range byRemainder[:count-allotted] {
+		shares[kind]++
+	}
+
+	indices := make([]int, 0, count)
+	for _, kind := range kinds {
+		stratum := strata[kind]
+		rng.Shuffle(len(stratum), func(i, j int) {
+			stratum[i], stratum[j] = stratum[j], stratum[i]
+		})
+		indices = append(indices, stratum[:shares[kind]]...)
+	}
+
+	return indices
+}
+
+// shuffleOptions shuffles the options of the ChoiceTasks, keeping track of the correct answers.
+func shuffleOptions(rng *mathrand.Rand, tasks []Task) {
+	for taskIdx, task := range tasks {
+		if task, ok := task.(ChoiceTask); ok {
+			options := slices.Clone(task.Options)
+			for i := range options {
+				j := i + rng.Intn(len(options)-i)
+				options[i], options[j] = options[j], options[i]
+
+				switch task.AnswerIdx {
+				case i:
+					task.AnswerIdx = j
+				case j:
+					task.AnswerIdx = i
+				}
+			}
+
+			task.Options = options
+			tasks[taskIdx] = task
+		}
+	}
+}
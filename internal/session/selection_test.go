@@ -0,0 +1,70 @@
+package session
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func newTestTasks() []Task {
+	var tasks []Task
+	for i := 0; i < 6; i++ {
+		tasks = append(tasks, ChoiceTask{
+			BaseTask:  BaseTask{Name: fmt.Sprint("choice ", i)},
+			Options:   []string{"a", "b", "c", "d"},
+			AnswerIdx: i % 4,
+		})
+	}
+	for i := 0; i < 3; i++ {
+		tasks = append(tasks, CheckedTextTask{BaseTask: BaseTask{Name: fmt.Sprint("text ", i)}, Answer: "A"})
+	}
+	return tasks
+}
+
+func TestArrangeTasksReproducible(t *testing.T) {
+	seed := int64(42)
+	sel := TaskSelection{Shuffle: true, Count: 5, Seed: &seed}
+
+	first, gotSeed := arrangeTasks(newTestTasks(), sel)
+	second, _ := arrangeTasks(newTestTasks(), sel)
+
+	if gotSeed != seed {
+		t.Errorf("expected the seed %d to be used, got %d", seed, gotSeed)
+	}
+	if len(first) != 5 {
+		t.Fatalf("expected 5 tasks, got %d", len(first))
+	}
+	if !reflect.DeepEqual(first, second) {
+		t.Error("the same seed should give the same tasks in the same order")
+	}
+}
+
+func TestArrangeTasksStratified(t *testing.T) {
+	tasks := newTestTasks()
+	seed := int64(7)
+	selected, _ := arrangeTasks(tasks, TaskSelection{Count: 3, Stratify: true, Seed: &seed})
+
+	kinds := make(map[string]int)
+	for _, task := range selected {
+		kinds[taskKind(task)]++
+	}
+	if kinds[taskKind(ChoiceTask{})] != 2 || kinds[taskKind(CheckedTextTask{})] != 1 {
+		t.Errorf("expected 2 choice tasks and 1 checked text task, got %v", kinds)
+	}
+
+	for _, task := range selected {
+		choice, ok := task.(ChoiceTask)
+		if !ok {
+			continue
+		}
+		var original ChoiceTask
+		for _, task := range tasks {
+			if task.GetName() == choice.Name {
+				original = task.(ChoiceTask)
+			}
+		}
+		if choice.Options[choice.AnswerIdx] != original.Options[original.AnswerIdx] {
+			t.Errorf("%s: the correct option is lost after shuffling", choice.Name)
+		}
+	}
+}
@@ -5,17 +5,21 @@ import "time"
 type State interface {
 	Deadline() time.Time
 
+	// setDeadline moves the deadline: used by the host controls.
+	setDeadline(deadline time.Time)
+
 	isState() // an unexported marker method so we don't have scary interface{}s floating around
 }
 
 // An AwaitingPlayersState is an initial session state during which the game is not yet started.
 // New players can discover the session via its invite code or session id, only the latter of which is permanent.
-// (The invite code expires once the game starts — or the session is closed before it starts if the owner quits.)
+// (The invite code expires once the game starts — or the session is closed if it stays without its owner
+// for its NoOwnerTimeout.)
 type AwaitingPlayersState struct {
 	// A short code used for session discovery.
 	inviteCode InviteCode
 
-	// When the session expires, should the owner fail to join it before.
+	// When the session expires, should the owner not join the lobby before.
 	deadline time.Time
 
 	// A set of players who expressed their readiness.
@@ -23,19 +27,16 @@ type AwaitingPlayersState struct {
 
 	// Whether all players need to be ready before the game can start.
 	requireReady bool
-
-	// The creator of the session.
-	// While waiting for players, they have additional privileges: for exmaple, they can remove people from the session.
-	// Of course, with great power comes great responsibility: if this player leaves, the session will be closed.
-	//
-	// NOTE: the owner may not have yet connected to the session!
-	owner ClientID
 }
 
 func (s *AwaitingPlayersState) Deadline() time.Time {
 	return s.deadline
 }
 
+func (s *AwaitingPlayersState) setDeadline(deadline time.Time) {
+	s.deadline = deadline
+}
+
 func (*AwaitingPlayersState) isState() {}
 
 // A GameStartedState is a state right after the game starts.
@@ -49,6 +50,10 @@ func (s *GameStartedState) Deadline() time.Time {
 	return s.deadline
 }
 
+func (s *GameStartedState) setDeadline(deadline time.Time) {
+	s.deadline = deadline
+}
+
 func (*GameStartedState) isState() {}
 
 // A TaskStartedState corresponds to a session state while a game task is in progress.
@@ -67,12 +72,24 @@ type TaskStartedState struct {
 
 	// A set of players that expressed their readiness.
 	ready map[PlayerID]struct{}
+
+	// A set of players that sent anything during the task.
+	// Used to detect idle players.
+	active map[PlayerID]struct{}
+
+	// A set of players that joined after the task had started.
+	// They can't answer and aren't waited for.
+	latecomers map[PlayerID]struct{}
 }
 
 func (s *TaskStartedState) Deadline() time.Time {
 	return s.deadline
 }
 
+func (s *TaskStartedState) setDeadline(deadline time.Time) {
+	s.deadline = deadline
+}
+
 func (*TaskStartedState) isState() {}
 
 // A PollStartedState is a state while players vote for each other's answers.
@@ -97,6 +114,10 @@ func (s *PollStartedState) Deadline() time.Time {
 	return s.deadline
 }
 
+func (s *PollStartedState) setDeadline(deadline time.Time) {
+	s.deadline = deadline
+}
+
 func (*PollStartedState) isState() {}
 
 // A TaskEndedState is a state right after a task ends.
@@ -121,12 +142,34 @@ func (s *TaskEndedState) Deadline() time.Time {
 	return s.deadline
 }
 
+func (s *TaskEndedState) setDeadline(deadline time.Time) {
+	s.deadline = deadline
+}
+
 func (*TaskEndedState) isState() {}
 
 // "But," you may ask, "what about the game-ended state?"
 // We remove a session once it reaches this state, so representing it is unnecessary.
 // Thus we don't.
 
+// StateName returns a short human-readable name of the state kind.
+func StateName(state State) string {
+	switch state.(type) {
+	case *AwaitingPlayersState:
+		return "awaiting-players"
+	case *GameStartedState:
+		return "game-started"
+	case *TaskStartedState:
+		return "task-started"
+	case *PollStartedState:
+		return "poll-started"
+	case *TaskEndedState:
+		return "task-ended"
+	default:
+		return "unknown"
+	}
+}
+
 // Assert all of these are states (to catch missing methods).
 var (
 	_ State = &AwaitingPlayersState{}
@@ -1,10 +1,8 @@
 package session
 
 import (
-	"crypto/rand"
 	"fmt"
-	"log"
-	"math/big"
+	"party-buddy/internal/metrics"
 	"sync"
 	"time"
 )
@@ -29,6 +27,11 @@ func (s *SyncStorage) Atomically(f func(s *UnsafeStorage)) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	start := time.Now()
+	defer func() {
+		metrics.StorageLockHeldSeconds.Observe(time.Since(start).Seconds())
+	}()
+
 	f(&s.inner)
 }
 
@@ -112,6 +115,7 @@ func (s *UnsafeStorage) newSession(
 	requireReady bool,
 	playersMax int,
 	deadline time.Time,
+	opts SessionOptions,
 ) (sid SessionID, code InviteCode, updateChan chan updateMsg, err error) {
 	code, err = s.newInviteCode()
 	if err != nil {
@@ -132,39 +136,28 @@ func (s *UnsafeStorage) newSession(
 			deadline:     deadline,
 			playersReady: make(map[PlayerID]struct{}),
 			requireReady: requireReady,
-			owner:        owner,
 		},
+		owner:      owner,
 		scoreboard: make(map[PlayerID]Score),
+		createdAt:  time.Now(),
+		listed:     opts.Listed,
+		timings:    opts.Timings.withDefaults(),
+		idleTasks:  make(map[PlayerID]int),
+		replayLogs: make(map[PlayerID]*replayLog),
+
+		disconnected: make(map[PlayerID]time.Time),
+
+		lateJoin:      opts.LateJoin,
+		lateJoinScore: opts.LateJoinScore,
 	}
 	s.inviteCodes[code] = sid
 
 	updateChan = make(chan updateMsg)
 	s.updaters[sid] = updateChan
 
-	// shuffle options in ChoiceTasks
-	tasks := s.sessions[sid].game.Tasks
-	for taskIdx, task := range tasks {
-		if task, ok := task.(ChoiceTask); ok {
-			for i := range task.Options {
-				offset, err := rand.Int(rand.Reader, big.NewInt(int64(len(task.Options)-i)))
-				if err != nil {
-					log.Panicf("could not generate a random number for shuffling ChoiceTask options: %s", err)
-				}
-
-				j := i + int(offset.Int64())
-				task.Options[i], task.Options[j] = task.Options[j], task.Options[i]
-
-				switch task.AnswerIdx {
-				case i:
-					task.AnswerIdx = j
-				case j:
-					task.AnswerIdx = i
-				}
-			}
-
-			tasks[taskIdx] = task
-		}
-	}
+	// pick the tasks and shuffle options in ChoiceTasks
+	session := s.sessions[sid]
+	session.game.Tasks, session.taskSeed = arrangeTasks(game.Tasks, opts.Tasks)
 
 	return
 }
@@ -204,6 +197,15 @@ func (s *UnsafeStorage) PlayerByClientID(sid SessionID, clientID ClientID) (Play
 	return session.players[playerID], nil
 }
 
+// ClientIsPlayer returns true iff the client is a player in a session.
+func (s *UnsafeStorage) ClientIsPlayer(sid SessionID, clientID ClientID) bool {
+	if session := s.sessions[sid]; session != nil {
+		_, ok := session.clients[clientID]
+		return ok
+	}
+	return false
+}
+
 // PlayerByID returns a player in a session with the given playerID.
 func (s *UnsafeStorage) PlayerByID(sid SessionID, playerID PlayerID) (player Player, err error) {
 	session, err := s.sessionByID(sid)
@@ -246,6 +248,14 @@ func (s *UnsafeStorage) PlayersMax(sid SessionID) int {
 	return 0
 }
 
+// SessionTimings returns the timings of a session.
+func (s *UnsafeStorage) SessionTimings(sid SessionID) Timings {
+	if session := s.sessions[sid]; session != nil {
+		return session.timings
+	}
+	return Timings{}.withDefaults()
+}
+
 // PlayerTxs returns a Tx channel for each player in a session.
 func (s *UnsafeStorage) PlayerTxs(sid SessionID) (txs []TxChan) {
 	s.ForEachPlayer(sid, func(player Player) {
@@ -426,10 +436,66 @@ func (s *UnsafeStorage) removePlayer(sid SessionID, clientID ClientID) (PlayerID
 	delete(session.players, playerID)
 	delete(session.clients, clientID)
 	delete(session.scoreboard, playerID)
+	delete(session.idleTasks, playerID)
+	delete(session.replayLogs, playerID)
+	delete(session.disconnected, playerID)
 
 	return playerID, true
 }
 
+// recordTaskActivity updates the players' idle task counters after a task ends.
+// The counters of the active players are reset, the others' are incremented.
+//
+// Returns the updated counters of the idle players.
+func (s *UnsafeStorage) recordTaskActivity(sid SessionID, active map[PlayerID]struct{}) map[PlayerID]int {
+	session := s.sessions[sid]
+	if session == nil {
+		return nil
+	}
+
+	idle := make(map[PlayerID]int)
+	for playerID := range session.players {
+		if _, ok := active[playerID]; ok {
+			delete(session.idleTasks, playerID)
+		} else {
+			session.idleTasks[playerID]++
+			idle[playerID] = session.idleTasks[playerID]
+		}
+	}
+
+	return idle
+}
+
+// resetIdleTasks resets the player's idle task counter.
+func (s *UnsafeStorage) resetIdleTasks(sid SessionID, playerID PlayerID) {
+	if session := s.sessions[sid]; session != nil {
+		delete(session.idleTasks, playerID)
+	}
+}
+
+// setPlayerTx replaces the Tx channel of a player who has reconnected.
+func (s *UnsafeStorage) setPlayerTx(sid SessionID, id PlayerID, tx TxChan) bool {
+	if session := s.sessions[sid]; session != nil {
+		if player, ok := session.players[id]; ok {
+			player.tx = tx
+			session.players[id] = player
+			delete(session.disconnected, id)
+			return true
+		}
+	}
+
+	return false
+}
+
+func (s *UnsafeStorage) setPlayerLegacy(sid SessionID, id PlayerID, legacy bool) {
+	if session := s.sessions[sid]; session != nil {
+		if player, ok := session.players[id]; ok {
+			player.legacy = legacy
+			session.players[id] = player
+		}
+	}
+}
+
 func (s *UnsafeStorage) closePlayerTx(sid SessionID, id PlayerID) bool {
 	if session := s.sessions[sid]; session != nil {
 		if player, ok := session.players[id]; ok {
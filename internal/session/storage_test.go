@@ -0,0 +1,24 @@
+package session
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSessionTimingsDefaults(t *testing.T) {
+	s := NewUnsafeStorage()
+	opts := SessionOptions{Timings: Timings{TaskEndTimeout: 30 * time.Second}}
+	sid, _, _, err := s.newSession(&Game{}, ClientID{}, false, 8, time.Now(), opts)
+	if err != nil {
+		t.Fatalf("could not create a session: %v", err)
+	}
+
+	want := Timings{
+		NoOwnerTimeout:     DefaultNoOwnerTimeout,
+		GameStartedTimeout: DefaultGameStartedTimeout,
+		TaskEndTimeout:     30 * time.Second,
+	}
+	if got := s.SessionTimings(sid); got != want {
+		t.Errorf("expected %+v, got %+v", want, got)
+	}
+}
@@ -1,38 +1,45 @@
 package session
 
-import "time"
+import (
+	"fmt"
+	"time"
+)
 
 func (u *sessionUpdater) makeGameStartedState(s *UnsafeStorage, state *AwaitingPlayersState) *GameStartedState {
 	return &GameStartedState{
-		deadline: time.Now().Add(GameStartedTimeout),
+		deadline: time.Now().Add(s.SessionTimings(u.sid).GameStartedTimeout),
 	}
 }
 
 func (u *sessionUpdater) makeFirstTaskStartedState(s *UnsafeStorage, state *GameStartedState) *TaskStartedState {
 	task := s.taskByIdx(u.sid, 0)
 	if task == nil {
-		u.log.Panicf("task 0 not found")
+		panic("task 0 not found")
 	}
 
 	return &TaskStartedState{
-		taskIdx:  0,
-		deadline: time.Now().Add(task.GetTaskDuration()),
-		answers:  make(map[PlayerID]TaskAnswer),
-		ready:    make(map[PlayerID]struct{}),
+		taskIdx:    0,
+		deadline:   time.Now().Add(task.GetTaskDuration()),
+		answers:    make(map[PlayerID]TaskAnswer),
+		ready:      make(map[PlayerID]struct{}),
+		active:     make(map[PlayerID]struct{}),
+		latecomers: make(map[PlayerID]struct{}),
 	}
 }
 
 func (u *sessionUpdater) makeNextTaskStartedState(s *UnsafeStorage, state *TaskEndedState) *TaskStartedState {
 	task := s.taskByIdx(u.sid, state.taskIdx+1)
 	if task == nil {
-		u.log.Panicf("task %d not found", state.taskIdx+1)
+		panic(fmt.Sprintf("task %d not found", state.taskIdx+1))
 	}
 
 	return &TaskStartedState{
-		taskIdx:  state.taskIdx + 1,
-		deadline: time.Now().Add(task.GetTaskDuration()),
-		answers:  make(map[PlayerID]TaskAnswer),
-		ready:    make(map[PlayerID]struct{}),
+		taskIdx:    state.taskIdx + 1,
+		deadline:   time.Now().Add(task.GetTaskDuration()),
+		answers:    make(map[PlayerID]TaskAnswer),
+		ready:      make(map[PlayerID]struct{}),
+		active:     make(map[PlayerID]struct{}),
+		latecomers: make(map[PlayerID]struct{}),
 	}
 }
 
@@ -108,16 +115,16 @@ func (u *sessionUpdater) makePlainTaskEndedState(s *UnsafeStorage, state *TaskSt
 		}
 
 	default:
-		u.log.Panicf(
+		panic(fmt.Sprintf(
 			"cannot make *TaskEndedState from *TaskStartedState: task %d (%T) requires a poll",
 			state.taskIdx,
 			task,
-		)
+		))
 	}
 
 	return &TaskEndedState{
 		taskIdx:  state.taskIdx,
-		deadline: time.Now().Add(TaskEndTimeout),
+		deadline: time.Now().Add(s.SessionTimings(u.sid).TaskEndTimeout),
 		results:  results,
 		winners:  winners,
 	}
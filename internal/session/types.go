@@ -4,6 +4,7 @@ import (
 	"cmp"
 	"crypto/rand"
 	"fmt"
+	"log/slog"
 	"math/big"
 	"strings"
 	"time"
@@ -46,6 +47,10 @@ func (sid SessionID) UUID() uuid.UUID {
 	return uuid.UUID(sid)
 }
 
+func (sid SessionID) NullUUID() uuid.NullUUID {
+	return uuid.NullUUID{UUID: uuid.UUID(sid), Valid: true}
+}
+
 func (id ClientID) UUID() uuid.UUID {
 	return uuid.UUID(id)
 }
@@ -70,6 +75,19 @@ func (id PlayerID) String() string {
 	return fmt.Sprintf("%d", uint32(id))
 }
 
+// LogValue implements slog.LogValuer, so the ids are logged as strings by every handler.
+func (id ImageID) LogValue() slog.Value {
+	return slog.StringValue(id.String())
+}
+
+func (sid SessionID) LogValue() slog.Value {
+	return slog.StringValue(sid.String())
+}
+
+func (id ClientID) LogValue() slog.Value {
+	return slog.StringValue(id.String())
+}
+
 // An InviteCode is a short code used for session discovery.
 // Only valid until the game starts.
 type InviteCode string
@@ -116,9 +134,55 @@ type session struct {
 	bannedClients map[ClientID]struct{}
 	state         State
 	scoreboard    Scoreboard
+	createdAt     time.Time
+
+	// The creator of the session, or whoever the ownership has passed to.
+	// They have additional privileges: for example, they decide when the game starts and can pause it.
+	// If the owner leaves, the ownership passes to the longest-present player.
+	//
+	// NOTE: the owner may not have yet connected to the session!
+	owner ClientID
+
+	// Whether the owner has been one of the players.
+	// Until then the lobby waits for the creator: nobody else can take the ownership over.
+	ownerJoined bool
+
+	// Whether the owner has paused the game, and how much time was left until the deadline when they did.
+	paused    bool
+	remaining time.Duration
+
+	timings Timings
+
+	lateJoin      bool
+	lateJoinScore LateJoinScore
+
+	// The seed used to select the tasks and shuffle the ChoiceTask options.
+	taskSeed int64
+
+	// listed sessions are shown in the lobby browser while they await players.
+	listed bool
+
+	// chatHistory holds the recent chat messages, oldest first.
+	chatHistory []ChatMessage
+	chatMuted   bool
+
+	// idleTasks is the number of consecutive tasks during which a player has sent nothing.
+	idleTasks map[PlayerID]int
+
+	// lastSeq is the sequence number of the latest message kept for replay.
+	lastSeq uint64
+
+	// replayLogs holds the messages recently sent to each player.
+	replayLogs map[PlayerID]*replayLog
+
+	// disconnected holds the players whose connection has dropped
+	// and the time they are removed at unless they reconnect.
+	disconnected map[PlayerID]time.Time
 }
 
 type Game struct {
+	// ID is the stored game's id; it is null for the games sent along with the session request.
+	ID          uuid.NullUUID
 	Name        string
 	Description string
 	ImageID     ImageID
@@ -126,11 +190,55 @@ type Game struct {
 	Tasks       []Task
 }
 
+// SessionOptions are the optional settings chosen by the owner when the session is created.
+type SessionOptions struct {
+	// Listed makes the session visible in the lobby browser.
+	Listed bool
+
+	Timings Timings
+
+	// LateJoin lets players join after the game has started. They start with the LateJoinScore.
+	LateJoin      bool
+	LateJoinScore LateJoinScore
+
+	Tasks TaskSelection
+}
+
+// Timings tell how long a session stays in the states whose deadlines don't depend on the tasks.
+// The zero durations are replaced with the defaults.
+type Timings struct {
+	// How long a lobby without its owner (empty, or not yet joined by its creator) is kept before it's closed.
+	NoOwnerTimeout time.Duration
+
+	// How long the players have to prepare before the first task.
+	GameStartedTimeout time.Duration
+
+	// How long the results of a task are shown before the next one starts.
+	TaskEndTimeout time.Duration
+}
+
+func (t Timings) withDefaults() Timings {
+	if t.NoOwnerTimeout == 0 {
+		t.NoOwnerTimeout = DefaultNoOwnerTimeout
+	}
+	if t.GameStartedTimeout == 0 {
+		t.GameStartedTimeout = DefaultGameStartedTimeout
+	}
+	if t.TaskEndTimeout == 0 {
+		t.TaskEndTimeout = DefaultTaskEndTimeout
+	}
+	return t
+}
+
 type Player struct {
 	ID       PlayerID
 	ClientID ClientID
 	Nickname string
 	tx       TxChan
+
+	// legacy is true if the client speaks a protocol predating the lobby ownership and the host controls
+	// (before partybuddy.v3): it's never told who the owner is.
+	legacy bool
 }
 
 type PollOption struct {
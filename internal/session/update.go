@@ -3,7 +3,9 @@ package session
 import (
 	"context"
 	"fmt"
-	"log"
+	"log/slog"
+	"party-buddy/internal/metrics"
+	"strings"
 	"time"
 
 	"github.com/jackc/pgx/v5"
@@ -19,6 +21,9 @@ type updateMsgPlayerAdded struct {
 	ctx         context.Context
 	playerID    PlayerID
 	reconnected bool
+
+	// lastSeq is the sequence number of the last message the reconnected client has received, if known
+	lastSeq *uint64
 }
 
 func (*updateMsgPlayerAdded) isUpdateMsg() {}
@@ -26,10 +31,19 @@ func (*updateMsgPlayerAdded) isUpdateMsg() {}
 type updateMsgRemovePlayer struct {
 	ctx      context.Context
 	playerID PlayerID
+	tx       TxChan
 }
 
 func (*updateMsgRemovePlayer) isUpdateMsg() {}
 
+type updateMsgDisconnectPlayer struct {
+	ctx      context.Context
+	playerID PlayerID
+	tx       TxChan
+}
+
+func (*updateMsgDisconnectPlayer) isUpdateMsg() {}
+
 type updateMsgChangeStateTo struct {
 	nextState State
 }
@@ -54,14 +68,86 @@ type updateMsgUpdTaskAnswer struct {
 
 func (*updateMsgUpdTaskAnswer) isUpdateMsg() {}
 
+type updateMsgCloseSession struct {
+	ctx    context.Context
+	reason string
+}
+
+func (*updateMsgCloseSession) isUpdateMsg() {}
+
+type updateMsgKickPlayer struct {
+	ctx      context.Context
+	playerID PlayerID
+	ban      bool
+}
+
+func (*updateMsgKickPlayer) isUpdateMsg() {}
+
+type updateMsgChat struct {
+	ctx      context.Context
+	playerID PlayerID
+	text     string
+}
+
+func (*updateMsgChat) isUpdateMsg() {}
+
+type updateMsgReaction struct {
+	ctx      context.Context
+	playerID PlayerID
+	reaction string
+}
+
+func (*updateMsgReaction) isUpdateMsg() {}
+
+type updateMsgMuteChat struct {
+	ctx   context.Context
+	muted bool
+}
+
+func (*updateMsgMuteChat) isUpdateMsg() {}
+
+type updateMsgTransferOwner struct {
+	ctx      context.Context
+	playerID PlayerID
+	to       PlayerID
+}
+
+func (*updateMsgTransferOwner) isUpdateMsg() {}
+
+type updateMsgPause struct {
+	ctx      context.Context
+	playerID PlayerID
+	paused   bool
+}
+
+func (*updateMsgPause) isUpdateMsg() {}
+
+type updateMsgSkipTask struct {
+	ctx      context.Context
+	playerID PlayerID
+}
+
+func (*updateMsgSkipTask) isUpdateMsg() {}
+
+type updateMsgAddTime struct {
+	ctx      context.Context
+	playerID PlayerID
+	d        time.Duration
+}
+
+func (*updateMsgAddTime) isUpdateMsg() {}
+
 // # Run logic
 
 type sessionUpdater struct {
 	m        *Manager
 	sid      SessionID
 	rx       <-chan updateMsg
-	log      *log.Logger
+	log      *slog.Logger
 	deadline *time.Timer
+
+	// reconnect fires when the earliest disconnected player's grace period ends.
+	reconnect *time.Timer
 }
 
 func (u *sessionUpdater) run(ctx context.Context) error {
@@ -79,28 +165,66 @@ func (u *sessionUpdater) run(ctx context.Context) error {
 				u.deadlineExpired(ctx, s)
 			})
 
+		case <-u.reconnect.C:
+			u.m.storage.Atomically(func(s *UnsafeStorage) {
+				u.reconnectExpired(ctx, s)
+			})
+
 		case msg := <-u.rx:
 			if msg == nil {
-				u.log.Println("the updater channel has been closed, stopping")
+				u.log.Info("the updater channel has been closed, stopping")
 				return nil
 			}
 
-			u.log.Printf("handling %T", msg)
+			u.log.Debug("handling an update", "msg", fmt.Sprintf("%T", msg))
+			start := time.Now()
 
 			u.m.storage.Atomically(func(s *UnsafeStorage) {
 				switch msg := msg.(type) {
 				case *updateMsgPlayerAdded:
-					u.playerAdded(ctx, msg.ctx, s, msg.playerID, msg.reconnected)
+					u.playerAdded(ctx, msg.ctx, s, msg.playerID, msg.reconnected, msg.lastSeq)
 				case *updateMsgRemovePlayer:
+					if player, err := s.PlayerByID(u.sid, msg.playerID); err == nil && player.tx != nil && player.tx != msg.tx {
+						u.log.Debug("ignoring removePlayer for a reconnected player", "player_id", msg.playerID)
+						break
+					}
 					u.removePlayer(ctx, msg.ctx, s, msg.playerID)
+				case *updateMsgDisconnectPlayer:
+					if player, err := s.PlayerByID(u.sid, msg.playerID); err == nil && player.tx != msg.tx {
+						u.log.Debug("ignoring disconnectPlayer for a reconnected player", "player_id", msg.playerID)
+						break
+					}
+					u.disconnectPlayer(ctx, msg.ctx, s, msg.playerID)
 				case *updateMsgChangeStateTo:
 					u.changeStateTo(ctx, ctx, s, msg.nextState)
 				case *updateMsgSetPlayerReady:
 					u.setPlayerReady(ctx, ctx, s, msg.playerID, msg.ready)
 				case *updateMsgUpdTaskAnswer:
 					u.updateAnswer(ctx, msg.ctx, s, msg.playerID, msg.answer, msg.ready, msg.taskIdx)
+				case *updateMsgCloseSession:
+					u.closeByAdmin(ctx, msg.ctx, s, msg.reason)
+				case *updateMsgKickPlayer:
+					u.kickPlayer(ctx, msg.ctx, s, msg.playerID, msg.ban)
+				case *updateMsgChat:
+					u.chatMessage(msg.ctx, s, msg.playerID, msg.text)
+				case *updateMsgReaction:
+					u.reaction(msg.ctx, s, msg.playerID, msg.reaction)
+				case *updateMsgMuteChat:
+					u.muteChat(msg.ctx, s, msg.muted)
+				case *updateMsgTransferOwner:
+					u.transferOwner(ctx, msg.ctx, s, msg.playerID, msg.to)
+				case *updateMsgPause:
+					u.pause(msg.ctx, s, msg.playerID, msg.paused)
+				case *updateMsgSkipTask:
+					u.skipTask(ctx, msg.ctx, s, msg.playerID)
+				case *updateMsgAddTime:
+					u.addTime(msg.ctx, s, msg.playerID, msg.d)
 				}
 			})
+
+			metrics.UpdaterHandlingSeconds.
+				WithLabelValues(strings.TrimPrefix(fmt.Sprintf("%T", msg), "*session.")).
+				Observe(time.Since(start).Seconds())
 		}
 	}
 }
@@ -111,6 +235,7 @@ func (u *sessionUpdater) playerAdded(
 	s *UnsafeStorage,
 	playerID PlayerID,
 	reconnected bool,
+	lastSeq *uint64,
 ) {
 	state := s.sessionState(u.sid)
 	if state == nil {
@@ -119,35 +244,59 @@ func (u *sessionUpdater) playerAdded(
 
 	player, err := s.PlayerByID(u.sid, playerID)
 	if err != nil {
-		u.log.Printf("could not handle added player: %s", err)
+		u.log.Warn("could not handle added player", "err", err)
 		return
 	}
 
+	// a player that has just (re)connected is certainly not idle
+	s.resetIdleTasks(u.sid, playerID)
+
 	var inviteCode *InviteCode
 
 	if state, ok := state.(*AwaitingPlayersState); ok {
-		if state.owner == player.ClientID {
-			u.log.Printf("the owner %s has joined the session", state.owner)
-
-			// the owner has at last joined the session
+		if !s.ownerPresent(u.sid) && s.ownerHasJoined(u.sid) {
+			u.log.Info("the player has joined a lobby the owner has left and becomes the owner", "client_id", player.ClientID)
+			s.setOwner(u.sid, player.ClientID)
+		}
+		if s.isOwnerClient(u.sid, player.ClientID) {
+			// the lobby has its owner now
+			s.setOwnerJoined(u.sid)
 			u.deadline.Stop()
 		}
 
 		inviteCode = &state.inviteCode
+	} else if !reconnected {
+		u.lateJoined(s, player)
+	}
+
+	if player.legacy && s.Paused(u.sid) {
+		// they couldn't tell the game is paused
+		u.log.Info("a legacy player has joined a paused game", "player_id", player.ID)
+		u.resume(msgCtx, s)
 	}
 
 	game, _ := s.SessionGame(u.sid)
-	joined := u.m.makeMsgJoined(msgCtx, player.ID, u.sid, inviteCode, &game, s.PlayersMax(u.sid))
+	joined := u.m.makeMsgJoined(msgCtx, player.ID, u.sid, inviteCode, &game, s.PlayersMax(u.sid), state)
 	u.m.sendToPlayer(player.tx, joined)
+	u.m.sendToPlayer(player.tx, u.m.makeMsgChatHistory(msgCtx, s.ChatMuted(u.sid), s.ChatHistory(u.sid)))
+
+	if reconnected && lastSeq != nil {
+		// if the client tells us what it has seen, we can send exactly what it has missed
+		if missed, ok := s.missedMessages(u.sid, playerID, *lastSeq); ok {
+			u.log.Info("replaying missed messages", "player_id", playerID, "last_seq", *lastSeq, "count", len(missed))
+			for _, msg := range missed {
+				u.m.sendToPlayer(player.tx, msg)
+			}
+			return
+		}
+	}
 
 	gameStatus := u.m.makeMsgGameStatus(msgCtx, s.Players(u.sid))
 
 	if reconnected {
-		u.m.sendToPlayer(player.tx, gameStatus)
+		u.m.sendLogged(s, u.sid, player, gameStatus)
 	} else {
-		for _, tx := range s.PlayerTxs(u.sid) {
-			u.m.sendToPlayer(tx, gameStatus)
-		}
+		u.m.sendToAllPlayers(s, u.sid, gameStatus)
 	}
 
 	var stateMessage ServerTx
@@ -163,9 +312,9 @@ func (u *sessionUpdater) playerAdded(
 		switch task.(type) {
 		case PhotoTask:
 			answer, ok := state.answers[playerID]
-			if !ok {
-				u.log.Panicf("no image registered for player %s (nickname=%q, clientID=%s)",
-					playerID, player.Nickname, player.ClientID)
+			if !ok && !state.isLatecomer(playerID) {
+				panic(fmt.Sprintf("no image registered for player %s (nickname=%q, clientID=%s)",
+					playerID, player.Nickname, player.ClientID))
 			}
 			stateMessage = u.m.makeMsgTaskStart(msgCtx, state.taskIdx, state.deadline, task, answer)
 
@@ -187,7 +336,16 @@ func (u *sessionUpdater) playerAdded(
 			state.results,
 		)
 	}
-	u.m.sendToPlayer(player.tx, stateMessage)
+	u.m.sendLogged(s, u.sid, player, stateMessage)
+
+	if owner, ok := s.ownerPlayer(u.sid); ok {
+		u.m.sendLogged(s, u.sid, player, u.m.makeMsgOwnerChanged(msgCtx, owner.ID))
+	}
+
+	if s.Paused(u.sid) {
+		// the deadline in the state message is stale
+		u.m.sendLogged(s, u.sid, player, u.makeMsgDeadlineChanged(msgCtx, s))
+	}
 }
 
 func (u *sessionUpdater) removePlayer(
@@ -198,18 +356,16 @@ func (u *sessionUpdater) removePlayer(
 ) {
 	player, err := s.PlayerByID(u.sid, playerID)
 	if err != nil {
-		u.log.Printf("received removePlayer for unknown player: %s", err)
+		u.log.Warn("received removePlayer for unknown player", "err", err)
 		return
 	}
 
-	if state, ok := s.sessionState(u.sid).(*AwaitingPlayersState); ok && state.owner == player.ClientID {
-		u.log.Println("the owner has left the session, closing")
+	if s.AwaitingPlayers(u.sid) && s.isOwnerClient(u.sid, player.ClientID) && s.legacyPlayersPresent(u.sid) {
+		u.log.Info("the owner has left a lobby with legacy players, closing")
 
 		// note that we have to send an error to the owner too.
 		// therefore we don't remove them here.
-		for _, tx := range s.PlayerTxs(u.sid) {
-			u.m.sendToPlayer(tx, u.m.makeMsgError(msgCtx, ErrOwnerLeft))
-		}
+		u.m.sendErrorToAllPlayers(msgCtx, s, u.sid, ErrOwnerLeft)
 		u.changeStateTo(ctx, msgCtx, s, nil)
 		return
 	}
@@ -218,14 +374,15 @@ func (u *sessionUpdater) removePlayer(
 	s.removePlayer(u.sid, player.ClientID)
 
 	if !s.AwaitingPlayers(u.sid) && s.PlayerCount(u.sid) == 0 {
-		u.log.Println("no players left in the session, closing")
+		u.log.Info("no players left in the session, closing")
 		u.changeStateTo(ctx, msgCtx, s, nil)
 		return
 	}
 
-	gameStatus := u.m.makeMsgGameStatus(msgCtx, s.Players(u.sid))
-	for _, tx := range s.PlayerTxs(u.sid) {
-		u.m.sendToPlayer(tx, gameStatus)
+	u.m.sendToAllPlayers(s, u.sid, u.m.makeMsgGameStatus(msgCtx, s.Players(u.sid)))
+
+	if s.isOwnerClient(u.sid, player.ClientID) {
+		u.ownerLeft(msgCtx, s)
 	}
 
 	switch state := s.sessionState(u.sid).(type) {
@@ -246,6 +403,39 @@ func (u *sessionUpdater) removePlayer(
 	}
 }
 
+// closeByAdmin closes the session on an administrator's request.
+func (u *sessionUpdater) closeByAdmin(ctx context.Context, msgCtx context.Context, s *UnsafeStorage, reason string) {
+	if !s.SessionExists(u.sid) {
+		return
+	}
+
+	u.log.Info("closed by an administrator", "reason", reason)
+	u.m.sendErrorToAllPlayers(msgCtx, s, u.sid, &ClosedByAdminError{Reason: reason})
+	u.changeStateTo(ctx, msgCtx, s, nil)
+}
+
+// kickPlayer removes a player on an administrator's request, optionally banning their client.
+func (u *sessionUpdater) kickPlayer(
+	ctx context.Context,
+	msgCtx context.Context,
+	s *UnsafeStorage,
+	playerID PlayerID,
+	ban bool,
+) {
+	player, err := s.PlayerByID(u.sid, playerID)
+	if err != nil {
+		u.log.Warn("received kickPlayer for unknown player", "err", err)
+		return
+	}
+
+	u.log.Info("kicking player", "player_id", playerID, "ban", ban)
+	if ban {
+		s.banClient(u.sid, player.ClientID)
+	}
+	u.m.sendToPlayer(player.tx, u.m.makeMsgError(msgCtx, ErrKicked))
+	u.removePlayer(ctx, msgCtx, s, playerID)
+}
+
 // changeStateTo changes the current session state to the nextState.
 // If the nextState is nil, the session is closed.
 func (u *sessionUpdater) changeStateTo(
@@ -262,13 +452,14 @@ func (u *sessionUpdater) changeStateTo(
 			return tx.Commit(ctx)
 		})
 		if err != nil {
-			u.log.Printf("could not close the session: %s", err)
+			u.log.Error("could not close the session", "err", err)
 		}
 
 		return
 	}
 
-	u.log.Printf("switching state to %T", nextState)
+	u.log.Info("switching state", "state", StateName(nextState))
+	s.clearPause(u.sid)
 	u.deadline.Reset(nextState.Deadline().Sub(time.Now()))
 
 	switch nextState := nextState.(type) {
@@ -281,7 +472,7 @@ func (u *sessionUpdater) changeStateTo(
 	case *TaskStartedState:
 		task := s.taskByIdx(u.sid, nextState.taskIdx)
 		if task == nil {
-			u.log.Panicf("task %d not found", nextState.taskIdx)
+			panic(fmt.Sprintf("task %d not found", nextState.taskIdx))
 		}
 		switch task.(type) {
 		case PhotoTask:
@@ -305,7 +496,7 @@ func (u *sessionUpdater) changeStateTo(
 				return nil
 			})
 			if err != nil {
-				u.log.Printf("could not start a PhotoTask: %s", err)
+				u.log.Error("could not start a PhotoTask", "err", err)
 				u.m.sendErrorToAllPlayers(msgCtx, s, u.sid, ErrInternal)
 				u.m.db.AcquireTx(ctx, func(tx pgx.Tx) error {
 					u.m.closeSession(ctx, s, tx, u.sid)
@@ -315,13 +506,11 @@ func (u *sessionUpdater) changeStateTo(
 				return
 			}
 			s.ForEachPlayer(u.sid, func(p Player) {
-				u.m.sendToPlayer(p.tx, u.m.makeMsgTaskStart(msgCtx, nextState.taskIdx, nextState.deadline, task, nextState.answers[p.ID]))
+				u.m.sendLogged(s, u.sid, p, u.m.makeMsgTaskStart(msgCtx, nextState.taskIdx, nextState.deadline, task, nextState.answers[p.ID]))
 			})
 
 		default:
-			for _, tx := range s.PlayerTxs(u.sid) {
-				u.m.sendToPlayer(tx, u.m.makeMsgTaskStart(msgCtx, nextState.taskIdx, nextState.deadline, task, nil))
-			}
+			u.m.sendToAllPlayers(s, u.sid, u.m.makeMsgTaskStart(msgCtx, nextState.taskIdx, nextState.deadline, task, nil))
 		}
 
 	case *PollStartedState:
@@ -356,7 +545,7 @@ func (u *sessionUpdater) setPlayerReady(
 	}
 
 	if _, err := s.PlayerByID(u.sid, playerID); err != nil {
-		u.log.Printf("could not set player readiness: %s", err)
+		u.log.Warn("could not set player readiness", "err", err)
 		return
 	}
 
@@ -381,7 +570,7 @@ func (u *sessionUpdater) updateAnswer(
 
 	player, err := s.PlayerByID(u.sid, playerID)
 	if err != nil {
-		u.log.Printf("could not update the answer for task %d: %s", taskIdx, err)
+		u.log.Warn("could not update the answer", "task_idx", taskIdx, "err", err)
 		return
 	}
 
@@ -395,6 +584,12 @@ func (u *sessionUpdater) updateAnswer(
 		return
 	}
 
+	if state.isLatecomer(playerID) {
+		u.rejectLatecomerAnswer(msgCtx, player)
+		return
+	}
+
+	state.active[playerID] = struct{}{}
 	if answer != nil {
 		state.answers[playerID] = answer
 	}
@@ -402,6 +597,11 @@ func (u *sessionUpdater) updateAnswer(
 }
 
 func (u *sessionUpdater) deadlineExpired(ctx context.Context, s *UnsafeStorage) {
+	if s.Paused(u.sid) {
+		// the timer fired right before the game was paused
+		return
+	}
+
 	switch state := s.sessionState(u.sid).(type) {
 	case *AwaitingPlayersState:
 		u.m.sendErrorToAllPlayers(ctx, s, u.sid, ErrNoOwnerTimeout)
@@ -411,7 +611,7 @@ func (u *sessionUpdater) deadlineExpired(ctx context.Context, s *UnsafeStorage)
 		u.changeStateTo(ctx, ctx, s, u.makeFirstTaskStartedState(s, state))
 
 	case *TaskStartedState:
-		u.finishTask(ctx, ctx, s, state)
+		u.finishTask(ctx, ctx, s, state, false)
 
 	case *PollStartedState:
 		u.changeStateTo(ctx, ctx, s, u.makePollTaskEndedState(s, state))
@@ -444,10 +644,7 @@ func (u *sessionUpdater) setPlayerStartReady(
 		delete(state.playersReady, playerID)
 	}
 
-	waiting := u.m.makeMsgWaiting(msgCtx, state.playersReady)
-	for _, tx := range s.PlayerTxs(u.sid) {
-		u.m.sendToPlayer(tx, waiting)
-	}
+	u.m.sendToAllPlayers(s, u.sid, u.m.makeMsgWaiting(msgCtx, state.playersReady))
 
 	if u.shouldStartGame(s) {
 		u.changeStateTo(ctx, msgCtx, s, u.makeGameStartedState(s, state))
@@ -460,8 +657,8 @@ func (u *sessionUpdater) shouldStartGame(s *UnsafeStorage) (start bool) {
 		return
 	}
 
-	owner, err := s.PlayerByClientID(u.sid, state.owner)
-	if err != nil {
+	owner, ok := s.ownerPlayer(u.sid)
+	if !ok {
 		return
 	}
 	if _, ok := state.playersReady[owner.ID]; !ok {
@@ -494,13 +691,13 @@ func (u *sessionUpdater) setPlayerAnswerReady(
 	}
 
 	for _, player := range s.Players(u.sid) {
-		if _, ok := state.ready[player.ID]; !ok {
+		if _, ok := state.ready[player.ID]; !ok && !state.isLatecomer(player.ID) {
 			return
 		}
 	}
 
-	u.log.Println("all players are ready; moving on")
-	u.finishTask(ctx, msgCtx, s, state)
+	u.log.Info("all players are ready; moving on")
+	u.finishTask(ctx, msgCtx, s, state, false)
 }
 
 func (u *sessionUpdater) setPlayerVote(
@@ -514,16 +711,33 @@ func (u *sessionUpdater) setPlayerVote(
 	// TODO
 }
 
-func (u *sessionUpdater) finishTask(ctx context.Context, msgCtx context.Context, s *UnsafeStorage, state *TaskStartedState) {
+// finishTask ends the task and moves on to its poll or its results.
+//
+// A task skipped by the owner doesn't count towards the players' idle tasks:
+// they might not have had the time to answer.
+func (u *sessionUpdater) finishTask(
+	ctx context.Context,
+	msgCtx context.Context,
+	s *UnsafeStorage,
+	state *TaskStartedState,
+	skipped bool,
+) {
 	task := s.taskByIdx(u.sid, state.taskIdx)
 	if task == nil {
-		u.log.Panicf("task %d not found", state.taskIdx)
+		panic(fmt.Sprintf("task %d not found", state.taskIdx))
 	}
+
+	var idle map[PlayerID]int
+	if !skipped {
+		idle = s.recordTaskActivity(u.sid, state.active)
+	}
+
 	if task.NeedsPoll() {
 		u.changeStateTo(ctx, msgCtx, s, u.makePollStartedState(s, state))
 	} else {
 		u.changeStateTo(ctx, msgCtx, s, u.makePlainTaskEndedState(s, state))
 	}
+	u.handleIdlePlayers(ctx, msgCtx, s, idle)
 }
 
 // finishGame finishes the game normally.
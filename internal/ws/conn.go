@@ -4,13 +4,14 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"party-buddy/internal/metrics"
+	"party-buddy/internal/ratelimit"
 	"party-buddy/internal/schemas/ws"
 	"party-buddy/internal/session"
 	"party-buddy/internal/validate"
 	"party-buddy/internal/ws/converters"
 	"party-buddy/internal/ws/utils"
-	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -19,11 +20,22 @@ import (
 	"github.com/gorilla/websocket"
 )
 
+var (
+	// PongTimeout is how long the connection may stay silent (not even answering pings) before it's considered dead
+	PongTimeout = 60 * time.Second
+
+	// PingPeriod is how often the client is pinged. Must be less than PongTimeout
+	PingPeriod = PongTimeout * 9 / 10
+
+	// WriteTimeout bounds the time spent sending a single message
+	WriteTimeout = 10 * time.Second
+)
+
 type Conn struct {
 	manager *session.Manager
 
-	// wsConn is a WebSocket (ws) connection
-	wsConn *websocket.Conn
+	// transport carries the messages to and from the client
+	transport Transport
 
 	// client is the ClientID to which ws connection is related
 	client session.ClientID
@@ -31,6 +43,21 @@ type Conn struct {
 	// sid is the SessionID to which ws connection is related
 	sid session.SessionID
 
+	// msgLimit limits the messages received from the client
+	msgLimit *ratelimit.Policy
+
+	// chatLimit additionally limits the chat messages and the reactions
+	chatLimit *ratelimit.Policy
+
+	// ip is the client address used for rate limiting
+	ip string
+
+	// protocol is the protocol version and encoding negotiated with the client
+	protocol ws.Protocol
+
+	// codec encodes and decodes the messages according to the protocol
+	codec ws.Codec
+
 	// msgToClientChan is the channel for messages ready to send to client
 	msgToClientChan chan<- ws.RespMessage
 
@@ -51,10 +78,13 @@ type Conn struct {
 	// servDataChan here for closing
 	servDataChan session.TxChan
 
-	mainLog   *log.Logger
-	readerLog *log.Logger
-	writerLog *log.Logger
-	serverLog *log.Logger
+	// parentLog is the logger passed to NewConn
+	parentLog *slog.Logger
+
+	mainLog   *slog.Logger
+	readerLog *slog.Logger
+	writerLog *slog.Logger
+	serverLog *slog.Logger
 
 	// state is the current web socket protocol state
 	state sessionState
@@ -63,46 +93,46 @@ type Conn struct {
 	stateMtx sync.Mutex
 }
 
-func logPrefix(sid session.SessionID, clientID session.ClientID, playerID *session.PlayerID, sub string) string {
-	var prefix strings.Builder
-
-	fmt.Fprintf(&prefix, "ws.Conn(sid %s, client %s", sid, clientID)
-	if playerID != nil {
-		fmt.Fprintf(&prefix, ", player %s", *playerID)
-	}
-	prefix.WriteString("): ")
-
-	if sub != "" {
-		fmt.Fprintf(&prefix, "%s: ", sub)
+// setLoggers derives the connection loggers from the parent logger,
+// attaching the session, client and (once joined) player ids.
+func (c *Conn) setLoggers() {
+	c.mainLog = c.parentLog.With("sid", c.sid, "client_id", c.client, "protocol", c.protocol)
+	if c.playerID != nil {
+		c.mainLog = c.mainLog.With("player_id", *c.playerID)
 	}
-
-	return prefix.String()
+	c.readerLog = c.mainLog.With("component", "reader")
+	c.writerLog = c.mainLog.With("component", "writer")
+	c.serverLog = c.mainLog.With("component", "mgr-rx")
 }
 
 func NewConn(
-	parentLogger *log.Logger,
+	parentLogger *slog.Logger,
 	manager *session.Manager,
-	wsConn *websocket.Conn,
+	transport Transport,
 	clientID session.ClientID,
 	sid session.SessionID,
+	msgLimit *ratelimit.Policy,
+	chatLimit *ratelimit.Policy,
+	ip string,
+	protocol ws.Protocol,
 ) *Conn {
-	mainLog := log.New(parentLogger.Writer(), logPrefix(sid, clientID, nil, ""), parentLogger.Flags())
-	readerLog := log.New(mainLog.Writer(), logPrefix(sid, clientID, nil, "reader"), mainLog.Flags())
-	writerLog := log.New(mainLog.Writer(), logPrefix(sid, clientID, nil, "writer"), mainLog.Flags())
-	serverLog := log.New(mainLog.Writer(), logPrefix(sid, clientID, nil, "mgr-rx"), mainLog.Flags())
-
-	return &Conn{
+	c := &Conn{
 		manager:       manager,
-		wsConn:        wsConn,
+		transport:     transport,
 		client:        clientID,
 		sid:           sid,
+		msgLimit:      msgLimit,
+		chatLimit:     chatLimit,
+		ip:            ip,
+		protocol:      protocol,
+		codec:         protocol.Codec(),
 		msgID:         atomic.Uint32{},
 		stopRequested: atomic.Bool{},
-		mainLog:       mainLog,
-		readerLog:     readerLog,
-		writerLog:     writerLog,
-		serverLog:     serverLog,
+		parentLog:     parentLogger,
 	}
+	c.setLoggers()
+
+	return c
 }
 
 func (c *Conn) StartReadAndWriteConn(f *valgo.ValidationFactory) {
@@ -119,15 +149,12 @@ func (c *Conn) StartReadAndWriteConn(f *valgo.ValidationFactory) {
 	go c.runReader(ctx, servChan)
 	go c.runServeToWriterConverter(ctx, msgChan, servChan)
 	go c.runWriter(ctx, msgChan)
-	c.mainLog.Println("started")
+	c.mainLog.Info("started")
 }
 
 func (c *Conn) setPlayerID(playerID session.PlayerID) {
 	c.playerID = &playerID
-	c.mainLog.SetPrefix(logPrefix(c.sid, c.client, c.playerID, ""))
-	c.readerLog.SetPrefix(logPrefix(c.sid, c.client, c.playerID, "reader"))
-	c.writerLog.SetPrefix(logPrefix(c.sid, c.client, c.playerID, "writer"))
-	c.serverLog.SetPrefix(logPrefix(c.sid, c.client, c.playerID, "mgr-rx"))
+	c.setLoggers()
 }
 
 func (c *Conn) runServeToWriterConverter(
@@ -136,9 +163,9 @@ func (c *Conn) runServeToWriterConverter(
 	servChan <-chan session.ServerTx,
 ) {
 	defer func() {
-		c.serverLog.Println("closing the writer channel")
+		c.serverLog.Debug("closing the writer channel")
 		close(msgChan)
-		c.serverLog.Println("stopping")
+		c.serverLog.Debug("stopping")
 	}()
 
 	for {
@@ -148,14 +175,14 @@ func (c *Conn) runServeToWriterConverter(
 
 		case msg := <-servChan:
 			if msg == nil {
-				c.serverLog.Println("the server channel has been closed, stopping")
+				c.serverLog.Info("the server channel has been closed, stopping")
 				c.stopRequested.Store(true)
 				return
 			}
 
-			c.serverLog.Printf("handling %T received via the server channel", msg)
+			c.serverLog.Debug("handling a message received via the server channel", "msg", fmt.Sprintf("%T", msg))
 			if c.stopRequested.Load() {
-				c.serverLog.Printf("stop requested, skipping message handling")
+				c.serverLog.Debug("stop requested, skipping message handling")
 				continue
 			}
 
@@ -168,20 +195,20 @@ func (c *Conn) runServeToWriterConverter(
 				clientMessage = &errorMsg
 
 			case *session.MsgJoined:
-				joinedMsg := converters.ToMessageJoined(*m)
+				joinedMsg := converters.ToMessageJoined(*m, c.protocol.Version)
 				joinedMsg.RefID = msgIDFromContext(m.Context())
 				clientMessage = &joinedMsg
 
 				c.stateMtx.Lock()
-				c.state = awaitingPlayersState{}
+				c.state = stateFromSession(m.State)
 				c.stateMtx.Unlock()
 
 			case *session.MsgGameStatus:
-				gameStatusMsg := converters.ToMessageGameStatus(*m)
+				gameStatusMsg := converters.ToMessageGameStatus(*m, c.protocol.Version)
 				clientMessage = &gameStatusMsg
 
 			case *session.MsgTaskStart:
-				taskStartMsg := converters.ToMessageTaskStart(*m)
+				taskStartMsg := converters.ToMessageTaskStart(*m, c.sid, c.protocol.Version)
 				clientMessage = &taskStartMsg
 
 				c.stateMtx.Lock()
@@ -189,7 +216,7 @@ func (c *Conn) runServeToWriterConverter(
 				c.stateMtx.Unlock()
 
 			case *session.MsgTaskEnd:
-				taskEndMsg := converters.ToMessageTaskEnd(*m)
+				taskEndMsg := converters.ToMessageTaskEnd(*m, c.sid, c.protocol.Version)
 				clientMessage = &taskEndMsg
 
 				c.stateMtx.Lock()
@@ -197,7 +224,7 @@ func (c *Conn) runServeToWriterConverter(
 				c.stateMtx.Unlock()
 
 			case *session.MsgGameStart:
-				gameStartMsg := converters.ToMessageGameStart(*m)
+				gameStartMsg := converters.ToMessageGameStart(*m, c.protocol.Version)
 				clientMessage = &gameStartMsg
 
 				c.stateMtx.Lock()
@@ -205,7 +232,7 @@ func (c *Conn) runServeToWriterConverter(
 				c.stateMtx.Unlock()
 
 			case *session.MsgWaiting:
-				waitingMsg := converters.ToMessageWaiting(*m)
+				waitingMsg := converters.ToMessageWaiting(*m, c.protocol.Version)
 				clientMessage = &waitingMsg
 
 				c.stateMtx.Lock()
@@ -213,12 +240,40 @@ func (c *Conn) runServeToWriterConverter(
 				c.stateMtx.Unlock()
 
 			case *session.MsgGameEnd:
-				gameEndMsg := converters.ToMessageGameEnd(*m)
+				gameEndMsg := converters.ToMessageGameEnd(*m, c.protocol.Version)
 				clientMessage = &gameEndMsg
+
+			case *session.MsgChat:
+				chatMsg := converters.ToMessageChatMessage(*m, c.protocol.Version)
+				clientMessage = &chatMsg
+
+			case *session.MsgReaction:
+				reactionMsg := converters.ToMessageReaction(*m, c.protocol.Version)
+				clientMessage = &reactionMsg
+
+			case *session.MsgChatMuted:
+				chatMutedMsg := converters.ToMessageChatMuted(*m, c.protocol.Version)
+				clientMessage = &chatMutedMsg
+
+			case *session.MsgChatHistory:
+				chatHistoryMsg := converters.ToMessageChatHistory(*m, c.protocol.Version)
+				clientMessage = &chatHistoryMsg
+
+			case *session.MsgOwnerChanged:
+				ownerChangedMsg := converters.ToMessageOwnerChanged(*m, c.protocol.Version)
+				clientMessage = &ownerChangedMsg
+
+			case *session.MsgDeadlineChanged:
+				deadlineChangedMsg := converters.ToMessageDeadlineChanged(*m, c.protocol.Version)
+				clientMessage = &deadlineChangedMsg
 			}
 
 			if clientMessage == nil {
-				c.serverLog.Println("unknown msg from server")
+				c.serverLog.Warn("unknown msg from server", "msg", fmt.Sprintf("%T", msg))
+				continue
+			}
+			if !c.protocol.Version.Supports(clientMessage.GetKind()) {
+				// the client doesn't know this kind of messages
 				continue
 			}
 			msgChan <- clientMessage
@@ -228,34 +283,57 @@ func (c *Conn) runServeToWriterConverter(
 
 func (c *Conn) runWriter(ctx context.Context, msgChan <-chan ws.RespMessage) {
 	defer func() {
-		c.writerLog.Println("stopping")
-		properWSClose(c.wsConn)
+		c.writerLog.Debug("stopping")
+		c.transport.Close()
 	}()
 
+	ticker := time.NewTicker(PingPeriod)
+	defer ticker.Stop()
+
 	for {
 		select {
 		case <-ctx.Done():
 			return
 
+		case <-ticker.C:
+			if err := c.transport.Ping(); err != nil {
+				c.writerLog.Info("could not send a ping", "err", err)
+			}
+
 		case msg := <-msgChan:
 			if msg == nil {
-				c.writerLog.Println("the writer channel has been closed, canceling the context")
+				c.writerLog.Debug("the writer channel has been closed, canceling the context")
 				return
 			}
 
 			msgID := ws.MessageID(c.nextMsgID())
 			msg.SetMsgID(msgID)
 
-			c.writerLog.Printf("sending `%s` to the client (msg-id %d)", msg.GetKind(), msgID)
-			err := c.wsConn.WriteJSON(msg)
+			c.writerLog.Debug("sending a message to the client", "kind", msg.GetKind(), "msg_id", msgID)
+			err := c.writeMessage(msg)
 
 			if err != nil {
-				c.writerLog.Printf("encountered an error while sending a message: %s", err)
+				c.writerLog.Warn("encountered an error while sending a message", "err", err)
+				continue
+			}
+
+			metrics.WSMessagesSent.WithLabelValues(string(msg.GetKind())).Inc()
+			if errMsg, ok := msg.(*ws.MessageError); ok {
+				metrics.WSErrors.WithLabelValues(string(errMsg.Code)).Inc()
 			}
 		}
 	}
 }
 
+// writeMessage encodes the message with the connection's codec and sends it
+func (c *Conn) writeMessage(msg ws.RespMessage) error {
+	data, err := c.codec.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	return c.transport.Write(data, c.codec.Binary())
+}
+
 type msgIDKeyType int
 
 var msgIDKey msgIDKeyType
@@ -268,16 +346,17 @@ func msgIDFromContext(ctx context.Context) *ws.MessageID {
 }
 
 func (c *Conn) runReader(ctx context.Context, servDataChan session.TxChan) {
-	defer c.readerLog.Printf("stopping")
+	defer c.readerLog.Debug("stopping")
+
 	for !c.stopRequested.Load() {
-		_, bytes, err := c.wsConn.ReadMessage()
+		bytes, err := c.transport.Read()
 		if err != nil {
-			c.readerLog.Printf("ReadMessage failed: %s", err)
+			c.readerLog.Info("Read failed", "err", err)
 
-			c.dispose(ctx)
+			c.disposeDropped(ctx)
 			return
 		}
-		msg, err := ws.ParseMessage(ctx, bytes)
+		msg, err := ws.ParseMessage(ctx, c.codec, bytes)
 		if err != nil {
 			var errDto *ws.Error
 			errors.As(ws.ParseErrorToMessageError(err), &errDto)
@@ -285,7 +364,7 @@ func (c *Conn) runReader(ctx context.Context, servDataChan session.TxChan) {
 				BaseMessage: utils.GenBaseMessage(&ws.MsgKindError),
 				Error:       *errDto,
 			}
-			c.readerLog.Printf("ParseMessage failed: %s (code `%s`)", err, errDto.Code)
+			c.readerLog.Info("ParseMessage failed", "err", err, "code", errDto.Code)
 			if !c.stopRequested.Load() {
 				c.msgToClientChan <- &rspMessage
 			}
@@ -293,6 +372,44 @@ func (c *Conn) runReader(ctx context.Context, servDataChan session.TxChan) {
 			return
 		}
 
+		metrics.WSMessagesReceived.WithLabelValues(string(msg.GetKind())).Inc()
+
+		if !c.protocol.Version.Supports(msg.GetKind()) {
+			id := msg.GetMsgID()
+			errMsg := utils.GenMessageError(&id, ws.ErrProtoViolation,
+				fmt.Sprintf("unacceptable message kind: `%s`", msg.GetKind()))
+			c.readerLog.Info("received a message not supported by the protocol version",
+				"kind", msg.GetKind(), "code", errMsg.Code)
+			if !c.stopRequested.Load() {
+				c.msgToClientChan <- &errMsg
+			}
+			c.dispose(ctx)
+			return
+		}
+
+		// a client exceeding the limit is told so, but the connection is kept alive
+		if ok, _ := c.msgLimit.Allow(c.client.String(), c.ip); !ok {
+			id := msg.GetMsgID()
+			errMsg := utils.GenMessageError(&id, ws.ErrRateLimited, "too many messages, the message was dropped")
+			c.readerLog.Info("message dropped: rate limit exceeded", "kind", msg.GetKind(), "code", errMsg.Code)
+			if !c.stopRequested.Load() {
+				c.msgToClientChan <- &errMsg
+			}
+			continue
+		}
+
+		if isChatMessage(msg) {
+			if ok, _ := c.chatLimit.Allow(c.client.String(), c.ip); !ok {
+				id := msg.GetMsgID()
+				errMsg := utils.GenMessageError(&id, ws.ErrRateLimited, "too many chat messages, the message was dropped")
+				c.readerLog.Info("chat message dropped: rate limit exceeded", "kind", msg.GetKind(), "code", errMsg.Code)
+				if !c.stopRequested.Load() {
+					c.msgToClientChan <- &errMsg
+				}
+				continue
+			}
+		}
+
 		c.stateMtx.Lock()
 		st := c.state
 		c.stateMtx.Unlock()
@@ -300,8 +417,8 @@ func (c *Conn) runReader(ctx context.Context, servDataChan session.TxChan) {
 			id := msg.GetMsgID()
 			errMsg := utils.GenMessageError(&id, ws.ErrProtoViolation,
 				fmt.Sprintf("the message `%s` is not allowed in the current state", msg.GetKind()))
-			c.readerLog.Printf("received a message `%s`: not allowed in the current state `%s` (error code `%v`)",
-				msg.GetKind(), st.name(), errMsg.Code)
+			c.readerLog.Info("received a message not allowed in the current state",
+				"kind", msg.GetKind(), "state", st.name(), "code", errMsg.Code)
 			if !c.stopRequested.Load() {
 				c.msgToClientChan <- &errMsg
 			}
@@ -310,7 +427,7 @@ func (c *Conn) runReader(ctx context.Context, servDataChan session.TxChan) {
 		}
 
 		ctx = context.WithValue(ctx, msgIDKey, msg.GetMsgID())
-		c.readerLog.Printf("handling message `%s`", msg.GetKind())
+		c.readerLog.Debug("handling a message", "kind", msg.GetKind(), "msg_id", msg.GetMsgID())
 
 		switch m := msg.(type) {
 		case *ws.MessageJoin:
@@ -325,8 +442,32 @@ func (c *Conn) runReader(ctx context.Context, servDataChan session.TxChan) {
 		case *ws.MessageTaskAnswer:
 			c.handleTaskAnswer(ctx, m)
 
+		case *ws.MessageChat:
+			c.handleChat(ctx, m)
+
+		case *ws.MessageReact:
+			c.handleReact(ctx, m)
+
+		case *ws.MessageMuteChat:
+			c.handleMuteChat(ctx, m)
+
+		case *ws.MessageTransferOwner:
+			c.handleTransferOwner(ctx, m)
+
+		case *ws.MessagePause:
+			c.handlePause(ctx, m)
+
+		case *ws.MessageResume:
+			c.handleResume(ctx, m)
+
+		case *ws.MessageSkipTask:
+			c.handleSkipTask(ctx, m)
+
+		case *ws.MessageAddTime:
+			c.handleAddTime(ctx, m)
+
 		default:
-			c.readerLog.Printf("message `%s` ignored: no handler registered", msg.GetKind())
+			c.readerLog.Warn("message ignored: no handler registered", "kind", msg.GetKind())
 		}
 	}
 }
@@ -339,25 +480,38 @@ func properWSClose(wsConn *websocket.Conn) {
 	_ = wsConn.Close()
 }
 
-// dispose is used for closing ws connection and related channels.
+// dispose is used for closing the connection and related channels.
 // There 2 possible cases to call dispose:
 //  1. reader call dispose and the client had NOT joined the session (so it has no PlayerID)
 //  2. reader call dispose and client had joined the session
 //
 // Disconnecting because of server initiative is handled in runServeToWriterConverter
 func (c *Conn) dispose(ctx context.Context) {
+	c.disposeWith(ctx, false)
+}
+
+// disposeDropped is used instead of dispose when the connection has dropped.
+// The player stays in the session for a while, so the client can reconnect and catch up.
+func (c *Conn) disposeDropped(ctx context.Context) {
+	c.disposeWith(ctx, true)
+}
+
+func (c *Conn) disposeWith(ctx context.Context, dropped bool) {
 	if c.stopRequested.Load() {
 		return
 	}
-	c.mainLog.Println("disconnecting")
+	c.mainLog.Info("disconnecting", "dropped", dropped)
 	c.stopRequested.Store(true)
-	if c.playerID != nil { // playerID indicates that client has already joined
+	if c.playerID != nil && dropped {
+		c.mainLog.Debug("keeping the player in the session until they reconnect")
+		c.manager.DisconnectPlayer(ctx, c.sid, *c.playerID, c.servDataChan)
+	} else if c.playerID != nil { // playerID indicates that client has already joined
 		// Here we are asking manager to disconnect us
-		c.mainLog.Printf("removing the player from the session")
-		c.manager.RemovePlayer(ctx, c.sid, *c.playerID)
+		c.mainLog.Debug("removing the player from the session")
+		c.manager.RemovePlayer(ctx, c.sid, *c.playerID, c.servDataChan)
 	} else {
 		// Manager knows nothing about client, so we just stop threads
-		c.mainLog.Printf("closing serv data chan")
+		c.mainLog.Debug("closing serv data chan")
 		close(c.servDataChan)
 	}
 }
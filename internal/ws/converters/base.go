@@ -0,0 +1,16 @@
+package converters
+
+import (
+	"party-buddy/internal/schemas/ws"
+	"party-buddy/internal/ws/utils"
+)
+
+// genBaseMessage generates the base of a server message for the protocol version v.
+// Since ProtocolV2, the message carries its sequence number (if it has one).
+func genBaseMessage(kind *ws.MessageKind, seq uint64, v ws.ProtocolVersion) ws.BaseMessage {
+	base := utils.GenBaseMessage(kind)
+	if v >= ws.ProtocolV2 && seq != 0 {
+		base.SetSeq(seq)
+	}
+	return base
+}
@@ -0,0 +1,49 @@
+package converters
+
+import (
+	"party-buddy/internal/schemas/ws"
+	"party-buddy/internal/session"
+)
+
+func toChatEntry(m session.ChatMessage) ws.ChatEntry {
+	return ws.ChatEntry{
+		PlayerID: uint32(m.PlayerID),
+		Text:     m.Text,
+		SentAt:   ws.Time(m.SentAt),
+	}
+}
+
+func ToMessageChatMessage(m session.MsgChat, v ws.ProtocolVersion) ws.MessageChatMessage {
+	return ws.MessageChatMessage{
+		BaseMessage: genBaseMessage(&ws.MsgKindChatMessage, m.Seq(), v),
+		ChatEntry:   toChatEntry(m.ChatMessage),
+	}
+}
+
+func ToMessageReaction(m session.MsgReaction, v ws.ProtocolVersion) ws.MessageReaction {
+	return ws.MessageReaction{
+		BaseMessage: genBaseMessage(&ws.MsgKindReaction, m.Seq(), v),
+		PlayerID:    uint32(m.PlayerID),
+		Reaction:    m.Reaction,
+	}
+}
+
+func ToMessageChatMuted(m session.MsgChatMuted, v ws.ProtocolVersion) ws.MessageChatMuted {
+	return ws.MessageChatMuted{
+		BaseMessage: genBaseMessage(&ws.MsgKindChatMuted, m.Seq(), v),
+		Muted:       m.Muted,
+	}
+}
+
+func ToMessageChatHistory(m session.MsgChatHistory, v ws.ProtocolVersion) ws.MessageChatHistory {
+	messages := make([]ws.ChatEntry, 0, len(m.Messages))
+	for _, msg := range m.Messages {
+		messages = append(messages, toChatEntry(msg))
+	}
+
+	return ws.MessageChatHistory{
+		BaseMessage: genBaseMessage(&ws.MsgKindChatHistory, m.Seq(), v),
+		Muted:       m.Muted,
+		Messages:    messages,
+	}
+}
@@ -2,6 +2,7 @@ package converters
 
 import (
 	"errors"
+	"fmt"
 	"party-buddy/internal/schemas/ws"
 	"party-buddy/internal/session"
 )
@@ -9,13 +10,25 @@ import (
 // ErrorCodeAndMessage takes a session package error and returns
 // its error code and a message suitable for sending over the wire.
 func ErrorCodeAndMessage(err error) (kind ws.ErrorKind, msg string) {
+	var closedByAdmin *session.ClosedByAdminError
+
 	switch {
+	case errors.As(err, &closedByAdmin):
+		return ws.ErrSessionClosed, fmt.Sprintf("the session was closed by an administrator: %s", closedByAdmin.Reason)
+	case errors.Is(err, session.ErrKicked):
+		return ws.ErrKicked, "you were removed from the session by an administrator"
+	case errors.Is(err, session.ErrInactivity):
+		return ws.ErrInactivity, "you were removed from the session for inactivity"
+	case errors.Is(err, session.ErrInactivityWarning):
+		return ws.ErrInactivityWarning, "you will soon be removed from the session unless you take part in the game"
+	case errors.Is(err, session.ErrJoinedMidTask):
+		return ws.ErrJoinedMidTask, "you have joined after the task had started, wait for the next one"
 	case errors.Is(err, session.ErrNoOwnerTimeout):
 		return ws.ErrSessionClosed, "timed out waiting for the owner"
-	case errors.Is(err, session.ErrReconnected):
-		return ws.ErrReconnected, "reconnected from another connection"
 	case errors.Is(err, session.ErrOwnerLeft):
 		return ws.ErrSessionClosed, "the owner left the session"
+	case errors.Is(err, session.ErrReconnected):
+		return ws.ErrReconnected, "reconnected from another connection"
 	case errors.Is(err, session.ErrNoSession), errors.Is(err, session.ErrGameInProgress), errors.Is(err, session.ErrClientBanned):
 		return ws.ErrSessionExpired, "no such session"
 	case errors.Is(err, session.ErrNicknameUsed):
@@ -28,6 +41,16 @@ func ErrorCodeAndMessage(err error) (kind ws.ErrorKind, msg string) {
 		return ws.ErrMalformedMsg, "the provided answer type cannot be used for this task"
 	case errors.Is(err, session.ErrTaskIndexOutOfBounds):
 		return ws.ErrMalformedMsg, "the task index is out of bounds"
+	case errors.Is(err, session.ErrOwnerOnly):
+		return ws.ErrOpOnly, "only the session owner may do this"
+	case errors.Is(err, session.ErrNoTargetPlayer):
+		return ws.ErrMalformedMsg, "no such player in the session"
+	case errors.Is(err, session.ErrTargetLegacy):
+		return ws.ErrMalformedMsg, "the player's client does not support this"
+	case errors.Is(err, session.ErrLegacyPlayers):
+		return ws.ErrMalformedMsg, "some players' clients do not support this"
+	case errors.Is(err, session.ErrChatMuted):
+		return ws.ErrChatMuted, "the chat is muted by the session owner"
 	case errors.Is(err, session.ErrNoPlayer):
 		return ws.ErrProtoViolation, "no such player in the session"
 	default:
@@ -3,10 +3,9 @@ package converters
 import (
 	"party-buddy/internal/schemas/ws"
 	"party-buddy/internal/session"
-	"party-buddy/internal/ws/utils"
 )
 
-func ToMessageGameEnd(m session.MsgGameEnd) ws.MessageGameEnd {
+func ToMessageGameEnd(m session.MsgGameEnd, v ws.ProtocolVersion) ws.MessageGameEnd {
 	var scores []ws.GamePlayerScore
 
 	for _, score := range m.Scoreboard.Scores() {
@@ -17,7 +16,7 @@ func ToMessageGameEnd(m session.MsgGameEnd) ws.MessageGameEnd {
 	}
 
 	return ws.MessageGameEnd{
-		BaseMessage: utils.GenBaseMessage(&ws.MsgKindGameEnd),
+		BaseMessage: genBaseMessage(&ws.MsgKindGameEnd, m.Seq(), v),
 		Scoreboard:  scores,
 	}
 }
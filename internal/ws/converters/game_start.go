@@ -3,12 +3,11 @@ package converters
 import (
 	"party-buddy/internal/schemas/ws"
 	"party-buddy/internal/session"
-	"party-buddy/internal/ws/utils"
 )
 
-func ToMessageGameStart(m session.MsgGameStart) ws.MessageGameStart {
+func ToMessageGameStart(m session.MsgGameStart, v ws.ProtocolVersion) ws.MessageGameStart {
 	return ws.MessageGameStart{
-		BaseMessage: utils.GenBaseMessage(&ws.MsgKindGameStart),
+		BaseMessage: genBaseMessage(&ws.MsgKindGameStart, m.Seq(), v),
 		// TODO: time delay compensation
 		Deadline: ws.Time(m.Deadline),
 	}
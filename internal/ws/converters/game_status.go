@@ -3,10 +3,9 @@ package converters
 import (
 	"party-buddy/internal/schemas/ws"
 	"party-buddy/internal/session"
-	"party-buddy/internal/ws/utils"
 )
 
-func ToMessageGameStatus(m session.MsgGameStatus) ws.MessageGameStatus {
+func ToMessageGameStatus(m session.MsgGameStatus, v ws.ProtocolVersion) ws.MessageGameStatus {
 	var players []ws.Player
 
 	for _, player := range m.Players {
@@ -17,7 +16,7 @@ func ToMessageGameStatus(m session.MsgGameStatus) ws.MessageGameStatus {
 	}
 
 	return ws.MessageGameStatus{
-		BaseMessage: utils.GenBaseMessage(&ws.MsgKindGameStatus),
+		BaseMessage: genBaseMessage(&ws.MsgKindGameStatus, m.Seq(), v),
 		Players:     players,
 	}
 }
@@ -0,0 +1,19 @@
+package converters
+
+import (
+	"party-buddy/internal/schemas/ws"
+	"party-buddy/internal/session"
+)
+
+func ToMessageDeadlineChanged(m session.MsgDeadlineChanged, v ws.ProtocolVersion) ws.MessageDeadlineChanged {
+	msg := ws.MessageDeadlineChanged{
+		BaseMessage: genBaseMessage(&ws.MsgKindDeadlineChanged, m.Seq(), v),
+		Deadline:    ws.Time(m.Deadline),
+		Paused:      m.Paused,
+	}
+	if m.Paused {
+		remaining := uint64(m.Remaining.Milliseconds())
+		msg.RemainingMs = &remaining
+	}
+	return msg
+}
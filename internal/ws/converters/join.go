@@ -6,7 +6,6 @@ import (
 	"party-buddy/internal/schemas"
 	"party-buddy/internal/schemas/ws"
 	"party-buddy/internal/session"
-	"party-buddy/internal/ws/utils"
 	"time"
 )
 
@@ -21,12 +20,12 @@ func ToPollDuration(pd session.PollDurationer) schemas.PollDuration {
 	panic(errors.New("bad poll duration from server"))
 }
 
-func ToSchemaTask(t session.Task) schemas.BaseTaskWithImg {
+func ToSchemaTask(t session.Task, sid session.SessionID) schemas.BaseTaskWithImg {
 	task := schemas.BaseTaskWithImg{}
 	task.Name = t.GetName()
 	task.Description = t.GetDescription()
 	if t.GetImageID().Valid {
-		task.ImgURI = configuration.GenImgURI(t.GetImageID().UUID)
+		task.ImgURI = configuration.GenImgURI(t.GetImageID().UUID, sid.NullUUID())
 	}
 	task.Duration = schemas.PollDuration{Kind: schemas.Fixed, Secs: uint16(t.GetTaskDuration().Seconds())}
 	switch t := t.(type) {
@@ -57,29 +56,29 @@ func ToSchemaTask(t session.Task) schemas.BaseTaskWithImg {
 	}
 }
 
-func ToGameDetails(g session.Game) schemas.GameDetails {
+func ToGameDetails(g session.Game, sid session.SessionID) schemas.GameDetails {
 	game := schemas.GameDetails{}
 	game.Name = g.Name
 	game.Description = g.Description
 	game.DateChanged = g.DateChanged
 	tasks := make([]schemas.BaseTaskWithImg, 0, len(g.Tasks))
 	for i := 0; i < len(g.Tasks); i++ {
-		tasks = append(tasks, ToSchemaTask(g.Tasks[i]))
+		tasks = append(tasks, ToSchemaTask(g.Tasks[i], sid))
 	}
 	game.Tasks = tasks
 	if g.ImageID.Valid {
-		game.ImgURI = configuration.GenImgURI(g.ImageID.UUID)
+		game.ImgURI = configuration.GenImgURI(g.ImageID.UUID, sid.NullUUID())
 	}
 	return game
 }
 
-func ToMessageJoined(m session.MsgJoined) ws.MessageJoined {
+func ToMessageJoined(m session.MsgJoined, v ws.ProtocolVersion) ws.MessageJoined {
 	msg := ws.MessageJoined{}
-	msg.BaseMessage = utils.GenBaseMessage(&ws.MsgKindJoined)
+	msg.BaseMessage = genBaseMessage(&ws.MsgKindJoined, m.Seq(), v)
 	msg.Sid = m.SessionID.UUID()
 	msg.InviteCode = (*string)(m.InviteCode)
 	msg.PlayerID = uint32(m.PlayerID)
-	msg.Game = ToGameDetails(*m.Game)
+	msg.Game = ToGameDetails(*m.Game, m.SessionID)
 	msg.MaxPlayers = uint8(m.MaxPlayers)
 	return msg
 }
@@ -0,0 +1,13 @@
+package converters
+
+import (
+	"party-buddy/internal/schemas/ws"
+	"party-buddy/internal/session"
+)
+
+func ToMessageOwnerChanged(m session.MsgOwnerChanged, v ws.ProtocolVersion) ws.MessageOwnerChanged {
+	return ws.MessageOwnerChanged{
+		BaseMessage: genBaseMessage(&ws.MsgKindOwnerChanged, m.Seq(), v),
+		PlayerID:    uint32(m.PlayerID),
+	}
+}
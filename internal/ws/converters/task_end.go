@@ -4,12 +4,11 @@ import (
 	"party-buddy/internal/configuration"
 	"party-buddy/internal/schemas/ws"
 	"party-buddy/internal/session"
-	"party-buddy/internal/ws/utils"
 )
 
-func ToMessageTaskEnd(m session.MsgTaskEnd) ws.MessageTaskEnd {
+func ToMessageTaskEnd(m session.MsgTaskEnd, sid session.SessionID, v ws.ProtocolVersion) ws.MessageTaskEnd {
 	msg := ws.MessageTaskEnd{
-		BaseMessage: utils.GenBaseMessage(&ws.MsgKindTaskEnd),
+		BaseMessage: genBaseMessage(&ws.MsgKindTaskEnd, m.Seq(), v),
 		TaskIdx:     uint8(m.TaskIdx),
 		Deadline:    ws.Time(m.Deadline),
 	}
@@ -40,7 +39,7 @@ func ToMessageTaskEnd(m session.MsgTaskEnd) ws.MessageTaskEnd {
 
 		case session.PhotoTask:
 			msg.Answers = append(msg.Answers, &ws.PhotoAnswer{
-				Value: configuration.GenImgURI(a.Value.(session.PhotoTaskAnswer).UUID),
+				Value: configuration.GenImgURI(a.Value.(session.PhotoTaskAnswer).UUID, sid.NullUUID()),
 				Votes: uint16(a.Votes),
 			})
 
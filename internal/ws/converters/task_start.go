@@ -4,12 +4,11 @@ import (
 	"party-buddy/internal/configuration"
 	"party-buddy/internal/schemas/ws"
 	"party-buddy/internal/session"
-	"party-buddy/internal/ws/utils"
 )
 
-func ToMessageTaskStart(m session.MsgTaskStart) ws.MessageTaskStart {
+func ToMessageTaskStart(m session.MsgTaskStart, sid session.SessionID, v ws.ProtocolVersion) ws.MessageTaskStart {
 	msg := ws.MessageTaskStart{
-		BaseMessage: utils.GenBaseMessage(&ws.MsgKindTaskStart),
+		BaseMessage: genBaseMessage(&ws.MsgKindTaskStart, m.Seq(), v),
 		TaskIdx:     uint8(m.TaskIdx),
 		Deadline:    ws.Time(m.Deadline),
 	}
@@ -18,7 +17,7 @@ func ToMessageTaskStart(m session.MsgTaskStart) ws.MessageTaskStart {
 		return msg
 	}
 	if m.ImgID != nil {
-		uri := configuration.GenImgURI(m.ImgID.UUID)
+		uri := configuration.GenImgURI(m.ImgID.UUID, sid.NullUUID())
 		msg.ImgURI = &uri
 	}
 	return msg
@@ -3,10 +3,9 @@ package converters
 import (
 	"party-buddy/internal/schemas/ws"
 	"party-buddy/internal/session"
-	"party-buddy/internal/ws/utils"
 )
 
-func ToMessageWaiting(m session.MsgWaiting) ws.MessageWaiting {
+func ToMessageWaiting(m session.MsgWaiting, v ws.ProtocolVersion) ws.MessageWaiting {
 	ready := make([]uint32, 0)
 
 	for playerID := range m.PlayersReady {
@@ -14,7 +13,7 @@ func ToMessageWaiting(m session.MsgWaiting) ws.MessageWaiting {
 	}
 
 	return ws.MessageWaiting{
-		BaseMessage: utils.GenBaseMessage(&ws.MsgKindWaiting),
+		BaseMessage: genBaseMessage(&ws.MsgKindWaiting, m.Seq(), v),
 		Ready:       ready,
 	}
 }
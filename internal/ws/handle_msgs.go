@@ -8,13 +8,14 @@ import (
 	"party-buddy/internal/session"
 	"party-buddy/internal/ws/converters"
 	"party-buddy/internal/ws/utils"
+	"time"
 )
 
 func (c *Conn) playerIDOrError(ctx context.Context, msgID *ws.MessageID) bool {
 	if c.playerID == nil {
 		code, message := ws.ErrInternal, "internal error"
 		errMsg := utils.GenMessageError(msgID, code, message)
-		c.readerLog.Printf("the client has not yet joined the session (code `%s`)", code)
+		c.readerLog.Info("the client has not yet joined the session", "code", code)
 		c.msgToClientChan <- &errMsg
 
 		c.dispose(ctx)
@@ -25,12 +26,21 @@ func (c *Conn) playerIDOrError(ctx context.Context, msgID *ws.MessageID) bool {
 }
 
 func (c *Conn) handleJoin(ctx context.Context, m *ws.MessageJoin, servDataChan session.TxChan) {
-	player, err := c.manager.JoinSession(ctx, c.sid, c.client, *m.Nickname, servDataChan)
+	lastSeq := m.LastSeq
+	if c.protocol.Version < ws.ProtocolV2 {
+		// v1 clients don't see the sequence numbers
+		lastSeq = nil
+	}
+
+	// the clients that can't be told about the new owner get the session closed when the owner leaves
+	legacy := !c.protocol.Version.Supports(ws.MsgKindOwnerChanged)
+
+	player, err := c.manager.JoinSession(ctx, c.sid, c.client, *m.Nickname, servDataChan, lastSeq, legacy)
 	if err != nil {
 		code, message := converters.ErrorCodeAndMessage(err)
 		errMsg := utils.GenMessageError(m.MsgID, code, message)
-		c.readerLog.Printf("the manager returned an error while processing the Join message: %s (code `%s`)",
-			err, errMsg.Code)
+		c.readerLog.Info("the manager returned an error while processing the Join message",
+			"err", err, "code", errMsg.Code)
 		c.msgToClientChan <- &errMsg
 
 		c.dispose(ctx)
@@ -48,8 +58,8 @@ func (c *Conn) handleReady(ctx context.Context, m *ws.MessageReady) {
 	if err != nil {
 		code, message := converters.ErrorCodeAndMessage(err)
 		errMsg := utils.GenMessageError(m.MsgID, code, message)
-		c.readerLog.Printf("the manager returned an error while processing the Ready message: %s (code `%s`)",
-			err, errMsg.Code)
+		c.readerLog.Info("the manager returned an error while processing the Ready message",
+			"err", err, "code", errMsg.Code)
 		c.msgToClientChan <- &errMsg
 
 		c.dispose(ctx)
@@ -79,7 +89,7 @@ func (c *Conn) handleTaskAnswer(ctx context.Context, m *ws.MessageTaskAnswer) {
 		case ws.Option:
 			answer = session.ChoiceTaskAnswer(*m.Answer.Option)
 		default:
-			c.readerLog.Panicf("unsupported answer type: %s", *m.Answer.Type)
+			panic(fmt.Sprintf("unsupported answer type: %s", *m.Answer.Type))
 		}
 	}
 
@@ -97,10 +107,118 @@ func (c *Conn) handleTaskAnswer(ctx context.Context, m *ws.MessageTaskAnswer) {
 		}
 
 		errMsg := utils.GenMessageError(m.MsgID, code, message)
-		c.readerLog.Printf("the manager returned an error while processing the TaskAnswer message: %s (code `%s`)",
-			err, errMsg.Code)
+		c.readerLog.Info("the manager returned an error while processing the TaskAnswer message",
+			"err", err, "code", errMsg.Code)
 		c.msgToClientChan <- &errMsg
 
 		c.dispose(ctx)
 	}
 }
+
+// isChatMessage returns true for the messages subject to the chat rate limit.
+func isChatMessage(m ws.RecvMessage) bool {
+	switch m.(type) {
+	case *ws.MessageChat, *ws.MessageReact:
+		return true
+	default:
+		return false
+	}
+}
+
+// sendRequestError reports a failed request to the client.
+// The errors that merely reject the request (e.g. because the player is not the owner) keep the connection open.
+func (c *Conn) sendRequestError(ctx context.Context, msgID *ws.MessageID, kind ws.MessageKind, err error) {
+	code, message := converters.ErrorCodeAndMessage(err)
+	errMsg := utils.GenMessageError(msgID, code, message)
+	c.readerLog.Info("the manager returned an error while processing a message",
+		"kind", kind, "err", err, "code", errMsg.Code)
+	c.msgToClientChan <- &errMsg
+
+	if errors.Is(err, session.ErrChatMuted) || errors.Is(err, session.ErrOwnerOnly) ||
+		errors.Is(err, session.ErrNoTargetPlayer) || errors.Is(err, session.ErrTargetLegacy) ||
+		errors.Is(err, session.ErrLegacyPlayers) {
+		return
+	}
+	c.dispose(ctx)
+}
+
+func (c *Conn) handleChat(ctx context.Context, m *ws.MessageChat) {
+	if !c.playerIDOrError(ctx, m.MsgID) {
+		return
+	}
+
+	if err := c.manager.SendChatMessage(ctx, c.sid, *c.playerID, *m.Text); err != nil {
+		c.sendRequestError(ctx, m.MsgID, m.GetKind(), err)
+	}
+}
+
+func (c *Conn) handleReact(ctx context.Context, m *ws.MessageReact) {
+	if !c.playerIDOrError(ctx, m.MsgID) {
+		return
+	}
+
+	if err := c.manager.SendReaction(ctx, c.sid, *c.playerID, *m.Reaction); err != nil {
+		c.sendRequestError(ctx, m.MsgID, m.GetKind(), err)
+	}
+}
+
+func (c *Conn) handleMuteChat(ctx context.Context, m *ws.MessageMuteChat) {
+	if !c.playerIDOrError(ctx, m.MsgID) {
+		return
+	}
+
+	if err := c.manager.MuteChat(ctx, c.sid, *c.playerID, *m.Muted); err != nil {
+		c.sendRequestError(ctx, m.MsgID, m.GetKind(), err)
+	}
+}
+
+func (c *Conn) handleTransferOwner(ctx context.Context, m *ws.MessageTransferOwner) {
+	if !c.playerIDOrError(ctx, m.MsgID) {
+		return
+	}
+
+	if err := c.manager.TransferOwnership(ctx, c.sid, *c.playerID, session.PlayerID(*m.PlayerID)); err != nil {
+		c.sendRequestError(ctx, m.MsgID, m.GetKind(), err)
+	}
+}
+
+func (c *Conn) handlePause(ctx context.Context, m *ws.MessagePause) {
+	if !c.playerIDOrError(ctx, m.MsgID) {
+		return
+	}
+
+	if err := c.manager.PauseGame(ctx, c.sid, *c.playerID, true); err != nil {
+		c.sendRequestError(ctx, m.MsgID, m.GetKind(), err)
+	}
+}
+
+func (c *Conn) handleResume(ctx context.Context, m *ws.MessageResume) {
+	if !c.playerIDOrError(ctx, m.MsgID) {
+		return
+	}
+
+	if err := c.manager.PauseGame(ctx, c.sid, *c.playerID, false); err != nil {
+		c.sendRequestError(ctx, m.MsgID, m.GetKind(), err)
+	}
+}
+
+func (c *Conn) handleSkipTask(ctx context.Context, m *ws.MessageSkipTask) {
+	if !c.playerIDOrError(ctx, m.MsgID) {
+		return
+	}
+
+	if err := c.manager.SkipTask(ctx, c.sid, *c.playerID); err != nil {
+		c.sendRequestError(ctx, m.MsgID, m.GetKind(), err)
+	}
+}
+
+func (c *Conn) handleAddTime(ctx context.Context, m *ws.MessageAddTime) {
+	if !c.playerIDOrError(ctx, m.MsgID) {
+		return
+	}
+
+	d := time.Duration(*m.Seconds) * time.Second
+	if err := c.manager.AddTime(ctx, c.sid, *c.playerID, d); err != nil {
+		c.sendRequestError(ctx, m.MsgID, m.GetKind(), err)
+	}
+}
@@ -0,0 +1,220 @@
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"party-buddy/internal/schemas/ws"
+	"party-buddy/internal/session"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// ErrTransportClosed is returned when using a transport that has been closed
+var ErrTransportClosed = errors.New("the transport has been closed")
+
+// SSETransport sends the server messages in a Server-Sent Events stream.
+// The client messages arrive in separate HTTP requests and are passed to the transport with Deliver.
+type SSETransport struct {
+	w       http.ResponseWriter
+	flusher http.Flusher
+
+	// incoming is unbuffered so that Deliver waits for the reader to take the message
+	incoming chan []byte
+
+	done chan struct{}
+
+	// mtx guards closed and the writes to w
+	mtx    sync.Mutex
+	closed bool
+}
+
+func newSSETransport(w http.ResponseWriter) (*SSETransport, error) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return nil, errors.New("the response writer does not support flushing")
+	}
+
+	return &SSETransport{
+		w:        w,
+		flusher:  flusher,
+		incoming: make(chan []byte),
+		done:     make(chan struct{}),
+	}, nil
+}
+
+func (t *SSETransport) Read() ([]byte, error) {
+	select {
+	case data := <-t.incoming:
+		return data, nil
+	case <-t.done:
+		return nil, ErrTransportClosed
+	}
+}
+
+func (t *SSETransport) Write(data []byte, binary bool) error {
+	if binary {
+		return errors.New("an SSE stream cannot carry binary messages")
+	}
+	return t.writeEvent("", data)
+}
+
+func (t *SSETransport) Ping() error {
+	// comments are ignored by the clients but keep proxies from closing an idle stream
+	return t.writeRaw(": ping\n\n")
+}
+
+func (t *SSETransport) Close() {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	if !t.closed {
+		t.closed = true
+		close(t.done)
+	}
+}
+
+// Done returns a channel that's closed when the transport is closed
+func (t *SSETransport) Done() <-chan struct{} {
+	return t.done
+}
+
+// Deliver passes a message received from the client to the connection reader.
+// It blocks until the reader takes the message, the transport is closed (ErrTransportClosed) or ctx is done.
+func (t *SSETransport) Deliver(ctx context.Context, data []byte) error {
+	select {
+	case t.incoming <- data:
+		return nil
+	case <-t.done:
+		return ErrTransportClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// writeEvent sends an event with the single-line data.
+// If event is empty, the client sees it as a `message` event.
+func (t *SSETransport) writeEvent(event string, data []byte) error {
+	var s string
+	if event != "" {
+		s = fmt.Sprintf("event: %s\n", event)
+	}
+	return t.writeRaw(s + fmt.Sprintf("data: %s\n\n", data))
+}
+
+func (t *SSETransport) writeRaw(s string) error {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	// the response writer must not be used once the handler has returned
+	if t.closed {
+		return ErrTransportClosed
+	}
+
+	if _, err := io.WriteString(t.w, s); err != nil {
+		return err
+	}
+	t.flusher.Flush()
+	return nil
+}
+
+// SSEConnectionEvent is the first event of an SSE stream (`event: connection`).
+// The client must pass Token in the SSEConnectionTokenHeader of the requests carrying its messages.
+type SSEConnectionEvent struct {
+	Token    string `json:"token"`
+	Protocol string `json:"protocol"`
+}
+
+// SSEConnectionTokenHeader is the request header identifying the SSE stream a client message belongs to
+const SSEConnectionTokenHeader = "X-Connection-Token"
+
+type sseEntry struct {
+	transport *SSETransport
+	client    session.ClientID
+}
+
+// SSERegistry keeps track of the open SSE streams by their connection tokens
+type SSERegistry struct {
+	mtx   sync.Mutex
+	conns map[string]sseEntry
+}
+
+func NewSSERegistry() *SSERegistry {
+	return &SSERegistry{conns: make(map[string]sseEntry)}
+}
+
+// Open starts an SSE stream in the response and registers it under a new connection token.
+// The token is sent to the client in the `connection` event.
+// The stream must be removed from the registry with Remove once the response is done.
+func (r *SSERegistry) Open(
+	w http.ResponseWriter,
+	client session.ClientID,
+	protocol ws.Protocol,
+) (token string, t *SSETransport, err error) {
+	t, err = newSSETransport(w)
+	if err != nil {
+		return "", nil, err
+	}
+
+	token = uuid.NewString()
+	event, err := json.Marshal(SSEConnectionEvent{Token: token, Protocol: protocol.Subprotocol()})
+	if err != nil {
+		return "", nil, err
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	// disables response buffering in nginx
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	if err = t.writeEvent("connection", event); err != nil {
+		return "", nil, err
+	}
+
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	r.conns[token] = sseEntry{transport: t, client: client}
+
+	return token, t, nil
+}
+
+// Lookup returns the stream with the connection token.
+// The stream is only returned to the client that has opened it.
+func (r *SSERegistry) Lookup(token string, client session.ClientID) (*SSETransport, bool) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	entry, ok := r.conns[token]
+	if !ok || entry.client != client {
+		return nil, false
+	}
+	return entry.transport, true
+}
+
+// Remove closes the stream and forgets its connection token
+func (r *SSERegistry) Remove(token string) {
+	r.mtx.Lock()
+	entry, ok := r.conns[token]
+	delete(r.conns, token)
+	r.mtx.Unlock()
+
+	if ok {
+		entry.transport.Close()
+	}
+}
+
+// CloseAll closes every open stream.
+// It's used during shutdown since, unlike WebSocket connections, the streams are in-flight requests.
+func (r *SSERegistry) CloseAll() {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	for _, entry := range r.conns {
+		entry.transport.Close()
+	}
+}
@@ -0,0 +1,72 @@
+package ws
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"party-buddy/internal/schemas/ws"
+	"party-buddy/internal/session"
+	"strings"
+	"testing"
+)
+
+func TestSSERegistry(t *testing.T) {
+	registry := NewSSERegistry()
+	rec := httptest.NewRecorder()
+	owner := session.ClientID{1}
+
+	token, transport, err := registry.Open(rec, owner, ws.Protocol{Version: ws.ProtocolV2, Encoding: ws.EncodingJSON})
+	if err != nil {
+		t.Fatalf("could not open a stream: %v", err)
+	}
+
+	if ct := rec.Header().Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("unexpected content type %q", ct)
+	}
+	want := `event: connection` + "\n" + `data: {"token":"` + token + `","protocol":"partybuddy.v2"}` + "\n\n"
+	if rec.Body.String() != want {
+		t.Errorf("unexpected connection event:\n got %q\nwant %q", rec.Body.String(), want)
+	}
+
+	if _, ok := registry.Lookup(token, session.ClientID{2}); ok {
+		t.Error("the stream was returned to another client")
+	}
+	if found, ok := registry.Lookup(token, owner); !ok || found != transport {
+		t.Fatal("the stream was not found by its token")
+	}
+
+	rec.Body.Reset()
+	if err = transport.Write([]byte(`{"kind":"waiting"}`), false); err != nil {
+		t.Fatalf("could not write a message: %v", err)
+	}
+	if want = "data: {\"kind\":\"waiting\"}\n\n"; rec.Body.String() != want {
+		t.Errorf("unexpected message event:\n got %q\nwant %q", rec.Body.String(), want)
+	}
+	if err = transport.Write([]byte{0xa0}, true); err == nil {
+		t.Error("a binary message was written")
+	}
+
+	go func() {
+		_ = transport.Deliver(context.Background(), []byte(`{"kind":"ready"}`))
+	}()
+	if data, err := transport.Read(); err != nil || string(data) != `{"kind":"ready"}` {
+		t.Errorf("unexpected delivered message %q (err: %v)", data, err)
+	}
+
+	registry.Remove(token)
+	if _, ok := registry.Lookup(token, owner); ok {
+		t.Error("the removed stream was found")
+	}
+	if _, err = transport.Read(); !errors.Is(err, ErrTransportClosed) {
+		t.Errorf("expected Read to fail with ErrTransportClosed, got %v", err)
+	}
+	if err = transport.Deliver(context.Background(), nil); !errors.Is(err, ErrTransportClosed) {
+		t.Errorf("expected Deliver to fail with ErrTransportClosed, got %v", err)
+	}
+	if err = transport.Ping(); !errors.Is(err, ErrTransportClosed) {
+		t.Errorf("expected Ping to fail with ErrTransportClosed, got %v", err)
+	}
+	if strings.Contains(rec.Body.String(), "ping") {
+		t.Error("a closed stream was written to")
+	}
+}
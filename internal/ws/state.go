@@ -1,7 +1,12 @@
 package ws
 
-import "party-buddy/internal/schemas/ws"
+import (
+	"party-buddy/internal/schemas/ws"
+	"party-buddy/internal/session"
+)
 
+// A sessionState decides which client messages are acceptable.
+// Note that the chat is closed while a task or a poll is underway so that the players can't share the answers.
 type sessionState interface {
 	isSessionState()
 	isAllowedMsg(m ws.RecvMessage) bool
@@ -31,7 +36,8 @@ func (awaitingPlayersState) isSessionState() {}
 
 func (awaitingPlayersState) isAllowedMsg(m ws.RecvMessage) bool {
 	switch m.(type) {
-	case *ws.MessageReady, *ws.MessageLeave, *ws.MessageKick, *ws.MessageError:
+	case *ws.MessageReady, *ws.MessageLeave, *ws.MessageKick, *ws.MessageError,
+		*ws.MessageChat, *ws.MessageReact, *ws.MessageMuteChat, *ws.MessageTransferOwner:
 		return true
 	default:
 		return false
@@ -48,7 +54,9 @@ func (gameStartedState) isSessionState() {}
 
 func (gameStartedState) isAllowedMsg(m ws.RecvMessage) bool {
 	switch m.(type) {
-	case *ws.MessageReady, *ws.MessageLeave, *ws.MessageKick, *ws.MessageError:
+	case *ws.MessageReady, *ws.MessageLeave, *ws.MessageKick, *ws.MessageError,
+		*ws.MessageChat, *ws.MessageReact, *ws.MessageMuteChat,
+		*ws.MessagePause, *ws.MessageResume, *ws.MessageAddTime:
 		return true
 	default:
 		return false
@@ -65,7 +73,8 @@ func (taskStartedState) isSessionState() {}
 
 func (taskStartedState) isAllowedMsg(m ws.RecvMessage) bool {
 	switch m.(type) {
-	case *ws.MessageReady, *ws.MessageLeave, *ws.MessageKick, *ws.MessageTaskAnswer, *ws.MessagePollChoose, *ws.MessageError:
+	case *ws.MessageReady, *ws.MessageLeave, *ws.MessageKick, *ws.MessageTaskAnswer, *ws.MessagePollChoose, *ws.MessageError,
+		*ws.MessagePause, *ws.MessageResume, *ws.MessageSkipTask, *ws.MessageAddTime:
 		return true
 	default:
 		return false
@@ -82,7 +91,8 @@ func (pollStartedState) isSessionState() {}
 
 func (pollStartedState) isAllowedMsg(m ws.RecvMessage) bool {
 	switch m.(type) {
-	case *ws.MessageReady, *ws.MessageLeave, *ws.MessageKick, *ws.MessageTaskAnswer, *ws.MessagePollChoose, *ws.MessageError:
+	case *ws.MessageReady, *ws.MessageLeave, *ws.MessageKick, *ws.MessageTaskAnswer, *ws.MessagePollChoose, *ws.MessageError,
+		*ws.MessagePause, *ws.MessageResume, *ws.MessageSkipTask, *ws.MessageAddTime:
 		return true
 	default:
 		return false
@@ -99,7 +109,9 @@ func (taskEndedState) isSessionState() {}
 
 func (taskEndedState) isAllowedMsg(m ws.RecvMessage) bool {
 	switch m.(type) {
-	case *ws.MessageReady, *ws.MessageLeave, *ws.MessageKick, *ws.MessageTaskAnswer, *ws.MessagePollChoose, *ws.MessageError:
+	case *ws.MessageReady, *ws.MessageLeave, *ws.MessageKick, *ws.MessageTaskAnswer, *ws.MessagePollChoose, *ws.MessageError,
+		*ws.MessageChat, *ws.MessageReact, *ws.MessageMuteChat,
+		*ws.MessagePause, *ws.MessageResume, *ws.MessageAddTime:
 		return true
 	default:
 		return false
@@ -109,3 +121,20 @@ func (taskEndedState) isAllowedMsg(m ws.RecvMessage) bool {
 func (taskEndedState) name() string {
 	return "task-ended"
 }
+
+// stateFromSession returns the protocol state corresponding to the session state.
+// A (re)joining client starts in it.
+func stateFromSession(state session.State) sessionState {
+	switch state.(type) {
+	case *session.GameStartedState:
+		return gameStartedState{}
+	case *session.TaskStartedState:
+		return taskStartedState{}
+	case *session.PollStartedState:
+		return pollStartedState{}
+	case *session.TaskEndedState:
+		return taskEndedState{}
+	default:
+		return awaitingPlayersState{}
+	}
+}
@@ -0,0 +1,75 @@
+package ws
+
+import (
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// A Transport carries the encoded messages between the client and the server.
+//
+// Read is only called by the connection reader, Write and Ping are only called by the writer.
+type Transport interface {
+	// Read blocks until a message from the client arrives.
+	// Once it fails, the connection is disposed of.
+	Read() ([]byte, error)
+
+	// Write sends the message to the client.
+	// The message must be sent in a binary frame if binary is true.
+	Write(data []byte, binary bool) error
+
+	// Ping keeps the transport alive and lets it detect a dead client
+	Ping() error
+
+	// Close closes the transport. Any pending Read fails
+	Close()
+}
+
+type wsTransport struct {
+	conn *websocket.Conn
+}
+
+// NewWSTransport makes a transport over the WebSocket connection
+func NewWSTransport(conn *websocket.Conn) Transport {
+	t := &wsTransport{conn: conn}
+
+	// the deadline is extended whenever the client shows signs of life.
+	// a half-open connection will fail to read once it expires.
+	_ = t.extendDeadline()
+	conn.SetPongHandler(func(string) error {
+		return t.extendDeadline()
+	})
+
+	return t
+}
+
+func (t *wsTransport) extendDeadline() error {
+	return t.conn.SetReadDeadline(time.Now().Add(PongTimeout))
+}
+
+func (t *wsTransport) Read() ([]byte, error) {
+	_, data, err := t.conn.ReadMessage()
+	if err != nil {
+		return nil, err
+	}
+	_ = t.extendDeadline()
+	return data, nil
+}
+
+func (t *wsTransport) Write(data []byte, binary bool) error {
+	messageType := websocket.TextMessage
+	if binary {
+		messageType = websocket.BinaryMessage
+	}
+	_ = t.conn.SetWriteDeadline(time.Now().Add(WriteTimeout))
+	return t.conn.WriteMessage(messageType, data)
+}
+
+func (t *wsTransport) Ping() error {
+	// if the client doesn't respond, the read deadline expires and the connection is disposed of
+	return t.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(WriteTimeout))
+}
+
+func (t *wsTransport) Close() {
+	properWSClose(t.conn)
+}
@@ -0,0 +1,7 @@
+BEGIN;
+
+DROP INDEX refresh_tokens_user_id_idx;
+DROP TABLE refresh_tokens;
+ALTER TABLE users DROP COLUMN created_at;
+
+COMMIT;
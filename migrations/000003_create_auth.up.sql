@@ -0,0 +1,22 @@
+BEGIN;
+
+-- every registered user now has a row in this table, not only administrators.
+-- roles other than "base" are granted by the `set-role` server command.
+ALTER TABLE users
+    ADD COLUMN created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now();
+
+-- refresh tokens issued to devices.
+-- only the SHA-256 hash of a token is stored.
+-- a token is single-use: refreshing revokes it and issues a new one.
+CREATE TABLE refresh_tokens (
+    token_hash BYTEA PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users ON DELETE CASCADE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    revoked_at TIMESTAMP WITH TIME ZONE NULL
+);
+
+CREATE INDEX refresh_tokens_user_id_idx
+    ON refresh_tokens (user_id);
+
+COMMIT;
@@ -0,0 +1,6 @@
+BEGIN;
+
+DROP INDEX admin_audit_log_admin_id_idx;
+DROP TABLE admin_audit_log;
+
+COMMIT;
@@ -0,0 +1,18 @@
+BEGIN;
+
+-- a record of every action performed through the admin API.
+-- sessions live in memory only, so session_id is not a foreign key.
+-- the log must survive its authors, so an administrator with audit records can't be deleted.
+CREATE TABLE admin_audit_log (
+    id BIGSERIAL PRIMARY KEY,
+    admin_id UUID NOT NULL REFERENCES users ON DELETE RESTRICT,
+    action VARCHAR(32) NOT NULL,
+    session_id UUID NULL,
+    details TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+CREATE INDEX admin_audit_log_admin_id_idx
+    ON admin_audit_log (admin_id);
+
+COMMIT;
@@ -0,0 +1,6 @@
+BEGIN;
+
+DROP INDEX game_forked_from_idx;
+ALTER TABLE games DROP COLUMN forked_from;
+
+COMMIT;
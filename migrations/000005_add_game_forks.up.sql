@@ -0,0 +1,12 @@
+BEGIN;
+
+-- the game a game has been cloned from, to credit the original author.
+-- the lineage is lost (but the fork survives) if the original game is deleted.
+ALTER TABLE games
+    ADD COLUMN forked_from UUID NULL REFERENCES games ON DELETE SET NULL;
+
+CREATE INDEX game_forked_from_idx
+    ON games (forked_from)
+    WHERE forked_from IS NOT NULL;
+
+COMMIT;
@@ -17,6 +17,16 @@ if [[ -v "START_DB_PASSWORD_FILE" ]]; then
 	export PARTY_BUDDY_DB_PASSWORD=$(<"${START_DB_PASSWORD_FILE}")
 fi
 
+if [[ -v "START_AUTH_SECRET_FILE" ]]; then
+	if [[ ! -r "${START_AUTH_SECRET_FILE}" ]]; then
+		echo "File ${START_AUTH_SECRET_FILE} not found or not readable!"
+		exit 1
+	fi
+
+	log "reading \$PARTY_BUDDY_AUTH_SECRET from ${START_AUTH_SECRET_FILE}..."
+	export PARTY_BUDDY_AUTH_SECRET=$(<"${START_AUTH_SECRET_FILE}")
+fi
+
 log "starting app..."
 
 exec /app/party-buddy "$@"